@@ -0,0 +1,974 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Commands, flags, exit codes and config keys are described here once and
+// rendered both as help text and as man pages by "gen-man".
+
+type commandDoc struct {
+	name      string
+	chainable bool
+	// summary is one line description for man pages. When blank, first
+	// sentence of text is used.
+	summary string
+	text    string
+}
+
+type flagDoc struct {
+	usage string
+	text  string
+}
+
+type exitCodeDoc struct {
+	code int
+	text string
+}
+
+type configDoc struct {
+	section string
+	key     string
+	values  string
+	text    string
+}
+
+var usageLines = []string{
+	"spicetify [-q] [-e] [-a] <command>...",
+	"spicetify [--config <path>] <command>...",
+	"spicetify {-c | --config} | {-v | --version} | {-h | --help}",
+}
+
+const description = "Customize Spotify client UI and functionality"
+
+var commandDocs = []commandDoc{
+	{
+		name:      "backup",
+		chainable: true,
+		text: `Start backup and preprocessing app files.
+Not chainable when used with a sub command:
+1. List backups with their dates and sizes:
+spicetify backup list
+2. Remove old backups, keeping current one and newest
+ones up to "--keep" (default 1):
+spicetify backup prune [--keep <n>] [--dry-run]
+3. Export current backup, or backup of another Spotify
+version, to a single archive file:
+spicetify backup export <file> [<Spotify version>]
+4. Import backup archive, e.g. after reinstalling OS. It
+becomes current backup when installed Spotify matches it:
+spicetify backup import <file>`,
+	},
+	{
+		name:      "apply",
+		chainable: true,
+		text: `Apply customization. Completed steps are recorded, so
+when an apply is interrupted, next "apply", "update" or
+"backup" run from a terminal offers to resume it or start
+over:
+spicetify apply --resume
+With "--manifest", config is first changed to match theme,
+scheme, extensions, custom apps and patches declared in a
+YAML or JSON file. Missing ones are downloaded or installed,
+enabled ones not listed are disabled:
+spicetify apply --manifest setup.yaml
+Apply is skipped when config, theme, extensions, custom
+apps and Spotify are unchanged since last successful
+apply and applied files are intact. Use "--force" to
+apply anyway.`,
+	},
+	{
+		name:      "update",
+		chainable: true,
+		text: `On default, update theme CSS and colors.
+Use with flag "-e" to update extensions.`,
+	},
+	{
+		name:      "restore",
+		chainable: true,
+		text:      `Restore Spotify to original state.`,
+	},
+	{
+		name:      "clear",
+		chainable: true,
+		text:      `Clear current backup files.`,
+	},
+	{
+		name:      "enable-devtools",
+		chainable: true,
+		text: `Enable Spotify's developer tools persistently, without
+launching Spotify with special flags.
+Hit Ctrl + Shift + I or right click in the client to
+start using.`,
+	},
+	{
+		name:      "disable-devtools",
+		chainable: true,
+		text: `Disable Spotify's developer tools and restore stock
+behavior.`,
+	},
+	{
+		name:      "watch",
+		chainable: true,
+		text: `Enter watch mode.
+On default, update CSS on color.ini or user.css's changes.
+Use with flag "-e" to update extensions on changes.
+Use with flag "-a" to update custom apps on changes.
+Add "--exec <command>" to run a build command, e.g.
+"npm run build", in custom app folder on source changes
+before updating it.`,
+	},
+	{
+		name:      "restart",
+		chainable: true,
+		text: `Gracefully quit then relaunch Spotify client.
+Use with "--kiosk" to relaunch in kiosk mode.`,
+	},
+	{
+		name:      "verify",
+		chainable: true,
+		summary:   "Check whether applied files are changed or missing",
+		text: `Check whether files written by last apply are changed or
+missing, e.g. overwritten by a Spotify update, and
+whether a re-apply is needed.`,
+	},
+	{
+		name:      "clean",
+		chainable: true,
+		text: `Remove extracted files, backups of old Spotify versions,
+orphaned AppX copy and temporary files, then report
+reclaimed space. Extracted files are regenerated from
+backup on next apply.
+Use with "--keep <n>" to keep <n> newest backups (default 1).
+Use with "--dry-run" to only list what would be removed.`,
+	},
+	{
+		name:      "rotate",
+		chainable: true,
+		text: `Switch to next theme or color scheme in "rotate_list"
+when rotation period set in "rotate_schemes" is due.
+Suitable for cron jobs or startup scripts.`,
+	},
+	{
+		name:      "ext",
+		chainable: false,
+		summary:   "List, enable, disable and configure extensions",
+		text: `1. List enabled and available extensions:
+spicetify ext list
+2. Enable or disable extensions. ".js" can be omitted.
+Glob patterns like "myext-*.js" are added as they are:
+spicetify ext enable <name>...
+spicetify ext disable <name>...
+Use with "--apply" to apply right away.
+3. Print or change settings of an extension. Blank value
+removes the key. Extensions read them from
+SpicetifyExtensionSettings["<name>.js"]:
+spicetify ext config <name> [<key>=<value>...]`,
+	},
+	{
+		name:      "app",
+		chainable: false,
+		summary:   "List, install, enable and disable custom apps",
+		text: `1. List enabled and available custom apps:
+spicetify app list
+2. Enable or disable custom apps. When Spotify is already
+applied, only custom apps are re-injected, without a full
+apply:
+spicetify app enable <name>...
+spicetify app disable <name>...
+3. Install custom app from a Github repository URL, a zip
+archive URL or a local zip file into CustomApps folder and
+enable it. Command in "build" field of its manifest.json
+is shown and run first, only after you confirm it. It is
+never run in quiet mode:
+spicetify app install <url | zip>`,
+	},
+	{
+		name:      "snippet",
+		chainable: false,
+		text: `Manage small Javascript snippets stored in [JsSnippets]
+config section. Enabled ones are put together into one
+injected file on apply.
+1. List snippets, enabled ones are marked with "*":
+spicetify snippet list
+2. Add and enable a snippet. <code> can be a .js file:
+spicetify snippet add <name> <code>
+3. Remove a snippet:
+spicetify snippet remove <name>
+4. Enable or disable snippets:
+spicetify snippet enable <name>...
+spicetify snippet disable <name>...`,
+	},
+	{
+		name:      "theme",
+		chainable: false,
+		summary:   "List, switch and download themes",
+		text: `1. List installed themes and their color schemes:
+spicetify theme list
+2. Switch to theme <name>, optionally with color scheme
+<scheme>, then apply:
+spicetify theme use <name> [<scheme>]
+3. Download theme from URL of a CSS file, gist, zip
+archive or Github repository, switch to it and apply:
+spicetify theme apply <url> [<scheme>]
+Downloaded themes are cached, use "--refresh" to
+download again.`,
+	},
+	{
+		name:      "spotify-data",
+		chainable: false,
+		text: `Manage Spotify client cache and storage.
+1. Print size of cache and storage folders:
+spicetify spotify-data show-size
+2. Remove streaming and browser caches:
+spicetify spotify-data clear-cache
+3. Change folder downloaded songs are stored in:
+spicetify spotify-data set-location <path>`,
+	},
+	{
+		name:      "uninstall",
+		chainable: false,
+		text: `Restore Spotify to stock state, remove every file spicetify
+injected, disable developer tools and remove autostart
+entries running spicetify. Then optionally delete
+spicetify config, user and cache folders. Config folder
+is only deleted when it has nothing but spicetify files.
+Use with "--purge" to delete them without prompting.`,
+	},
+	{
+		name:      "path",
+		chainable: false,
+		summary:   "Print paths of theme, extension and custom app files",
+		text: `Print path of color, css, extension file or
+custom app directory and quit.
+1. Print all theme's assests:
+spicetify path
+2. Print theme's color.ini path:
+spicetify path color
+3. Print theme's user.css path:
+spicetify path css
+4. Print theme's assets path:
+spicetify path assets
+5. Print all extensions path:
+spicetify -e path
+6. Print extension <name> path:
+spicetify -e path <name>
+7. Print all custom apps path:
+spicetify -a path
+8. Print custom app <name> path:
+spicetify -a path <name>`,
+	},
+	{
+		name:      "storage",
+		chainable: false,
+		text: `Export or import localStorage of running Spotify client,
+where extensions and themes keep their settings. Only
+entries whose keys start with name of spicetify, current
+theme, an enabled extension or custom app are included.
+Use with "--all" to include every entry.
+<file> defaults to "spicetify-storage.json".
+Spotify has to be running with remote debugging on.
+spicetify storage export [<file>]
+spicetify storage import [<file>]`,
+	},
+	{
+		name:      "eval",
+		chainable: false,
+		text: `Evaluate a Javascript expression in running Spotify
+client and print its result as JSON. Spotify has to be
+running with remote debugging on.
+Example usage:
+spicetify eval "Spicetify.Player.data"`,
+	},
+	{
+		name:      "player",
+		chainable: false,
+		text: `Control playback of running Spotify client through
+Spicetify.Player API. Spotify has to be running with remote
+debugging on and "expose_apis" applied.
+Sub commands: play, pause, next, prev and now-playing, which
+prints current track. Use "--json" to print it as one JSON
+line for status bars.
+Example usage:
+spicetify player next
+spicetify player now-playing --json`,
+	},
+	{
+		name:      "run",
+		chainable: false,
+		text: `Inject Javascript files into running Spotify client and
+execute them once, without installing them as extensions.
+Result of last expression is printed as JSON.
+Example usage:
+spicetify run ./myScript.js`,
+	},
+	{
+		name:      "test-patch",
+		chainable: false,
+		text: `Run a patch against extracted Spotify files and print
+match counts, positions and a preview of the replacement,
+without writing anything. <patch> is a built-in patch
+name (disable_sentry, disable_ui_logging,
+expose_apis_main, expose_apis_vendor) or one of
+its parts (e.g. expose_apis_main.player), a
+"<file>_find_<n>" key in [Patch] section, or a regular
+expression with optional replacement.
+Example usage:
+spicetify test-patch expose_apis_main
+spicetify test-patch xpui.js_find_0
+spicetify test-patch "(\w+)\.isPremium" "true"`,
+	},
+	{
+		name:      "spotify-info",
+		chainable: false,
+		text: `Print detected Spotify version, install type, install and
+prefs paths, xpui hash and whether remote debugging is
+reachable. Use "--json" for machine readable output.
+Please include it in bug reports.`,
+	},
+	{
+		name:      "wizard",
+		chainable: false,
+		text: `Set up spicetify step by step: confirm Spotify location,
+pick theme and color scheme, toggle common options, then
+back up and apply. It's offered when spicetify is run
+without a command from a terminal for the first time.`,
+	},
+	{
+		name:      "bug-report",
+		chainable: false,
+		text: `Collect versions, Spotify info, status, enabled addons,
+config and log of last apply into one file, with user
+paths redacted, to attach to a Github issue. <file>
+defaults to "spicetify-bug-report.txt". With a ".zip"
+file, config and apply log are also stored separately.
+spicetify bug-report [<file>]`,
+	},
+	{
+		name:      "gen-man",
+		chainable: false,
+		text: `Generate man pages of spicetify, every command and config
+file into <dir>, which defaults to "man". Set
+SOURCE_DATE_EPOCH for reproducible dates.
+spicetify gen-man [<dir>]`,
+	},
+	{
+		name:      "diff-extract",
+		chainable: false,
+		text: `Print a colored unified diff between Raw and Themed
+versions of an extracted file, to see what preprocessing
+changed. Minified lines are split into statements.
+Example usage:
+spicetify diff-extract xpui.js
+spicetify diff-extract xpui/home-hpto.css`,
+	},
+	{
+		name:      "config",
+		chainable: false,
+		summary:   "Print and change config values",
+		text: `1. Print all config fields and values:
+spicetify config
+
+2. Print one config field's value:
+spicetify config <field>
+
+Example usage:
+spicetify config color_scheme
+spicetify config custom_apps
+
+3. Change value of one or multiple config fields.
+spicetify config <field> <value> [<field> <value> ...]
+
+"extensions" and "custom_apps" fields are arrays of values,
+so <value> will be appended to those fields' current value.
+To remove one of array's values, postfix "-" to <value>.
+
+Example usage:
+- Enable "disable_sentry" preprocess:
+spicetify config disable_sentry 1
+- Add extension "myFakeExt.js" to current extensions list:
+spicetify config extensions myFakeExt.js
+- Remove extension "wrongname.js" from extensions list:
+spicetify config extensions wrongname.js-
+- Disable "inject_css" and enable "song_page"
+spicetify config inject_css 0 song_page 1`,
+	},
+	{
+		name:      "color",
+		chainable: false,
+		summary:   "Print, change and import color scheme values",
+		text: `1. Print all color fields and values.
+spicetify color
+
+Color boxes require 24-bit color (True color) supported
+terminal to show colors correctly.
+
+2. Change theme's one or multiple color values.
+spicetify color <field> <value> [<field> <value> ...]
+
+<value> can be in hex or decimal (rrr,ggg,bbb) format.
+
+Example usage:
+- Change main_bg to ff0000
+spicetify color main_bg ff0000
+- Change slider_bg to 00ff00 and pressing_fg to 0000ff
+spicetify color slider_bg 00ff00 pressing_fg 0000ff
+
+3. Edit current color scheme interactively.
+spicetify color edit
+
+Changes are previewed live when Spotify debugger is
+on (see "watch -l"). Edited colors can be saved to
+current scheme or to a new scheme with "save-as".
+
+4. Convert a VS Code theme, iTerm2 ".itermcolors" file or
+Windows Terminal scheme to a new scheme of current theme,
+named <scheme> or after the imported theme, and use it.
+Format is detected from file unless "--from" is set:
+spicetify color import [--from <format>] <file> [<scheme>]`,
+	},
+	{
+		name:      "upgrade",
+		chainable: false,
+		text:      `Upgrade spicetify latest version`,
+	},
+	{
+		name:      "build",
+		chainable: false,
+		summary:   "Write customized copy of a Spotify Apps folder.",
+		text: `Write customized copy of stock Spotify Apps folder to an
+empty output folder, for packaging, e.g. with Nix. Installed
+Spotify, its "prefs" and backups are not touched and the
+bundled CSS map is used, so output only depends on inputs.
+Changes needed in "prefs" and launch flags are printed:
+spicetify build <Spotify Apps folder> <output folder>`,
+	},
+}
+
+var flagDocs = []flagDoc{
+	{
+		usage: "-q, --quiet",
+		text: `Quiet mode (no output). Be careful, dangerous operations
+like clear backup, restore will proceed without prompting
+permission.`,
+	},
+	{
+		usage: "-e, --extension",
+		text: `Use with "update", "watch" or "path" command to
+focus on extensions.`,
+	},
+	{
+		usage: "-a, --app",
+		text:  `Use with "path" or "watch" to focus on custom apps.`,
+	},
+	{
+		usage: "-n, --no-restart",
+		text: `Do not restart Spotify after running command(s), except
+"restart" command.`,
+	},
+	{
+		usage: "-r, --restart",
+		text: `Gracefully quit and relaunch Spotify after running
+command(s), even when "-n" is set. With "apply", Spotify is
+relaunched with debugger on and checked for unexposed APIs,
+uncaught exceptions and extensions that are not loaded.
+Example: spicetify apply --restart`,
+	},
+	{
+		usage: "-l, --live-update",
+		text:  `Use with "watch" command to auto-reload Spotify on change`,
+	},
+	{
+		usage: "--exec <command>",
+		text: `Use with "watch -a" to run a build command in custom app
+folder whenever its source files change.
+Example: spicetify watch -a myApp --exec "npm run build"`,
+	},
+	{
+		usage: "--keep <n>",
+		text: `Use with "clean" or "backup prune" to keep <n> newest
+backups. Backup of current Spotify version is always kept.`,
+	},
+	{
+		usage: "--dry-run",
+		text: `Use with "clean" or "backup prune" to only list what
+would be removed.`,
+	},
+	{
+		usage: "--apply",
+		text:  `Use with "ext" to apply changes right away.`,
+	},
+	{
+		usage: "--kiosk",
+		text: `Use with "restart" to launch Spotify fullscreen with cursor
+auto-hide, for media center and party setups.`,
+	},
+	{
+		usage: "--log-format <text | json>",
+		text: `With "json", print one JSON event per line instead of
+messages: command and stage start/end with result and
+duration, and messages with their level and file. Prompts
+and other text are printed to stderr, use with "-q" to skip
+prompts. Useful for wrappers and GUIs.`,
+	},
+	{
+		usage: "--refresh",
+		text: `Download remote theme set in "current_theme" or
+"theme_source", release info and CSS map again instead of
+using cached copies. Without it, release info and CSS map
+are cached for 6 hours, and used when offline.`,
+	},
+	{
+		usage: "--json",
+		text: `Use with "spotify-info" or "player now-playing" to print
+as JSON.`,
+	},
+	{
+		usage: "--from <vscode | iterm | windows-terminal>",
+		text:  `Use with "color import" to set format of imported file.`,
+	},
+	{
+		usage: "--manifest <file>",
+		text: `Use with "apply" to make config match a manifest file
+before applying. Manifest keys are "theme" (a name, or
+"name" and "source" URL), "scheme", "extensions",
+"custom_apps", "preprocesses" and "patch".`,
+	},
+	{
+		usage: "--resume",
+		text: `Use with "apply" to continue an interrupted apply from
+its last completed step without asking.`,
+	},
+	{
+		usage: "--all",
+		text: `Use with "storage" to export or import every localStorage
+entry, including Spotify's own.`,
+	},
+	{
+		usage: "--force",
+		text: `Use with "apply" to apply even when nothing changed since
+last apply.`,
+	},
+	{
+		usage: "--select <n>",
+		text: `When multiple Spotify installs or "prefs" files are found,
+pick <n>th one instead of asking.`,
+	},
+	{
+		usage: "--purge",
+		text: `Use with "uninstall" to also delete spicetify config, user
+and cache folders without prompting.`,
+	},
+	{
+		usage: "-c, --config [<path> | -]",
+		text: `Without a value, print config file path and quit.
+"-c" never takes a value.
+With a path, use that config file instead of default one.
+With "-", read config from stdin. Changes to it are not
+saved.
+Example: spicetify --config ~/work.ini apply`,
+	},
+	{
+		usage: "-h, --help",
+		text: `Print this help text and quit.
+"spicetify -h <command>" prints help of one command.`,
+	},
+	{
+		usage: "-v, --version",
+		text:  `Print version number and quit`,
+	},
+}
+
+var exitCodeDocs = []exitCodeDoc{
+	{utils.ExitOK, "Success"},
+	{utils.ExitError, "Any other failure"},
+	{utils.ExitUsage, "Invalid command, flag or argument"},
+	{utils.ExitSpotifyNotFound, "Spotify installation not found"},
+	{utils.ExitPrefsNotFound, "Spotify \"prefs\" file not found"},
+	{utils.ExitNoBackup, "No backup"},
+	{utils.ExitBackupOutdated, "Backup and Spotify versions are mismatched"},
+	{utils.ExitPatchFailed, "Some patches in [Patch] could not be applied"},
+	{utils.ExitNetwork, "Network error"},
+	{utils.ExitPermission, "Permission denied"},
+	{utils.ExitAddonFailed, "Some extensions, custom apps or snippets could not be applied"},
+	{utils.ExitThemeNotFound, "Theme not found"},
+	{utils.ExitHealthCheck, "Spotify failed health check after \"apply --restart\""},
+}
+
+var configDocs = []configDoc{
+	{
+		section: "Setting",
+		key:     "spotify_path",
+		values:  "",
+		text: `Path to Spotify directory. Preview (beta) builds are detected when
+stable build is not installed. Set this to mod a preview build when
+both are installed.`,
+	},
+	{
+		section: "Setting",
+		key:     "prefs_path",
+		values:  "",
+		text:    `Path to Spotify's "prefs" file`,
+	},
+	{
+		section: "Setting",
+		key:     "current_theme",
+		values:  "",
+		text: `Name of folder of your theme, name of a single CSS file in Themes folder
+(with or without ".css"), which is used as user.css with default
+colors, or URL of a Github repository or zip
+archive to download theme from. Add "/tree/<branch>/<path>" to
+repository URL to use a branch or sub folder. Downloaded theme is cached,
+use "--refresh" flag to download it again.`,
+	},
+	{
+		section: "Setting",
+		key:     "theme_paths",
+		values:  "<folder>|<folder>...",
+		text: `Extra folders to look up themes in, after user and executable
+Themes folders. "theme list" shows themes in them too.`,
+	},
+	{
+		section: "Setting",
+		key:     "theme_source",
+		values:  "",
+		text: `Theme URL, same as URL in "current_theme". When set, it is used instead
+of "current_theme".`,
+	},
+	{
+		section: "Setting",
+		key:     "color_scheme",
+		values:  "",
+		text: `Color config section name in color.ini file.
+If color_scheme is blank, first section in color.ini file would be used.`,
+	},
+	{
+		section: "Setting",
+		key:     "inject_css",
+		values:  "<0 | 1>",
+		text: `Whether custom css from user.css in theme folder is applied.
+Local files imported with @import, including glob patterns like
+"./partials/*.css", are bundled into it.`,
+	},
+	{
+		section: "Setting",
+		key:     "replace_colors",
+		values:  "<0 | 1>",
+		text:    `Whether custom colors is applied`,
+	},
+	{
+		section: "Setting",
+		key:     "spotify_launch_flags",
+		values:  "",
+		text: `Command-line flags used when launching/restarting Spotify.
+Separate each flag with "|".
+List of valid flags: https://github.com/khanhas/spicetify-cli/wiki/Spotify-Commandline-Flags`,
+	},
+	{
+		section: "Setting",
+		key:     "check_spicetify_upgrade",
+		values:  "<0 | 1>",
+		text:    `Whether to check for new spicetify release before running commands.`,
+	},
+	{
+		section: "Setting",
+		key:     "update_channel",
+		values:  "<stable | prerelease>",
+		text: `Release channel used by "upgrade" and upgrade check.
+"prerelease" also gets pre-releases, which often contain fixes for
+newest Spotify builds.`,
+	},
+	{
+		section: "Setting",
+		key:     "rotate_schemes",
+		values:  "<daily | startup | duration>",
+		text: `How often "spicetify rotate" switches to next entry of "rotate_list".
+Duration is in Go format, e.g. "1h", "30m". Leave blank to disable rotation.`,
+	},
+	{
+		section: "Setting",
+		key:     "rotate_list",
+		values:  "<string>",
+		text: `List of color schemes or themes to rotate through. Separate each entry with "|".
+Entry can be a scheme name of current theme, "theme:scheme" or "theme:".
+Theme can also be an URL, e.g. "https://github.com/user/theme:dark".
+If blank, all schemes of current theme are used.`,
+	},
+	{
+		section: "Setting",
+		key:     "debug_port",
+		values:  "<number>",
+		text: `Port of Spotify remote debugging server, used by watch live reload,
+color editor and other commands talking to running Spotify. Default is 9222.
+Change it when another tool already uses the port.`,
+	},
+	{
+		section: "Setting",
+		key:     "debug_host",
+		values:  "<string>",
+		text: `Host of Spotify remote debugging server. Leave blank to use "localhost".
+Spotify launched by spicetify only listens on a non-local host when
+"debug_expose" is 1.`,
+	},
+	{
+		section: "Setting",
+		key:     "debug_expose",
+		values:  "<0 | 1>",
+		text: `Let remote debugging server of Spotify launched by spicetify listen on
+"debug_host" when it is not a local address. Anyone reaching it can control
+Spotify, only turn it on in trusted networks.`,
+	},
+	{
+		section: "Setting",
+		key:     "debug_timeout",
+		values:  "<duration>",
+		text: `Timeout of each attempt to connect to Spotify remote debugging server,
+e.g. "5s".`,
+	},
+	{
+		section: "Setting",
+		key:     "debug_retries",
+		values:  "<number>",
+		text: `How many times connecting to Spotify remote debugging server is retried
+before giving up.`,
+	},
+	{
+		section: "Setting",
+		key:     "debug_backoff",
+		values:  "<duration>",
+		text: `Delay before first retry to connect to Spotify remote debugging server,
+e.g. "500ms". It doubles after each retry.`,
+	},
+	{
+		section: "Setting",
+		key:     "kiosk_mode",
+		values:  "<0 | 1>",
+		text: `Always launch Spotify fullscreen with cursor auto-hide when spicetify
+restarts it. Remote debugging is turned on to inject kiosk payload.`,
+	},
+	{
+		section: "Setting",
+		key:     "cache_path",
+		values:  "",
+		text: `Folder to store regenerable files, like extracted Spotify app files.
+Leave blank to use XDG_CACHE_HOME (Linux), ~/Library/Caches (macOS)
+or %LOCALAPPDATA% (Windows).`,
+	},
+	{
+		section: "Setting",
+		key:     "download_mirror",
+		values:  "<url>",
+		text: `Mirror every download is routed through: upgrades, remote themes and
+CSS map. Useful where Github is blocked. Placeholders "{url}", "{host}"
+and "{path}" are replaced with original URL, its host and its path.
+Without placeholders, original URL is appended to it.
+Example: https://ghproxy.com/{url}`,
+	},
+	{
+		section: "Setting",
+		key:     "max_backups",
+		values:  "",
+		text: `Maximum number of backups to keep. Older ones are removed after each
+backup, current one is always kept. 0 keeps all backups.`,
+	},
+	{
+		section: "Preprocesses",
+		key:     "disable_sentry",
+		values:  "<0 | 1>",
+		text: `Prevents Sentry and Amazon Qualaroo to send console log/error/warning to Spotify developers.
+Enable if you don't want to catch their attention when developing extension or app.`,
+	},
+	{
+		section: "Preprocesses",
+		key:     "disable_ui_logging",
+		values:  "<0 | 1>",
+		text: `Various elements logs every user clicks, scrolls.
+Enable to stop logging and improve user experience.`,
+	},
+	{
+		section: "Preprocesses",
+		key:     "remove_rtl_rule",
+		values:  "<0 | 1>",
+		text: `To support Arabic and other Right-To-Left language, Spotify added a lot of
+CSS rules that are obsoleted to Left-To-Right users.
+Enable to remove all of them and improve render speed.`,
+	},
+	{
+		section: "Preprocesses",
+		key:     "expose_apis",
+		values:  "<0 | 1>",
+		text: `Leaks some Spotify's API, functions, objects to Spicetify global object that
+are useful for making extensions to extend Spotify functionality.`,
+	},
+	{
+		section: "Preprocesses",
+		key:     "disable_upgrade_check",
+		values:  "<0 | 1>",
+		text: `Prevent Spotify checking new version and visually notifying user.
+[Windows] Note: Automatic update still works if you don't manually delete "SpotifyMigrator.exe" and "SpotifyUpdate.exe".`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "custom_apps",
+		values:  "<string>",
+		text:    `List of custom apps. Separate each app with "|".`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "extensions",
+		values:  "<string>",
+		text: `List of Javascript files to be executed along with Spotify main script.
+Separate each extension with "|".
+Glob patterns like "myext-*.js" or "devdir/*.js" are expanded against
+extension folders on every apply and watch.`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "js_snippets",
+		values:  "<string>",
+		text: `List of enabled JS snippets from [JsSnippets] section.
+Separate each snippet name with "|".`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "home_config",
+		values:  "<0 | 1>",
+		text: `Enable ability to re-arrange sections in Home page.
+Navigate to Home page, turn "Home config" mode on in Profile menu and hover on sections to show customization buttons.`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "sidebar_config",
+		values:  "<0 | 1>",
+		text: `Enable ability to stick, hide, re-arrange sidebar items.
+Turn "Sidebar config" mode on in Profile menu and hover on sidebar items to show customization buttons.`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "window_effect",
+		values:  "<mica | acrylic>",
+		text: `Windows 11 only. Apply Mica or Acrylic backdrop to Spotify window when spicetify launches it,
+and clear client backgrounds so it shows through. Acrylic needs Windows 11 22H2.
+Backdrop is not saved with Spotify: when Spotify is started any other way, e.g. from Start menu
+or at login, backgrounds stay cleared but there is no backdrop. Start it with "spicetify restart"
+instead. Themes can style translucent surfaces under "html[data-window-effect]".
+Ignored on other systems. Blank to disable.`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "rtl",
+		values:  "<0 | 1>",
+		text: `Mirror layout right-to-left, e.g. for Hebrew and Arabic, whatever Spotify display language is.
+"rtl.css" in theme folder is injected along when present.
+Needs preprocess "remove_rtl_rule" off, which keeps Spotify's own RTL styles.`,
+	},
+	{
+		section: "AdditionalOptions",
+		key:     "ui_scale",
+		values:  "<number>",
+		text: `Zoom whole client by this factor, from 0.5 to 3, e.g. 1.25 for 125%.
+Written to "app.browser.zoom-level" in Spotify "prefs" file while Spotify is closed,
+and exposed to themes as CSS variable "--spicetify-ui-scale". When "prefs" file cannot
+be written, root font size of the client is scaled instead. Blank leaves zoom as it is.`,
+	},
+}
+
+// helpColumn is the column descriptions start at in help text.
+const helpColumn = 20
+
+// formatHelpEntry lays out `name` and its description `text` in two
+// columns. Names too long for the first column get their own line.
+func formatHelpEntry(name, text string) string {
+	indent := strings.Repeat(" ", helpColumn)
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		if i > 0 && len(lines[i]) > 0 {
+			lines[i] = indent + lines[i]
+		}
+	}
+
+	if len(name) < helpColumn-1 {
+		return name + strings.Repeat(" ", helpColumn-len(name)) + strings.Join(lines, "\n") + "\n"
+	}
+	return name + "\n" + indent + strings.Join(lines, "\n") + "\n"
+}
+
+func help() {
+	utils.PrintBold("spicetify v" + version)
+
+	usage := strings.Join(usageLines, "\n")
+	usage = strings.ReplaceAll(usage, "<command>", "\x1B[4mcommand\033[0m")
+	text := utils.Bold("USAGE") + "\n" + usage + "\n\n" +
+		utils.Bold("DESCRIPTION") + "\n" + description + "\n\n"
+
+	for _, chainable := range []bool{true, false} {
+		if chainable {
+			text += utils.Bold("CHAINABLE COMMANDS") + "\n"
+		} else {
+			text += utils.Bold("NON-CHAINABLE COMMANDS") + "\n"
+		}
+		for _, doc := range commandDocs {
+			if doc.chainable == chainable {
+				text += formatHelpEntry(doc.name, doc.text) + "\n"
+			}
+		}
+	}
+
+	text += utils.Bold("FLAGS") + "\n"
+	for _, doc := range flagDocs {
+		text += formatHelpEntry(doc.usage, doc.text) + "\n"
+	}
+
+	text += utils.Bold("EXIT CODES") + "\n"
+	for _, doc := range exitCodeDocs {
+		text += formatHelpEntry(fmt.Sprint(doc.code), doc.text)
+	}
+
+	log.Println(text + `
+For config information, run "spicetify -h config".
+For more information and bug report: https://github.com/khanhas/spicetify-cli/`)
+}
+
+// helpCommand prints help text of command `name`. Returns false if there
+// is no such command.
+func helpCommand(name string) bool {
+	for _, doc := range commandDocs {
+		if doc.name == name {
+			log.Print(formatHelpEntry(doc.name, doc.text))
+			return true
+		}
+	}
+	return false
+}
+
+func helpConfig() {
+	utils.PrintBold("CONFIG MEANING")
+
+	text := ""
+	section := ""
+	for _, doc := range configDocs {
+		if doc.section != section {
+			if len(section) > 0 {
+				text += "\n"
+			}
+			section = doc.section
+			text += utils.Bold("["+section+"]") + "\n"
+		} else {
+			text += "\n"
+		}
+
+		text += strings.TrimSpace(doc.key+" "+doc.values) + "\n"
+		for _, line := range strings.Split(doc.text, "\n") {
+			if len(line) > 0 {
+				line = "    " + line
+			}
+			text += line + "\n"
+		}
+	}
+
+	log.Print(text)
+}
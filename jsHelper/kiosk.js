@@ -0,0 +1,45 @@
+(function SpicetifyKiosk() {
+    if (window.SpicetifyKiosk) {
+        return;
+    }
+    window.SpicetifyKiosk = true;
+
+    const IDLE_TIMEOUT = 3000;
+
+    const style = document.createElement("style");
+    style.id = "spicetify-kiosk";
+    style.textContent = `
+body.spicetify-kiosk-idle,
+body.spicetify-kiosk-idle * {
+    cursor: none !important;
+}
+::-webkit-scrollbar {
+    display: none;
+}
+body {
+    user-select: none;
+}
+`;
+    document.head.appendChild(style);
+
+    // Hide cursor after a while without mouse movement
+    let idleTimer;
+    function wake() {
+        document.body.classList.remove("spicetify-kiosk-idle");
+        clearTimeout(idleTimer);
+        idleTimer = setTimeout(() => document.body.classList.add("spicetify-kiosk-idle"), IDLE_TIMEOUT);
+    }
+    document.addEventListener("mousemove", wake, true);
+    document.addEventListener("mousedown", wake, true);
+    wake();
+
+    // Fullscreen needs user gesture. It is granted when injected by
+    // spicetify, else any click brings fullscreen back.
+    function enterFullscreen() {
+        if (!document.fullscreenElement) {
+            document.documentElement.requestFullscreen().catch(() => {});
+        }
+    }
+    document.addEventListener("click", enterFullscreen, true);
+    enterFullscreen();
+})();
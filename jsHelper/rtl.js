@@ -0,0 +1,10 @@
+// Forces right-to-left layout. Spotify sets document direction from its
+// display language, so it is set back whenever Spotify changes it.
+(function SpicetifyRTL() {
+    const root = document.documentElement;
+    const force = () => {
+        if (root.dir !== "rtl") root.dir = "rtl";
+    };
+    force();
+    new MutationObserver(force).observe(root, { attributes: true, attributeFilter: ["dir"] });
+})();
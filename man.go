@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// genMan writes roff man pages generated from command, flag and config
+// docs to `dir`: spicetify(1), spicetify-<command>(1) for every command
+// and spicetify-config(5).
+func genMan(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	pages := map[string]string{
+		"spicetify.1":        manMain(),
+		"spicetify-config.5": manConfig(),
+	}
+	for _, doc := range commandDocs {
+		pages["spicetify-"+doc.name+".1"] = manCommand(doc)
+	}
+
+	for name, content := range pages {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	utils.PrintSuccess(fmt.Sprintf("%d man pages are written to %s", len(pages), dir))
+	return nil
+}
+
+// manDate returns date of man pages. SOURCE_DATE_EPOCH is respected for
+// reproducible package builds.
+func manDate() string {
+	date := time.Now()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		date = time.Unix(epoch, 0)
+	}
+	return date.UTC().Format("2006-01-02")
+}
+
+func manHeader(title string, section int, manual string) string {
+	return fmt.Sprintf(".TH %s %d \"%s\" \"spicetify %s\" \"%s\"\n",
+		strings.ToUpper(title), section, manDate(), version, manual)
+}
+
+// roffEscape escapes `text` to be used as roff text lines.
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffBlock renders `text` without filling, so its line breaks and
+// examples are kept as they are.
+func roffBlock(text string) string {
+	return ".nf\n" + roffEscape(text) + "\n.fi\n"
+}
+
+func roffEntry(term, text string) string {
+	return ".TP\n.B " + roffEscape(term) + "\n" + roffBlock(text)
+}
+
+// manSummary returns first sentence of command description, for NAME
+// section.
+func manSummary(doc commandDoc) string {
+	if len(doc.summary) > 0 {
+		return doc.summary
+	}
+
+	var paragraph []string
+	for _, line := range strings.Split(doc.text, "\n") {
+		if len(line) == 0 {
+			break
+		}
+		paragraph = append(paragraph, line)
+	}
+
+	summary := strings.Join(paragraph, " ")
+	if i := strings.IndexAny(summary, ".:"); i != -1 {
+		summary = summary[:i]
+	}
+	return summary
+}
+
+func manMain() string {
+	page := manHeader("spicetify", 1, "User Commands")
+	page += ".SH NAME\nspicetify \\- " + roffEscape(description) + "\n"
+
+	page += ".SH SYNOPSIS\n"
+	for i, line := range usageLines {
+		if i > 0 {
+			page += ".br\n"
+		}
+		page += roffEscape(line) + "\n"
+	}
+
+	page += ".SH DESCRIPTION\n" + roffEscape(description) + ".\n" +
+		"Commands in CHAINABLE COMMANDS can be run one after another in one call, e.g.\n" +
+		".B spicetify backup apply\n"
+
+	for _, chainable := range []bool{true, false} {
+		if chainable {
+			page += ".SH CHAINABLE COMMANDS\n"
+		} else {
+			page += ".SH NON-CHAINABLE COMMANDS\n"
+		}
+		for _, doc := range commandDocs {
+			if doc.chainable == chainable {
+				page += roffEntry(doc.name, doc.text)
+			}
+		}
+	}
+
+	page += ".SH OPTIONS\n"
+	for _, doc := range flagDocs {
+		page += roffEntry(doc.usage, doc.text)
+	}
+
+	page += ".SH EXIT STATUS\n"
+	for _, doc := range exitCodeDocs {
+		page += roffEntry(strconv.Itoa(doc.code), doc.text)
+	}
+
+	page += ".SH SEE ALSO\n"
+	var refs []string
+	for _, doc := range commandDocs {
+		refs = append(refs, ".BR spicetify\\-"+roffEscape(doc.name)+" (1),")
+	}
+	refs = append(refs, ".BR spicetify\\-config (5)")
+	page += strings.Join(refs, "\n") + "\n"
+
+	return page
+}
+
+func manCommand(doc commandDoc) string {
+	name := "spicetify-" + doc.name
+	page := manHeader(name, 1, "User Commands")
+	page += ".SH NAME\n" + roffEscape(name) + " \\- " + roffEscape(manSummary(doc)) + "\n"
+
+	// Usage lines in description make the synopsis.
+	page += ".SH SYNOPSIS\n"
+	var synopsis []string
+	for _, line := range strings.Split(doc.text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "spicetify ") && strings.Contains(line, " "+doc.name) {
+			synopsis = append(synopsis, roffEscape(line))
+		}
+	}
+	if len(synopsis) == 0 {
+		synopsis = append(synopsis, roffEscape("spicetify "+doc.name))
+	}
+	page += strings.Join(synopsis, "\n.br\n") + "\n"
+
+	page += ".SH DESCRIPTION\n" + roffBlock(doc.text)
+	if doc.chainable {
+		page += ".PP\nThis command can be chained with other chainable commands.\n"
+	}
+
+	page += ".SH SEE ALSO\n.BR spicetify (1),\n.BR spicetify\\-config (5)\n"
+	return page
+}
+
+func manConfig() string {
+	page := manHeader("spicetify-config", 5, "File Formats")
+	page += ".SH NAME\nspicetify\\-config \\- spicetify configuration file config\\-xpui.ini\n"
+	page += ".SH DESCRIPTION\n" +
+		"spicetify reads its settings from an INI file. Run\n" +
+		".B spicetify \\-c\n" +
+		"to print its location. Values are changed with\n" +
+		".B spicetify config <key> <value>\n"
+
+	section := ""
+	for _, doc := range configDocs {
+		if doc.section != section {
+			section = doc.section
+			page += ".SH [" + section + "]\n"
+		}
+		page += ".TP\n" + strings.TrimSpace("\\fB"+roffEscape(doc.key)+"\\fR "+roffEscape(doc.values)) + "\n"
+		page += roffBlock(doc.text)
+	}
+
+	page += ".SH SEE ALSO\n.BR spicetify (1)\n"
+	return page
+}
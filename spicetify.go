@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/khanhas/spicetify-cli/src/cmd"
 	"github.com/khanhas/spicetify-cli/src/utils"
@@ -23,7 +26,31 @@ var (
 	extensionFocus = false
 	appFocus       = false
 	noRestart      = false
+	forceRestart   = false
 	liveUpdate     = false
+	dryRun         = false
+	purge          = false
+	applyNow       = false
+	kiosk          = false
+	refresh        = false
+	jsonOutput     = false
+	resume         = false
+	force          = false
+	all            = false
+	// Flags that take a value and their values
+	flagValues = map[string]string{
+		"--exec":       "",
+		"--config":     "",
+		"--keep":       "1",
+		"--log-format": "text",
+		"--select":     "",
+		"--from":       "",
+		"--manifest":   "",
+	}
+	// Flags that can also be used without a value
+	optionalValueFlags = map[string]bool{
+		"--config": true,
+	}
 )
 
 func init() {
@@ -39,8 +66,15 @@ func init() {
 	log.SetOutput(colorable.NewColorableStdout())
 
 	// Separates flags and commands
-	for _, v := range os.Args[1:] {
-		if v[0] == '-' && v != "-1" {
+	args := os.Args[1:]
+	for i := 0; i < len(args); i++ {
+		v := args[i]
+		if len(v) > 1 && v[0] == '-' && v != "-1" {
+			if name, ok := parseValueFlag(args, &i); ok {
+				flags = append(flags, name)
+				continue
+			}
+
 			if v[1] != '-' && len(v) > 2 {
 				for _, char := range v[1:] {
 					flags = append(flags, "-"+string(char))
@@ -56,8 +90,10 @@ func init() {
 	for _, v := range flags {
 		switch v {
 		case "-c", "--config":
-			fmt.Println(cmd.GetConfigPath())
-			os.Exit(0)
+			if v == "-c" || len(flagValues["--config"]) == 0 {
+				fmt.Println(cmd.GetConfigPath())
+				os.Exit(0)
+			}
 		case "-h", "--help":
 			kind := ""
 			if len(commands) > 0 {
@@ -65,7 +101,7 @@ func init() {
 			}
 			if kind == "config" {
 				helpConfig()
-			} else {
+			} else if len(kind) == 0 || !helpCommand(kind) {
 				help()
 			}
 
@@ -81,27 +117,103 @@ func init() {
 			quiet = true
 		case "-n", "--no-restart":
 			noRestart = true
+		case "-r", "--restart":
+			forceRestart = true
 		case "-l", "--live-update":
 			liveUpdate = true
+		case "--dry-run":
+			dryRun = true
+		case "--purge":
+			purge = true
+		case "--apply":
+			applyNow = true
+		case "--kiosk":
+			kiosk = true
+		case "--refresh":
+			refresh = true
+		case "--json":
+			jsonOutput = true
+		case "--resume":
+			resume = true
+		case "--force":
+			force = true
+		case "--all":
+			all = true
 		}
 	}
 
+	switch flagValues["--log-format"] {
+	case "text":
+	case "json":
+		// Stdout only has JSON lines, prompts and other text go to stderr.
+		utils.EnableJSONLog(os.Stdout)
+		log.SetOutput(colorable.NewColorableStderr())
+	default:
+		utils.PrintError(`"--log-format" is either "text" or "json".`)
+		os.Exit(utils.ExitUsage)
+	}
+
+	if selection := flagValues["--select"]; len(selection) > 0 {
+		n, err := strconv.Atoi(selection)
+		if err != nil || n < 1 {
+			utils.PrintError(`"--select" needs a number starting from 1.`)
+			os.Exit(utils.ExitUsage)
+		}
+		cmd.SetCandidateSelection(n)
+	}
+
+	cmd.SetApplyResume(resume)
+	utils.FetchRefresh = refresh
+
 	if quiet {
 		log.SetOutput(ioutil.Discard)
 		os.Stdout = nil
 	}
 
-	cmd.InitConfig(quiet)
+	cmd.InitConfig(quiet, flagValues["--config"])
+
+	if refresh {
+		cmd.RefreshRemoteThemes()
+	}
 
 	if len(commands) < 1 {
+		// Wizard is only offered to someone at a terminal, never in place
+		// of a command.
+		if cmd.IsFreshConfig() && cmd.IsInteractive() &&
+			cmd.ReadAnswer("Looks like it's your first run. Start setup wizard? [Y/n]: ", true, false) {
+			commands = []string{"wizard"}
+			return
+		}
+
 		utils.PrintInfo(`Run "spicetify -h" for commands list.`)
 		os.Exit(0)
 	}
 }
 
 func main() {
+
 	// Non-chainable commands
 	switch commands[0] {
+	case "gen-man":
+		dir := "man"
+		if len(commands) > 1 {
+			dir = commands[1]
+		}
+		if err := genMan(dir); err != nil {
+			utils.Fatal(err)
+		}
+		return
+
+	case "wizard":
+		applied, err := cmd.Wizard(version)
+		if applied {
+			restartSpotify()
+		}
+		if err != nil {
+			utils.Fatal(err)
+		}
+		return
+
 	case "config":
 		commands = commands[1:]
 		if len(commands) == 0 {
@@ -116,6 +228,20 @@ func main() {
 		commands = commands[1:]
 		if len(commands) == 0 {
 			cmd.DisplayColors()
+		} else if commands[0] == "edit" {
+			cmd.EditColorInteractive()
+		} else if commands[0] == "import" {
+			if len(commands) < 2 {
+				utils.PrintError(`Usage: spicetify color import [--from <format>] <file> [<scheme>]`)
+				os.Exit(utils.ExitUsage)
+			}
+			name := ""
+			if len(commands) > 2 {
+				name = commands[2]
+			}
+			if err := cmd.ColorImport(flagValues["--from"], commands[1], name); err != nil {
+				utils.Fatal(err)
+			}
 		} else {
 			cmd.EditColor(commands)
 		}
@@ -152,13 +278,277 @@ func main() {
 	case "upgrade":
 		cmd.Upgrade(version)
 		return
+
+	case "build":
+		if len(commands) < 3 {
+			utils.PrintError(`Usage: spicetify build <Spotify Apps folder> <output folder>`)
+			os.Exit(utils.ExitUsage)
+		}
+		if err := cmd.Build(commands[1], commands[2]); err != nil {
+			if utils.ExitCodeOf(err) == utils.ExitAddonFailed {
+				os.Exit(utils.ExitAddonFailed)
+			}
+			utils.Fatal(err)
+		}
+		return
+
+	case "ext":
+		commands = commands[1:]
+		if len(commands) == 0 || commands[0] == "list" {
+			cmd.ExtList()
+			return
+		}
+
+		if commands[0] == "config" && len(commands) >= 2 {
+			if cmd.ExtConfig(commands[1], commands[2:]) {
+				cmd.InitPaths()
+				cmd.RefreshExtensionSettings()
+			}
+			return
+		}
+
+		if (commands[0] != "enable" && commands[0] != "disable") || len(commands) < 2 {
+			utils.PrintError(`Usage: spicetify ext {enable | disable} <name>...`)
+			os.Exit(utils.ExitUsage)
+		}
+
+		if cmd.ExtToggle(commands[1:], commands[0] == "enable") && applyNow {
+			cmd.InitPaths()
+			if err := cmd.Apply(version); err != nil {
+				os.Exit(utils.ExitCodeOf(err))
+			}
+			restartSpotify()
+		}
+		return
+
+	case "app":
+		commands = commands[1:]
+		if len(commands) == 0 || commands[0] == "list" {
+			cmd.AppList()
+			return
+		}
+
+		if commands[0] == "install" && len(commands) == 2 {
+			cmd.InitPaths()
+			name, err := cmd.AppInstall(commands[1])
+			if err != nil {
+				utils.Fatal(err)
+			}
+			utils.PrintSuccess(`Custom app "` + name + `" is installed and enabled.`)
+		} else if (commands[0] != "enable" && commands[0] != "disable") || len(commands) < 2 {
+			utils.PrintError(`Usage: spicetify app {enable | disable} <name>... or spicetify app install <url | zip>`)
+			os.Exit(utils.ExitUsage)
+		} else if !cmd.AppToggle(commands[1:], commands[0] == "enable") {
+			return
+		}
+
+		cmd.InitPaths()
+		applied, err := cmd.RefreshCustomApps()
+		if err != nil {
+			utils.PrintErrorOf("", err)
+			os.Exit(utils.ExitCodeOf(err))
+		}
+		if applied {
+			utils.PrintSuccess("Custom apps are updated.")
+			restartSpotify()
+		}
+		return
+
+	case "backup":
+		if len(commands) < 2 {
+			break
+		}
+
+		switch commands[1] {
+		case "list":
+			cmd.BackupList()
+			return
+		case "prune":
+			cmd.BackupPrune(keepFlag(), dryRun)
+			return
+		case "export", "import":
+			if len(commands) < 3 {
+				utils.PrintError(`Usage: spicetify backup export <file> [<Spotify version>] or spicetify backup import <file>`)
+				os.Exit(utils.ExitUsage)
+			}
+			var err error
+			if commands[1] == "export" {
+				spotifyVersion := ""
+				if len(commands) > 3 {
+					spotifyVersion = commands[3]
+				}
+				err = cmd.BackupExport(commands[2], spotifyVersion, version)
+			} else {
+				cmd.InitPaths()
+				err = cmd.BackupImport(commands[2], version)
+			}
+			if err != nil {
+				utils.Fatal(err)
+			}
+			return
+		}
+
+	case "snippet":
+		commands = commands[1:]
+		if len(commands) == 0 {
+			commands = []string{"list"}
+		}
+
+		switch {
+		case commands[0] == "list":
+			cmd.SnippetList()
+		case commands[0] == "add" && len(commands) >= 3:
+			cmd.SnippetAdd(commands[1], strings.Join(commands[2:], " "))
+		case commands[0] == "remove" && len(commands) == 2:
+			cmd.SnippetRemove(commands[1])
+		case (commands[0] == "enable" || commands[0] == "disable") && len(commands) >= 2:
+			cmd.SnippetToggle(commands[1:], commands[0] == "enable")
+		default:
+			utils.PrintError(`Usage: spicetify snippet {list | add <name> <code> | remove <name> | enable <name>... | disable <name>...}`)
+			os.Exit(utils.ExitUsage)
+		}
+		return
+
+	case "theme":
+		commands = commands[1:]
+		if len(commands) == 0 || commands[0] == "list" {
+			cmd.ThemeList()
+			return
+		}
+
+		if (commands[0] != "use" && commands[0] != "apply") || len(commands) < 2 {
+			utils.PrintError(`Usage: spicetify theme {use | apply} <name | url> [<scheme>]`)
+			os.Exit(utils.ExitUsage)
+		}
+
+		scheme := ""
+		if len(commands) > 2 {
+			scheme = commands[2]
+		}
+		if err := cmd.ThemeUse(commands[1], scheme); err != nil {
+			utils.Fatal(err)
+		}
+		cmd.InitPaths()
+		if err := cmd.Apply(version); err != nil {
+			os.Exit(utils.ExitCodeOf(err))
+		}
+		restartSpotify()
+		return
+
+	case "storage":
+		commands = commands[1:]
+		if len(commands) == 0 || (commands[0] != "export" && commands[0] != "import") {
+			utils.PrintError(`Usage: spicetify storage {export | import} [<file>]`)
+			os.Exit(utils.ExitUsage)
+		}
+
+		file := "spicetify-storage.json"
+		if len(commands) > 1 {
+			file = commands[1]
+		}
+
+		var err error
+		if commands[0] == "export" {
+			err = cmd.StorageExport(file, all)
+		} else {
+			err = cmd.StorageImport(file, all)
+		}
+		if err != nil {
+			utils.Fatal(err)
+		}
+		return
+
+	case "eval":
+		commands = commands[1:]
+		if len(commands) == 0 {
+			utils.PrintError("No expression given.")
+			os.Exit(utils.ExitUsage)
+		}
+		if err := cmd.Eval(strings.Join(commands, " ")); err != nil {
+			utils.Fatal(err)
+		}
+		return
+
+	case "player":
+		if len(commands) < 2 {
+			utils.PrintError(`Usage: spicetify player {play | pause | next | prev | now-playing} [--json]`)
+			os.Exit(utils.ExitUsage)
+		}
+		if err := cmd.Player(commands[1], jsonOutput); err != nil {
+			utils.Fatal(err)
+		}
+		return
+
+	case "run":
+		commands = commands[1:]
+		if len(commands) == 0 {
+			utils.PrintError("No script file given.")
+			os.Exit(utils.ExitUsage)
+		}
+		for _, script := range commands {
+			if err := cmd.Run(script); err != nil {
+				utils.Fatal(err)
+			}
+		}
+		return
+
+	case "test-patch":
+		commands = commands[1:]
+		if len(commands) == 0 {
+			utils.PrintError("No patch given.")
+			os.Exit(utils.ExitUsage)
+		}
+		replacement := ""
+		if len(commands) > 1 {
+			replacement = commands[1]
+		}
+		if err := cmd.TestPatch(commands[0], replacement); err != nil {
+			utils.Fatal(err)
+		}
+		return
+
+	case "spotify-info":
+		cmd.InitPaths()
+		cmd.SpotifyInfo(jsonOutput)
+		return
+
+	case "diff-extract":
+		commands = commands[1:]
+		if len(commands) == 0 {
+			utils.PrintError("No file given.")
+			os.Exit(utils.ExitUsage)
+		}
+		for _, file := range commands {
+			if err := cmd.DiffExtract(file); err != nil {
+				utils.Fatal(err)
+			}
+		}
+		return
 	}
 
-	utils.PrintBold("spicetify v" + version)
+	if !utils.IsJSONLog() {
+		utils.PrintBold("spicetify v" + version)
+	}
 	cmd.CheckUpgrade(version)
 
 	cmd.InitPaths()
 
+	// Interrupted apply is only offered to resume before commands changing
+	// Spotify. Apply, restore and uninstall deal with it themselves.
+	recoverApply := false
+	for _, v := range commands {
+		if v == "apply" || v == "auto" || v == "restore" || v == "uninstall" {
+			recoverApply = false
+			break
+		}
+		if v == "update" || v == "backup" {
+			recoverApply = true
+		}
+	}
+	if recoverApply && cmd.RecoverInterruptedApply(version) {
+		restartSpotify()
+	}
+
 	// Unchainable commands
 	switch commands[0] {
 	case "watch":
@@ -169,15 +559,69 @@ func main() {
 		if extensionFocus {
 			cmd.WatchExtensions(name, liveUpdate)
 		} else if appFocus {
-			cmd.WatchCustomApp(name, liveUpdate)
+			cmd.WatchCustomApp(name, liveUpdate, flagValues["--exec"])
 		} else {
 			cmd.Watch(liveUpdate)
 		}
 		return
+
+	case "uninstall":
+		cmd.Uninstall(purge)
+		return
+
+	case "spotify-data":
+		commands = commands[1:]
+		if len(commands) == 0 {
+			commands = []string{"show-size"}
+		}
+
+		switch commands[0] {
+		case "show-size":
+			cmd.SpotifyDataSize()
+		case "clear-cache":
+			cmd.SpotifyDataClearCache()
+			restartSpotify()
+		case "set-location":
+			if len(commands) < 2 {
+				utils.PrintError("No location given.")
+				os.Exit(utils.ExitUsage)
+			}
+			if err := cmd.SpotifyDataSetLocation(commands[1]); err != nil {
+				utils.Fatal(err)
+			}
+			restartSpotify()
+		default:
+			utils.PrintError(`Usage: spicetify spotify-data {show-size | clear-cache | set-location <path>}`)
+			os.Exit(utils.ExitUsage)
+		}
+		return
+
+	case "bug-report":
+		output := "spicetify-bug-report.txt"
+		if len(commands) > 1 {
+			output = commands[1]
+		}
+		if err := cmd.BugReport(version, output); err != nil {
+			utils.Fatal(err)
+		}
+		utils.PrintSuccess("Bug report is written to " + output + ". Please review it before attaching it to your issue.")
+		return
+	}
+
+	// Keep output of apply for bug reports.
+	for _, v := range commands {
+		if v == "apply" || v == "auto" {
+			cmd.StartApplyLog()
+			break
+		}
 	}
 
 	// Chainable commands
+	exitCode := utils.ExitOK
 	for _, v := range commands {
+		var err error
+		utils.StartCommand(v)
+
 		switch v {
 		case "backup":
 			cmd.Backup(version)
@@ -186,8 +630,23 @@ func main() {
 			cmd.Clear()
 
 		case "apply":
-			cmd.Apply(version)
-			restartSpotify()
+			if manifest := flagValues["--manifest"]; len(manifest) > 0 {
+				if err = cmd.ApplyManifest(manifest); err != nil {
+					break
+				}
+			}
+			if !force && cmd.IsApplyUpToDate(version) {
+				utils.PrintSuccess(`Nothing changed since last apply. Run with "--force" to apply anyway.`)
+				break
+			}
+			err = cmd.Apply(version)
+			if forceRestart && err == nil {
+				// Relaunch with debugger on to check applied client.
+				cmd.RestartSpotify(utils.DebuggerFlags()...)
+				err = cmd.HealthCheck()
+			} else {
+				restartSpotify()
+			}
 
 		case "update":
 			if extensionFocus {
@@ -200,218 +659,99 @@ func main() {
 			cmd.Restore()
 			restartSpotify()
 
-		case "enable-devtool":
+		case "enable-devtools", "enable-devtool":
 			cmd.SetDevTool(true)
 			restartSpotify()
 
-		case "disable-devtool":
+		case "disable-devtools", "disable-devtool":
 			cmd.SetDevTool(false)
 			restartSpotify()
 
 		case "restart":
-			cmd.RestartSpotify()
+			if kiosk {
+				cmd.RestartKiosk()
+			} else {
+				cmd.RestartSpotify()
+			}
+
+		case "rotate":
+			cmd.Rotate()
+
+		case "clean":
+			cmd.Clean(keepFlag(), dryRun)
+
+		case "verify":
+			if !cmd.Verify() {
+				err = errors.New("verification failed")
+			}
 
 		case "auto":
-			cmd.Auto(version)
+			err = cmd.Auto(version)
 			restartSpotify()
 
 		default:
 			utils.PrintError(`Command "` + v + `" not found.`)
 			utils.PrintInfo(`Run "spicetify -h" for list of valid commands.`)
-			os.Exit(1)
+			os.Exit(utils.ExitUsage)
 		}
-	}
-}
 
-func restartSpotify() {
-	if !noRestart {
-		cmd.RestartSpotify()
+		if err != nil {
+			exitCode = utils.ExitCodeOf(err)
+		}
+		utils.EndCommand(err != nil)
 	}
-}
 
-func help() {
-	utils.PrintBold("spicetify v" + version)
-	log.Println(utils.Bold("USAGE") + "\n" +
-		"spicetify [-q] [-e] [-a] \x1B[4mcommand\033[0m...\n" +
-		"spicetify {-c | --config} | {-v | --version} | {-h | --help}\n\n" +
-		utils.Bold("DESCRIPTION") + "\n" +
-		"Customize Spotify client UI and functionality\n\n" +
-		utils.Bold("CHAINABLE COMMANDS") + `
-backup              Start backup and preprocessing app files.
-
-apply               Apply customization.
-
-update              On default, update theme CSS and colors.
-                    Use with flag "-e" to update extensions.
-
-restore             Restore Spotify to original state.
-
-clear               Clear current backup files.
-
-enable-devtool      Enable Spotify's developer tools.
-                    Hit Ctrl + Shift + I in the client to start using.
-
-disable-devtool     Disable Spotify's developer tools.
-
-watch               Enter watch mode.
-                    On default, update CSS on color.ini or user.css's changes.
-                    Use with flag "-e" to update extensions on changes.
-
-restart             Restart Spotify client.
-
-` + utils.Bold("NON-CHAINABLE COMMANDS") + `
-path                Print path of color, css, extension file or
-                    custom app directory and quit.
-                    1. Print all theme's assests:
-                    spicetify path
-                    2. Print theme's color.ini path:
-                    spicetify path color
-                    3. Print theme's user.css path:
-                    spicetify path css
-                    4. Print theme's assets path:
-                    spicetify path assets
-                    5. Print all extensions path:
-                    spicetify -e path
-                    6. Print extension <name> path:
-                    spicetify -e path <name>
-                    7. Print all custom apps path:
-                    spicetify -a path
-                    8. Print custom app <name> path:
-                    spicetify -a path <name>
-
-config              1. Print all config fields and values:
-                    spicetify config
-                    
-                    2. Print one config field's value:
-                    spicetify config <field>
-
-                    Example usage:
-                    spicetify config color_scheme
-                    spicetify config custom_apps
-                    
-                    3. Change value of one or multiple config fields.
-                    spicetify config <field> <value> [<field> <value> ...]
-
-                    "extensions" and "custom_apps" fields are arrays of values,
-                    so <value> will be appended to those fields' current value.
-                    To remove one of array's values, postfix "-" to <value>.
-
-                    Example usage:
-                    - Enable "disable_sentry" preprocess:
-                    spicetify config disable_sentry 1
-                    - Add extension "myFakeExt.js" to current extensions list:
-                    spicetify config extensions myFakeExt.js
-                    - Remove extension "wrongname.js" from extensions list:
-                    spicetify config extensions wrongname.js-
-                    - Disable "inject_css" and enable "song_page"
-                    spicetify config inject_css 0 song_page 1
-
-color               1. Print all color fields and values. 
-                    spicetify color
-
-                    Color boxes require 24-bit color (True color) supported 
-                    terminal to show colors correctly.
-
-                    2. Change theme's one or multiple color values.
-                    spicetify color <field> <value> [<field> <value> ...]
-
-                    <value> can be in hex or decimal (rrr,ggg,bbb) format.
-
-                    Example usage:
-                    - Change main_bg to ff0000
-                    spicetify color main_bg ff0000
-                    - Change slider_bg to 00ff00 and pressing_fg to 0000ff
-                    spicetify color slider_bg 00ff00 pressing_fg 0000ff
-
-upgrade             Upgrade spicetify latest version
-
-` + utils.Bold("FLAGS") + `
--q, --quiet         Quiet mode (no output). Be careful, dangerous operations
-                    like clear backup, restore will proceed without prompting
-                    permission.
-
--e, --extension     Use with "update", "watch" or "path" command to
-                    focus on extensions.
-
--a, --app           Use with "path" to focus on custom apps.
-
--n, --no-restart    Do not restart Spotify after running command(s), except
-                    "restart" command.
-
--l, --live-update   Use with "watch" command to auto-reload Spotify on change
-
--c, --config        Print config file path and quit
-
--h, --help          Print this help text and quit
-
--v, --version       Print version number and quit
-
-For config information, run "spicetify -h config".
-For more information and bug report: https://github.com/khanhas/spicetify-cli/`)
+	os.Exit(exitCode)
 }
 
-func helpConfig() {
-	utils.PrintBold("CONFIG MEANING")
-	log.Println(utils.Bold("[Setting]") + `
-spotify_path
-    Path to Spotify directory
-
-prefs_path
-    Path to Spotify's "prefs" file
-
-current_theme
-    Name of folder of your theme
-
-color_scheme
-    Color config section name in color.ini file.
-    If color_scheme is blank, first section in color.ini file would be used.
-
-inject_css <0 | 1>
-    Whether custom css from user.css in theme folder is applied
-
-replace_colors <0 | 1>
-    Whether custom colors is applied
-
-spotify_launch_flags
-    Command-line flags used when launching/restarting Spotify.
-    Separate each flag with "|".
-    List of valid flags: https://github.com/khanhas/spicetify-cli/wiki/Spotify-Commandline-Flags
-
-` + utils.Bold("[Preprocesses]") + `
-disable_sentry <0 | 1>
-    Prevents Sentry and Amazon Qualaroo to send console log/error/warning to Spotify developers.
-    Enable if you don't want to catch their attention when developing extension or app.
-
-disable_ui_logging <0 | 1>
-    Various elements logs every user clicks, scrolls.
-    Enable to stop logging and improve user experience.
-
-remove_rtl_rule <0 | 1>
-    To support Arabic and other Right-To-Left language, Spotify added a lot of
-    CSS rules that are obsoleted to Left-To-Right users.
-    Enable to remove all of them and improve render speed.
+// parseValueFlag reads flag that takes a value, in "--flag value" or
+// "--flag=value" form, at args[*index] into flagValues. Index is moved past
+// the value.
+func parseValueFlag(args []string, index *int) (string, bool) {
+	name := args[*index]
+	value := ""
+	hasValue := false
+	if eq := strings.Index(name, "="); eq != -1 {
+		name, value, hasValue = name[:eq], name[eq+1:], true
+	}
 
-expose_apis <0 | 1>
-    Leaks some Spotify's API, functions, objects to Spicetify global object that
-    are useful for making extensions to extend Spotify functionality.
+	if _, ok := flagValues[name]; !ok {
+		return "", false
+	}
 
-disable_upgrade_check <0 | 1>
-    Prevent Spotify checking new version and visually notifying user.
-    [Windows] Note: Automatic update still works if you don't manually delete "SpotifyMigrator.exe" and "SpotifyUpdate.exe".
+	if !hasValue {
+		next := *index + 1
+		hasNext := next < len(args) && (args[next] == "-" || !strings.HasPrefix(args[next], "-"))
+		if !hasNext {
+			if optionalValueFlags[name] {
+				return name, true
+			}
+			utils.PrintError(`Flag "` + name + `" needs a value.`)
+			os.Exit(utils.ExitUsage)
+		}
+		*index = next
+		value = args[next]
+	}
 
-` + utils.Bold("[AdditionalOptions]") + `
-custom_apps <string>
-    List of custom apps. Separate each app with "|".
+	flagValues[name] = value
+	return name, true
+}
 
-extensions <string>
-    List of Javascript files to be executed along with Spotify main script.
-    Separate each extension with "|".
+// keepFlag returns value of "--keep" flag.
+func keepFlag() int {
+	keep, err := strconv.Atoi(flagValues["--keep"])
+	if err != nil || keep < 0 {
+		utils.PrintError(`"--keep" needs a non-negative number.`)
+		os.Exit(utils.ExitUsage)
+	}
 
-home_config <0 | 1>
-    Enable ability to re-arrange sections in Home page.
-    Navigate to Home page, turn "Home config" mode on in Profile menu and hover on sections to show customization buttons.
+	return keep
+}
 
-sidebar_config <0 | 1>
-    Enable ability to stick, hide, re-arrange sidebar items.
-    Turn "Sidebar config" mode on in Profile menu and hover on sidebar items to show customization buttons.`)
+func restartSpotify() {
+	if !noRestart || forceRestart {
+		cmd.RestartSpotify()
+	} else {
+		cmd.RelaunchIfClosed()
+	}
 }
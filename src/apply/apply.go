@@ -1,10 +1,14 @@
 package apply
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/khanhas/spicetify-cli/src/utils"
@@ -16,42 +20,170 @@ type Flag struct {
 	CustomApp     []string
 	SidebarConfig bool
 	HomeConfig    bool
+	// ExtensionSettings maps extension names to their settings
+	ExtensionSettings map[string]map[string]string
+	JsSnippets        []JsSnippet
+	// WindowEffect is Windows backdrop material, "mica" or "acrylic".
+	// Blank means none.
+	WindowEffect string
+	// RTL forces right-to-left layout.
+	RTL bool
+	// UIScale is client zoom factor, exposed to themes as
+	// "--spicetify-ui-scale". 0 means not set.
+	UIScale float64
+	// UIScaleFont scales root font size by UIScale, for when client zoom
+	// level cannot be set.
+	UIScaleFont bool
 }
 
-// AdditionalOptions .
-func AdditionalOptions(appsFolderPath string, flags Flag) {
-	filesToModified := map[string]func(path string, flags Flag){
+// JsSnippet is a small named script from "JsSnippets" config section.
+type JsSnippet struct {
+	Name string
+	Code string
+}
+
+// AddonError describes an extension, custom app or helper that cannot be
+// injected.
+type AddonError struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+func (e *AddonError) Error() string {
+	return e.Kind + ` "` + e.Name + `": ` + e.Err.Error()
+}
+
+// AdditionalOptions injects extensions, custom apps and helpers into
+// Spotify apps files. Failing addons are skipped and returned so the rest
+// can still be applied.
+func AdditionalOptions(appsFolderPath string, flags Flag) []error {
+	filesToModified := map[string]func(path string, flags Flag) []error{
 		filepath.Join(appsFolderPath, "xpui", "index.html"):          htmlMod,
 		filepath.Join(appsFolderPath, "xpui", "xpui.js"):             insertCustomApp,
 		filepath.Join(appsFolderPath, "xpui", "xpui-routes-home.js"): insertHomeConfig,
 	}
 
+	var errs []error
 	for file, call := range filesToModified {
 		if _, err := os.Stat(file); os.IsNotExist(err) {
 			continue
 		}
 
-		call(file, flags)
+		errs = append(errs, safeModify(file, flags, call)...)
 	}
 
 	if flags.SidebarConfig {
-		utils.CopyFile(
+		if err := utils.CopyFile(
 			filepath.Join(utils.GetJsHelperDir(), "sidebarConfig.js"),
-			filepath.Join(appsFolderPath, "xpui", "helper"))
+			filepath.Join(appsFolderPath, "xpui", "helper")); err != nil {
+			errs = append(errs, &AddonError{"helper", "sidebar_config", err})
+		}
 	}
 
 	if flags.HomeConfig {
-		utils.CopyFile(
+		if err := utils.CopyFile(
 			filepath.Join(utils.GetJsHelperDir(), "homeConfig.js"),
-			filepath.Join(appsFolderPath, "xpui", "helper"))
+			filepath.Join(appsFolderPath, "xpui", "helper")); err != nil {
+			errs = append(errs, &AddonError{"helper", "home_config", err})
+		}
+	}
+
+	if len(flags.WindowEffect) > 0 {
+		if err := utils.CopyFile(
+			filepath.Join(utils.GetJsHelperDir(), "windowEffect.css"),
+			filepath.Join(appsFolderPath, "xpui", "helper")); err != nil {
+			errs = append(errs, &AddonError{"helper", "window_effect", err})
+		}
 	}
+
+	if flags.RTL {
+		if err := utils.CopyFile(
+			filepath.Join(utils.GetJsHelperDir(), "rtl.js"),
+			filepath.Join(appsFolderPath, "xpui", "helper")); err != nil {
+			errs = append(errs, &AddonError{"helper", "rtl", err})
+		}
+	}
+
+	if len(flags.JsSnippets) > 0 {
+		if err := jsSnippets(appsFolderPath, flags.JsSnippets); err != nil {
+			errs = append(errs, &AddonError{"helper", "JS snippets", err})
+		}
+	}
+
+	if len(flags.ExtensionSettings) > 0 {
+		if err := ExtensionSettings(appsFolderPath, flags.ExtensionSettings); err != nil {
+			errs = append(errs, &AddonError{"helper", "extension settings", err})
+		}
+	}
+
+	return errs
+}
+
+// ExtensionSettings writes extension settings helper, which exposes
+// settings to extensions as `SpicetifyExtensionSettings[<extension name>]`.
+func ExtensionSettings(appsFolderPath string, settings map[string]map[string]string) error {
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	dest := filepath.Join(appsFolderPath, "xpui", "helper")
+	utils.CheckExistAndCreate(dest)
+	return ioutil.WriteFile(
+		filepath.Join(dest, "extensionSettings.js"),
+		[]byte("window.SpicetifyExtensionSettings = "+string(content)+";\n"),
+		0700)
+}
+
+// jsSnippets concatenates snippets into one helper file. Each snippet runs
+// in its own scope so one failing does not stop others.
+func jsSnippets(appsFolderPath string, snippets []JsSnippet) error {
+	content := ""
+	for _, snippet := range snippets {
+		content += fmt.Sprintf(
+			"// %s\ntry {\n(function() {\n%s\n})();\n} catch (e) {\nconsole.error(%q, e);\n}\n",
+			snippet.Name, snippet.Code, `Spicetify JS snippet "`+snippet.Name+`" failed:`)
+	}
+
+	dest := filepath.Join(appsFolderPath, "xpui", "helper")
+	utils.CheckExistAndCreate(dest)
+	return ioutil.WriteFile(filepath.Join(dest, "jsSnippets.js"), []byte(content), 0700)
+}
+
+// CustomApps injects routes of custom apps into xpui.js alone, so enabled
+// apps can be changed without a full apply. xpui.js has to be unmodified.
+func CustomApps(appsFolderPath string, flags Flag) []error {
+	return safeModify(filepath.Join(appsFolderPath, "xpui", "xpui.js"), flags, insertCustomApp)
+}
+
+// safeModify runs a file modification and turns its panic, if any, into
+// an error instead of aborting the whole process.
+func safeModify(file string, flags Flag, call func(path string, flags Flag) []error) (errs []error) {
+	defer func() {
+		if r := recover(); r != nil {
+			errs = append(errs, &AddonError{"file", filepath.Base(file), fmt.Errorf("%v", r)})
+		}
+	}()
+
+	return call(file, flags)
 }
 
 // UserCSS creates user.css file in "xpui".
 // To not use custom css, set `themeFolder` to blank string
 // To use default color scheme, set `scheme` to `nil`
 func UserCSS(appsFolderPath, themeFolder string, scheme map[string]string) {
-	css := []byte(getColorCSS(scheme) + getUserCSS(themeFolder))
+	userCSS := getUserCSS(themeFolder)
+
+	// @import is only valid before other rules, remote ones left in
+	// user.css are moved to the top.
+	imports := cssImportRe.FindAllString(userCSS, -1)
+	userCSS = cssImportRe.ReplaceAllString(userCSS, "")
+	for i := range imports {
+		imports[i] = strings.TrimSpace(imports[i]) + "\n"
+	}
+
+	css := []byte(strings.Join(imports, "") + getColorCSS(scheme) + userCSS)
 
 	dest := filepath.Join(appsFolderPath, "xpui", "user.css")
 	if err := ioutil.WriteFile(dest, css, 0700); err != nil {
@@ -59,6 +191,24 @@ func UserCSS(appsFolderPath, themeFolder string, scheme map[string]string) {
 	}
 }
 
+// RTLCSS writes right-to-left stylesheet to "xpui/helper/rtl.css": base
+// rules, followed by "rtl.css" of theme when it has one.
+func RTLCSS(appsFolderPath, themeFolder string) error {
+	base, err := ioutil.ReadFile(filepath.Join(utils.GetJsHelperDir(), "rtl.css"))
+	if err != nil {
+		return err
+	}
+
+	css := string(base)
+	if info, err := os.Stat(themeFolder); len(themeFolder) > 0 && err == nil && info.IsDir() {
+		css += bundleCSS(filepath.Join(themeFolder, "rtl.css"), map[string]bool{}, nil)
+	}
+
+	dest := filepath.Join(appsFolderPath, "xpui", "helper")
+	utils.CheckExistAndCreate(dest)
+	return ioutil.WriteFile(filepath.Join(dest, "rtl.css"), []byte(css), 0700)
+}
+
 // UserAsset .
 func UserAsset(appsFolderPath, themeFolder string) {
 	var assetsPath = getAssetsPath(themeFolder)
@@ -68,16 +218,24 @@ func UserAsset(appsFolderPath, themeFolder string) {
 	}
 }
 
-func htmlMod(htmlPath string, flags Flag) {
+func htmlMod(htmlPath string, flags Flag) []error {
 	if len(flags.Extension) == 0 &&
+		len(flags.JsSnippets) == 0 &&
 		!flags.HomeConfig &&
-		!flags.SidebarConfig {
-		return
+		!flags.SidebarConfig &&
+		len(flags.WindowEffect) == 0 &&
+		!flags.RTL &&
+		flags.UIScale == 0 {
+		return nil
 	}
 
 	extensionsHTML := "\n"
 	helperHTML := "\n"
 
+	if len(flags.ExtensionSettings) > 0 {
+		helperHTML += `<script src="helper/extensionSettings.js"></script>` + "\n"
+	}
+
 	if flags.SidebarConfig {
 		helperHTML += `<script defer src="helper/sidebarConfig.js"></script>` + "\n"
 	}
@@ -86,6 +244,28 @@ func htmlMod(htmlPath string, flags Flag) {
 		helperHTML += `<script defer src="helper/homeConfig.js"></script>` + "\n"
 	}
 
+	if len(flags.JsSnippets) > 0 {
+		helperHTML += `<script defer src="helper/jsSnippets.js"></script>` + "\n"
+	}
+
+	if flags.UIScale != 0 {
+		style := fmt.Sprintf("--spicetify-ui-scale: %g;", flags.UIScale)
+		if flags.UIScaleFont {
+			style += " font-size: calc(100% * var(--spicetify-ui-scale));"
+		}
+		helperHTML += "<style>:root { " + style + " }</style>\n"
+	}
+
+	if flags.RTL {
+		helperHTML += `<link rel="stylesheet" href="helper/rtl.css">` + "\n" +
+			`<script src="helper/rtl.js"></script>` + "\n"
+	}
+
+	if len(flags.WindowEffect) > 0 {
+		helperHTML += `<link rel="stylesheet" href="helper/windowEffect.css">` + "\n" +
+			`<script>document.documentElement.dataset.windowEffect = "` + flags.WindowEffect + `";</script>` + "\n"
+	}
+
 	for _, v := range flags.Extension {
 		if strings.HasSuffix(v, ".mjs") {
 			extensionsHTML += `<script defer type="module" src="extensions/` + v + `"></script>` + "\n"
@@ -105,6 +285,18 @@ func htmlMod(htmlPath string, flags Flag) {
 			extensionsHTML+"${0}")
 		return content
 	})
+
+	return nil
+}
+
+// UserCSSPath returns path of CSS file of theme. Single-file themes are a
+// lone CSS file, which is returned as is.
+func UserCSSPath(themeFolder string) string {
+	if info, err := os.Stat(themeFolder); err == nil && !info.IsDir() {
+		return themeFolder
+	}
+
+	return filepath.Join(themeFolder, "user.css")
 }
 
 func getUserCSS(themeFolder string) string {
@@ -112,19 +304,85 @@ func getUserCSS(themeFolder string) string {
 		return ""
 	}
 
-	cssFilePath := filepath.Join(themeFolder, "user.css")
+	cssFilePath := UserCSSPath(themeFolder)
 	_, err := os.Stat(cssFilePath)
 
 	if err != nil {
 		return ""
 	}
 
-	content, err := ioutil.ReadFile(cssFilePath)
+	return bundleCSS(cssFilePath, map[string]bool{}, nil)
+}
+
+// cssImportRe matches @import statements. Groups are quoted or bare
+// target and trailing media query.
+var cssImportRe = regexp.MustCompile(`(?m)^[ \t]*@import\s+(?:url\(\s*)?(?:"([^"]+)"|'([^']+)'|([^\s"'();]+))\s*\)?\s*([^;]*);`)
+
+// CSSImports returns local files imported by CSS file at `path`, directly
+// or through other imported files.
+func CSSImports(path string) []string {
+	var files []string
+	bundleCSS(path, map[string]bool{}, &files)
+	return files
+}
+
+// bundleCSS reads CSS file at `path` and inlines its local @import
+// statements, which can be glob patterns like "./partials/*.css", so
+// themes split in multiple files work with only user.css injected.
+// Remote imports are kept as they are. Imported paths are appended to
+// `files` when it is not nil.
+func bundleCSS(path string, seen map[string]bool, files *[]string) string {
+	content, err := ioutil.ReadFile(path)
 	if err != nil {
 		return ""
 	}
 
-	return string(content)
+	if abs, err := filepath.Abs(path); err == nil {
+		seen[abs] = true
+	}
+
+	dir := filepath.Dir(path)
+	return cssImportRe.ReplaceAllStringFunc(string(content), func(statement string) string {
+		match := cssImportRe.FindStringSubmatch(statement)
+		target := match[1] + match[2] + match[3]
+		media := strings.TrimSpace(match[4])
+
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "//") ||
+			strings.HasPrefix(target, "data:") || strings.HasPrefix(media, "layer") ||
+			strings.HasPrefix(media, "supports") {
+			return statement
+		}
+
+		pattern := filepath.Join(dir, filepath.FromSlash(target))
+		imported, err := filepath.Glob(pattern)
+		if err != nil || len(imported) == 0 {
+			if files == nil {
+				utils.PrintWarning(`CSS import "` + target + `" in ` + path + ` not found.`)
+			}
+			return statement
+		}
+		sort.Strings(imported)
+
+		var bundled strings.Builder
+		for _, file := range imported {
+			abs, err := filepath.Abs(file)
+			if err != nil || seen[abs] {
+				continue
+			}
+
+			if files != nil {
+				*files = append(*files, file)
+			}
+			bundled.WriteString("/* " + filepath.Base(file) + " */\n")
+			bundled.WriteString(bundleCSS(file, seen, files))
+			bundled.WriteString("\n")
+		}
+
+		if len(media) > 0 {
+			return "@media " + media + " {\n" + bundled.String() + "}\n"
+		}
+		return bundled.String()
+	})
 }
 
 func getColorCSS(scheme map[string]string) string {
@@ -142,8 +400,20 @@ func getColorCSS(scheme map[string]string) string {
 		}
 	}
 
-	for k, v := range mergedScheme {
-		parsed := utils.ParseColor(v)
+	// Base colors come first in their usual order, then theme's own colors
+	// sorted by name, so generated CSS is the same on every run.
+	names := append([]string{}, utils.BaseColorOrder...)
+	var extra []string
+	for k := range mergedScheme {
+		if _, ok := utils.BaseColorList[k]; !ok {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	names = append(names, extra...)
+
+	for _, k := range names {
+		parsed := utils.ParseColor(mergedScheme[k])
 		variableList += fmt.Sprintf("    --spice-%s: #%s;\n", k, parsed.Hex())
 		variableRGBList += fmt.Sprintf("    --spice-rgb-%s: %s;\n", k, parsed.RGB())
 	}
@@ -151,7 +421,9 @@ func getColorCSS(scheme map[string]string) string {
 	return fmt.Sprintf(":root {\n%s\n%s\n}\n", variableList, variableRGBList)
 }
 
-func insertCustomApp(jsPath string, flags Flag) {
+func insertCustomApp(jsPath string, flags Flag) []error {
+	var errs []error
+
 	utils.ModifyFile(jsPath, func(content string) string {
 		reactSymbs := utils.FindSymbol(
 			"Custom app React symbols",
@@ -164,13 +436,21 @@ func insertCustomApp(jsPath string, flags Flag) {
 			[]string{
 				`createElement\(([\w\.]+),\{path:"\/collection"\}`})
 
+		customApps := flags.CustomApp
+		if reactSymbs == nil || eleSymbs == nil {
+			for _, app := range customApps {
+				errs = append(errs, &AddonError{"custom app", app, errors.New("cannot find React symbols to inject route")})
+			}
+			customApps = nil
+		}
+
 		appMap := ""
 		appReactMap := ""
 		appEleMap := ""
 		cssEnableMap := ""
 		appNameArray := ""
 
-		for index, app := range flags.CustomApp {
+		for index, app := range customApps {
 			appName := `spicetify-routes-` + app
 			appMap += fmt.Sprintf(`"%s":"%s",`, appName, appName)
 			appNameArray += fmt.Sprintf(`"%s",`, app)
@@ -217,11 +497,15 @@ func insertCustomApp(jsPath string, flags Flag) {
 			`\("li",\{className:\w+\},\w+\(\)\.createElement\(\w+,\{uri:"spotify:user:@:collection",to:"/collection"\}`,
 			'(', ')')
 
-		content = strings.Replace(
-			content,
-			sidebarItemMatch,
-			sidebarItemMatch+",Spicetify._cloneSidebarItem(["+appNameArray+"])",
-			1)
+		if len(sidebarItemMatch) > 0 {
+			content = strings.Replace(
+				content,
+				sidebarItemMatch,
+				sidebarItemMatch+",Spicetify._cloneSidebarItem(["+appNameArray+"])",
+				1)
+		} else if len(customApps) > 0 {
+			errs = append(errs, &AddonError{"custom app", strings.Join(customApps, ", "), errors.New("cannot find sidebar item to add app links")})
+		}
 
 		if flags.SidebarConfig {
 			utils.ReplaceOnce(
@@ -232,11 +516,13 @@ func insertCustomApp(jsPath string, flags Flag) {
 
 		return content
 	})
+
+	return errs
 }
 
-func insertHomeConfig(jsPath string, flags Flag) {
+func insertHomeConfig(jsPath string, flags Flag) []error {
 	if !flags.HomeConfig {
-		return
+		return nil
 	}
 
 	utils.ModifyFile(jsPath, func(content string) string {
@@ -250,6 +536,8 @@ func insertHomeConfig(jsPath string, flags Flag) {
 			`;${1}(()=>{SpicetifyHomeConfig.addToMenu();return SpicetifyHomeConfig.removeMenu;},[])${0}`)
 		return content
 	})
+
+	return nil
 }
 
 func getAssetsPath(themeFolder string) string {
@@ -0,0 +1,175 @@
+package apply
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes `files`, slash separated path to content, into `dir`.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0700)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBundleCSS(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		want        string
+		wantImports []string
+	}{
+		{
+			name:        "local import",
+			files:       map[string]string{"a.css": "a{}"},
+			want:        "/* a.css */\na{}\n",
+			wantImports: []string{"a.css"},
+		},
+		{
+			name:        "url with media query",
+			files:       map[string]string{"main.css": "@import url('a.css') screen;", "a.css": "a{}"},
+			want:        "@media screen {\n/* a.css */\na{}\n}\n",
+			wantImports: []string{"a.css"},
+		},
+		{
+			name: "glob",
+			files: map[string]string{
+				"main.css":    `@import "./parts/*.css";`,
+				"parts/y.css": "y{}",
+				"parts/x.css": "x{}",
+			},
+			want:        "/* x.css */\nx{}\n/* y.css */\ny{}\n",
+			wantImports: []string{"parts/x.css", "parts/y.css"},
+		},
+		{
+			name: "nested import is relative to its file",
+			files: map[string]string{
+				"main.css":  `@import "sub/b.css";`,
+				"sub/b.css": `@import "../c.css";` + "\nb{}",
+				"c.css":     "c{}",
+			},
+			want:        "/* b.css */\n/* c.css */\nc{}\n\nb{}\n",
+			wantImports: []string{"sub/b.css", "c.css"},
+		},
+		{
+			name: "import cycle",
+			files: map[string]string{
+				"main.css": `@import "a.css";`,
+				"a.css":    `@import "main.css";` + "\na{}",
+			},
+			want:        "/* a.css */\n\na{}\n",
+			wantImports: []string{"a.css"},
+		},
+		{
+			name: "kept imports",
+			files: map[string]string{
+				"main.css": `@import url("https://fonts.example.com/a.css");` + "\n" +
+					`@import "missing.css";` + "\n" +
+					`@import "a.css" layer(base);`,
+				"a.css": "a{}",
+			},
+			want: `@import url("https://fonts.example.com/a.css");` + "\n" +
+				`@import "missing.css";` + "\n" +
+				`@import "a.css" layer(base);`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, ok := tt.files["main.css"]; !ok {
+				tt.files["main.css"] = `@import "a.css";`
+			}
+			writeFiles(t, dir, tt.files)
+			main := filepath.Join(dir, "main.css")
+
+			if got := bundleCSS(main, map[string]bool{}, nil); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			var imports []string
+			for _, file := range CSSImports(main) {
+				rel, _ := filepath.Rel(dir, file)
+				imports = append(imports, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(imports, tt.wantImports) {
+				t.Errorf("got imports %q, want %q", imports, tt.wantImports)
+			}
+		})
+	}
+}
+
+func TestHTMLMod(t *testing.T) {
+	const html = "<html><head><!-- spicetify helpers --></head><body></body></html>"
+
+	tests := []struct {
+		name    string
+		flags   Flag
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "nothing enabled",
+			notWant: []string{"<script", "<style"},
+		},
+		{
+			name:  "extensions",
+			flags: Flag{Extension: []string{"a.js", "b.mjs"}},
+			want: []string{
+				`<script defer src="extensions/a.js"></script>`,
+				`<script defer type="module" src="extensions/b.mjs"></script>`,
+			},
+		},
+		{
+			name:    "ui scale",
+			flags:   Flag{UIScale: 1.25},
+			want:    []string{"<style>:root { --spicetify-ui-scale: 1.25; }</style>"},
+			notWant: []string{"font-size"},
+		},
+		{
+			name:  "ui scale with font size",
+			flags: Flag{UIScale: 1.25, UIScaleFont: true},
+			want: []string{
+				"--spicetify-ui-scale: 1.25;",
+				"font-size: calc(100% * var(--spicetify-ui-scale));",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.html")
+			if err := ioutil.WriteFile(path, []byte(html), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if errs := htmlMod(path, tt.flags); len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("%q not found in %s", want, content)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(content), notWant) {
+					t.Errorf("unexpected %q in %s", notWant, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// archiveFormat is bumped when archive layout changes incompatibly.
+const archiveFormat = 1
+
+// archiveInfoName is name of archive entry describing its backup.
+const archiveInfoName = "spicetify-backup.json"
+
+// ArchiveInfo describes backup in an exported archive. Besides it, the
+// archive holds backed up files as compressed objects, like backup store
+// does, under "objects/".
+type ArchiveInfo struct {
+	Format    int       `json:"format"`
+	Exported  time.Time `json:"exported"`
+	Spicetify string    `json:"spicetify"`
+	Manifest  Manifest  `json:"manifest"`
+}
+
+// Export writes backup of Spotify `version` to a single archive file at
+// `dest`, which Import can add to backup store on another machine.
+func Export(backupPath, version, spicetifyVersion, dest string) error {
+	manifest, err := ReadManifest(backupPath, version)
+	if err != nil {
+		return err
+	}
+
+	info, err := json.MarshalIndent(ArchiveInfo{
+		Format:    archiveFormat,
+		Exported:  time.Now(),
+		Spicetify: spicetifyVersion,
+		Manifest:  *manifest,
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dest + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	err = writeArchive(out, backupPath, manifest, info)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest + ".tmp")
+		return err
+	}
+
+	return os.Rename(dest+".tmp", dest)
+}
+
+func writeArchive(out io.Writer, backupPath string, manifest *Manifest, info []byte) error {
+	archive := zip.NewWriter(out)
+
+	entry, err := archive.Create(archiveInfoName)
+	if err != nil {
+		return err
+	}
+	if _, err = entry.Write(info); err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	for _, file := range manifest.Files {
+		for _, hash := range file.Objects() {
+			if written[hash] {
+				continue
+			}
+			written[hash] = true
+
+			// Objects are already compressed.
+			entry, err := archive.CreateHeader(&zip.FileHeader{
+				Name:   "objects/" + hash,
+				Method: zip.Store,
+			})
+			if err != nil {
+				return err
+			}
+
+			object, err := os.Open(objectPath(backupPath, hash))
+			if err != nil {
+				return fmt.Errorf("%s: %s", file.Name, err)
+			}
+			_, err = io.Copy(entry, object)
+			object.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
+
+// Import adds backup in archive at `src`, written by Export, to backup
+// store. Content of every file is verified before anything is stored. When
+// backup of the same Spotify version exists, it's only replaced if
+// `overwrite` returns true.
+func Import(backupPath, src string, overwrite func(version string) bool) (*ArchiveInfo, error) {
+	archive, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	entries := map[string]*zip.File{}
+	for _, entry := range archive.File {
+		entries[entry.Name] = entry
+	}
+
+	infoEntry, ok := entries[archiveInfoName]
+	if !ok {
+		return nil, errors.New("not a spicetify backup archive")
+	}
+
+	info, err := readArchiveInfo(infoEntry)
+	if err != nil {
+		return nil, err
+	}
+	if info.Format != archiveFormat {
+		return nil, fmt.Errorf("unsupported backup archive format %d", info.Format)
+	}
+	if err = validateManifest(&info.Manifest); err != nil {
+		return nil, err
+	}
+	if Exists(backupPath, info.Manifest.Version) && !overwrite(info.Manifest.Version) {
+		return nil, errors.New("backup of Spotify " + info.Manifest.Version + " already exists")
+	}
+
+	for _, file := range info.Manifest.Files {
+		for _, hash := range file.Objects() {
+			entry, ok := entries["objects/"+hash]
+			if !ok {
+				return nil, errors.New(file.Name + " is missing in backup archive")
+			}
+			if err = verifyArchiveObject(entry, hash); err != nil {
+				return nil, errors.New(file.Name + ": " + err.Error())
+			}
+		}
+	}
+
+	utils.CheckExistAndCreate(objectsPath(backupPath))
+	for _, file := range info.Manifest.Files {
+		for _, hash := range file.Objects() {
+			if err = importObject(backupPath, entries["objects/"+hash], hash); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = writeManifest(backupPath, &info.Manifest); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// versionRe matches Spotify versions, which name manifest files.
+var versionRe = regexp.MustCompile(`^[0-9A-Za-z.\-]+$`)
+
+// validateManifest rejects manifest from an archive whose version or file
+// names could point outside backup or Spotify Apps folder.
+func validateManifest(manifest *Manifest) error {
+	if !versionRe.MatchString(manifest.Version) || strings.Trim(manifest.Version, ".") == "" {
+		return errors.New(`invalid Spotify version "` + manifest.Version + `" in backup archive`)
+	}
+	if len(manifest.Files) == 0 {
+		return errors.New("backup archive has no file")
+	}
+
+	for _, file := range manifest.Files {
+		if err := validateFileName(file.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateFileName rejects names of backed up files other than app
+// packages directly in Apps folder.
+func validateFileName(name string) error {
+	if filepath.Base(name) != name || !strings.HasSuffix(name, ".spa") || strings.ContainsAny(name, `/\`) {
+		return errors.New(`invalid file name "` + name + `" in backup`)
+	}
+	return nil
+}
+
+func readArchiveInfo(entry *zip.File) (*ArchiveInfo, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var info ArchiveInfo
+	if err = json.Unmarshal(content, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// verifyArchiveObject checks that object `entry` decompresses to content
+// of `hash`.
+func verifyArchiveObject(entry *zip.File, hash string) error {
+	if path.Base(entry.Name) != hash {
+		return errors.New("invalid object name " + entry.Name)
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	content, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, content); err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != hash {
+		return errors.New("content does not match its hash, archive is corrupted")
+	}
+
+	return nil
+}
+
+// importObject copies object `entry` to objects store, unless the same
+// content is already stored.
+func importObject(backupPath string, entry *zip.File, hash string) error {
+	dest := objectPath(backupPath, hash)
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(dest + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest + ".tmp")
+		return err
+	}
+
+	return os.Rename(dest+".tmp", dest)
+}
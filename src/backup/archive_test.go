@@ -0,0 +1,202 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportTestBackup backs up a small Spotify build and exports it, returning
+// path of archive.
+func exportTestBackup(t *testing.T, root string) string {
+	t.Helper()
+
+	appPath := filepath.Join(root, "Apps")
+	backupPath := filepath.Join(root, "Backup")
+	writeApps(t, appPath, map[string][]byte{
+		"xpui.spa": makeZip(t, []string{"index.html", "xpui.js"}, map[string]string{
+			"index.html": "<html></html>",
+			"xpui.js":    "xpui code",
+		}),
+	})
+	if err := Start(appPath, backupPath, "1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(root, "backup.zip")
+	if err := Export(backupPath, "1.2.3", "2.0.0", dest); err != nil {
+		t.Fatal(err)
+	}
+	return dest
+}
+
+// rewriteArchive copies archive at `src` to a new file, passing info and
+// object content through `edit`.
+func rewriteArchive(t *testing.T, src string, edit func(name string, content []byte) []byte) string {
+	t.Helper()
+
+	reader, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, entry := range reader.File {
+		rc, err := entry.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(rc)
+		rc.Close()
+
+		out, err := writer.Create(entry.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(edit(entry.Name, content))
+	}
+	writer.Close()
+
+	dest := src + ".edited.zip"
+	if err := ioutil.WriteFile(dest, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return dest
+}
+
+// editInfo returns an edit func for rewriteArchive changing archive info.
+func editInfo(t *testing.T, change func(info *ArchiveInfo)) func(string, []byte) []byte {
+	return func(name string, content []byte) []byte {
+		if name != archiveInfoName {
+			return content
+		}
+
+		var info ArchiveInfo
+		if err := json.Unmarshal(content, &info); err != nil {
+			t.Fatal(err)
+		}
+		change(&info)
+		content, _ = json.Marshal(info)
+		return content
+	}
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      func(t *testing.T) func(string, []byte) []byte
+		existing  bool
+		overwrite bool
+		wantErr   string
+	}{
+		{name: "round trip"},
+		{name: "replace existing", existing: true, overwrite: true},
+		{name: "keep existing", existing: true, wantErr: "already exists"},
+		{
+			name: "corrupted object",
+			edit: func(t *testing.T) func(string, []byte) []byte {
+				return func(name string, content []byte) []byte {
+					if !strings.HasPrefix(name, "objects/") {
+						return content
+					}
+					var buf bytes.Buffer
+					writer := gzip.NewWriter(&buf)
+					writer.Write([]byte("tampered"))
+					writer.Close()
+					return buf.Bytes()
+				}
+			},
+			wantErr: "archive is corrupted",
+		},
+		{
+			name: "path in version",
+			edit: func(t *testing.T) func(string, []byte) []byte {
+				return editInfo(t, func(info *ArchiveInfo) { info.Manifest.Version = "../../evil" })
+			},
+			wantErr: "invalid Spotify version",
+		},
+		{
+			name: "path in file name",
+			edit: func(t *testing.T) func(string, []byte) []byte {
+				return editInfo(t, func(info *ArchiveInfo) { info.Manifest.Files[0].Name = "../xpui.spa" })
+			},
+			wantErr: "invalid file name",
+		},
+		{
+			name: "missing object",
+			edit: func(t *testing.T) func(string, []byte) []byte {
+				return editInfo(t, func(info *ArchiveInfo) { info.Manifest.Files[0].Parts[0].Hash = "0000" })
+			},
+			wantErr: "missing in backup archive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			archive := exportTestBackup(t, root)
+			if tt.edit != nil {
+				archive = rewriteArchive(t, archive, tt.edit(t))
+			}
+
+			backupPath := filepath.Join(root, "Imported")
+			if tt.existing {
+				if err := writeManifest(backupPath, &Manifest{Version: "1.2.3"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			info, err := Import(backupPath, archive, func(string) bool { return tt.overwrite })
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Spicetify != "2.0.0" || info.Manifest.Version != "1.2.3" {
+				t.Fatalf("unexpected archive info %+v", info)
+			}
+
+			want, _ := ioutil.ReadFile(filepath.Join(root, "Apps", "xpui.spa"))
+			got, err := ReadFile(backupPath, "1.2.3", "xpui.spa")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatal("imported xpui.spa differs from original")
+			}
+		})
+	}
+}
+
+func TestValidateFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"xpui.spa", true},
+		{"login.spa", true},
+		{"xpui.js", false},
+		{"../xpui.spa", false},
+		{"sub/xpui.spa", false},
+		{`sub\xpui.spa`, false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateFileName(tt.name); (err == nil) != tt.valid {
+			t.Errorf("validateFileName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
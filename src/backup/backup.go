@@ -1,32 +1,640 @@
 package backup
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
-// Start backing up Spotify Apps folder to backupPath
-func Start(appPath, backupPath string) error {
-	return utils.Copy(appPath, backupPath, false, []string{".spa"})
+// Backups are stored in a content-addressed way: every backed up piece of
+// content is compressed once into "objects", named after its hash, and
+// each Spotify version has a manifest in "versions" listing its files.
+// App packages are zip archives whose entries mostly stay the same between
+// Spotify versions, so each entry is stored on its own and is shared by all
+// versions containing it.
+
+// File is a backed up file. Hash is of its whole content. When Skeleton is
+// set, file is stored split: Skeleton object holds everything but data of
+// zip entries, like headers and central directory, and Parts hold entry
+// data. Otherwise whole file is stored as object Hash.
+type File struct {
+	Name     string      `json:"name"`
+	Hash     string      `json:"hash"`
+	Size     int64       `json:"size"`
+	Mode     os.FileMode `json:"mode"`
+	ModTime  time.Time   `json:"mod_time"`
+	Skeleton string      `json:"skeleton,omitempty"`
+	Parts    []Part      `json:"parts,omitempty"`
+}
+
+// Part is data of a zip entry, found at Offset of original file.
+type Part struct {
+	Offset int64  `json:"offset"`
+	Hash   string `json:"hash"`
+}
+
+// Objects returns hashes of objects file is stored in.
+func (f *File) Objects() []string {
+	if len(f.Skeleton) == 0 {
+		return []string{f.Hash}
+	}
+
+	list := []string{f.Skeleton}
+	for _, part := range f.Parts {
+		list = append(list, part.Hash)
+	}
+	return list
+}
+
+// Manifest describes backup of one Spotify version.
+type Manifest struct {
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Files   []File    `json:"files"`
+}
+
+func objectsPath(backupPath string) string {
+	return filepath.Join(backupPath, "objects")
+}
+
+func objectPath(backupPath, hash string) string {
+	return filepath.Join(objectsPath(backupPath), hash)
+}
+
+// ManifestPath returns location of manifest of Spotify `version` backup.
+func ManifestPath(backupPath, version string) string {
+	return filepath.Join(backupPath, "versions", version+".json")
+}
+
+// Exists reports whether backup of Spotify `version` is available.
+func Exists(backupPath, version string) bool {
+	_, err := os.Stat(ManifestPath(backupPath, version))
+	return err == nil
+}
+
+// ReadManifest reads manifest of Spotify `version` backup.
+func ReadManifest(backupPath, version string) (*Manifest, error) {
+	content, err := os.ReadFile(ManifestPath(backupPath, version))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+func writeManifest(backupPath string, manifest *Manifest) error {
+	content, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	path := ManifestPath(backupPath, manifest.Version)
+	utils.CheckExistAndCreate(filepath.Dir(path))
+	if err = os.WriteFile(path+".tmp", content, 0700); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// Start backing up Spotify Apps folder to backupPath. Only files that are
+// not stored by previous backups take up space. New parts are compressed
+// and written in parallel.
+func Start(appPath, backupPath, version string) error {
+	fileList, err := ioutil.ReadDir(appPath)
+	if err != nil {
+		return err
+	}
+
+	manifest := &Manifest{
+		Version: version,
+		Created: time.Now(),
+	}
+
+	var pieces [][]byte
+	queued := map[string]bool{}
+	total := 0
+	for _, file := range fileList {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".spa") {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(appPath, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		stored := File{
+			Name:    file.Name(),
+			Hash:    hashBytes(content),
+			Size:    file.Size(),
+			Mode:    file.Mode().Perm(),
+			ModTime: file.ModTime(),
+		}
+
+		for _, piece := range splitFile(&stored, content) {
+			total++
+			// Same piece in two files is stored once.
+			if hash := hashBytes(piece); !queued[hash] {
+				queued[hash] = true
+				pieces = append(pieces, piece)
+			}
+		}
+
+		manifest.Files = append(manifest.Files, stored)
+	}
+
+	if len(manifest.Files) == 0 {
+		return errors.New("no app file found in " + appPath)
+	}
+
+	var stored int32
+	err = utils.RunParallel(len(pieces), func(i int) error {
+		isNew, err := storeObject(backupPath, pieces[i])
+		if isNew {
+			atomic.AddInt32(&stored, 1)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	reused := total - int(stored)
+
+	if reused > 0 {
+		utils.PrintInfo(fmt.Sprintf("%d of %d unchanged parts of app files are shared with previous backups", reused, total))
+	}
+
+	return writeManifest(backupPath, manifest)
+}
+
+// splitFile sets Skeleton and Parts of `file` from its zip `content` and
+// returns pieces of content to store, in order of file.Objects. Content
+// that is not a well formed zip is stored whole.
+func splitFile(file *File, content []byte) [][]byte {
+	whole := [][]byte{content}
+
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return whole
+	}
+
+	type span struct{ start, end int64 }
+	var spans []span
+	for _, entry := range reader.File {
+		if entry.CompressedSize64 == 0 {
+			continue
+		}
+
+		start, err := entry.DataOffset()
+		if err != nil {
+			return whole
+		}
+		spans = append(spans, span{start, start + int64(entry.CompressedSize64)})
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var skeleton []byte
+	var pieces [][]byte
+	var parts []Part
+	last := int64(0)
+	for _, sp := range spans {
+		if sp.start < last || sp.end > int64(len(content)) {
+			return whole
+		}
+
+		data := content[sp.start:sp.end]
+		skeleton = append(skeleton, content[last:sp.start]...)
+		parts = append(parts, Part{Offset: sp.start, Hash: hashBytes(data)})
+		pieces = append(pieces, data)
+		last = sp.end
+	}
+	skeleton = append(skeleton, content[last:]...)
+
+	file.Skeleton = hashBytes(skeleton)
+	file.Parts = parts
+	return append([][]byte{skeleton}, pieces...)
+}
+
+// joinFile puts content of split `file` back together from `skeleton` and
+// data of its parts, in order.
+func joinFile(file *File, skeleton []byte, parts [][]byte) ([]byte, error) {
+	content := make([]byte, 0, file.Size)
+	used := 0
+	for i, part := range file.Parts {
+		gap := int(part.Offset) - len(content)
+		if gap < 0 || used+gap > len(skeleton) {
+			return nil, errors.New(file.Name + " has invalid parts")
+		}
+
+		content = append(content, skeleton[used:used+gap]...)
+		content = append(content, parts[i]...)
+		used += gap
+	}
+
+	return append(content, skeleton[used:]...), nil
+}
+
+func hashBytes(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// storeObject compresses `content` into objects store, unless the same
+// content is already stored, and reports whether it's newly stored.
+func storeObject(backupPath string, content []byte) (bool, error) {
+	dest := objectPath(backupPath, hashBytes(content))
+	if _, err := os.Stat(dest); err == nil {
+		return false, nil
+	}
+
+	utils.CheckExistAndCreate(objectsPath(backupPath))
+
+	out, err := os.Create(dest + ".tmp")
+	if err != nil {
+		return false, err
+	}
+
+	writer := gzip.NewWriter(out)
+	if _, err = writer.Write(content); err == nil {
+		err = writer.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest + ".tmp")
+		return false, err
+	}
+
+	return true, os.Rename(dest+".tmp", dest)
+}
+
+// openObject returns a reader of decompressed content of object `hash`.
+func openObject(backupPath, hash string) (io.ReadCloser, error) {
+	file, err := os.Open(objectPath(backupPath, hash))
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &objectReader{reader, file}, nil
+}
+
+type objectReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *objectReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
+
+// ReadFile returns content of file `name` in Spotify `version` backup.
+func ReadFile(backupPath, version, name string) ([]byte, error) {
+	manifest, err := ReadManifest(backupPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range manifest.Files {
+		if file.Name == name {
+			return readContent(backupPath, &file)
+		}
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// readContent reads back content of backed up `file` and checks it
+// against its hash.
+func readContent(backupPath string, file *File) ([]byte, error) {
+	var objects [][]byte
+	for _, hash := range file.Objects() {
+		content, err := readObject(backupPath, hash)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, content)
+	}
+
+	content := objects[0]
+	if len(file.Skeleton) > 0 {
+		var err error
+		if content, err = joinFile(file, objects[0], objects[1:]); err != nil {
+			return nil, err
+		}
+	}
+
+	if hashBytes(content) != file.Hash {
+		return nil, errors.New("backup of " + file.Name + " is corrupted")
+	}
+
+	return content, nil
 }
 
-// Extract all SPA files from backupPath to extractPath
-func Extract(backupPath, extractPath string) {
+func readObject(backupPath, hash string) ([]byte, error) {
+	reader, err := openObject(backupPath, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+// FileHash returns hash of file `name` in Spotify `version` backup, as
+// returned by utils.HashFile for original file.
+func FileHash(backupPath, version, name string) (string, error) {
+	manifest, err := ReadManifest(backupPath, version)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range manifest.Files {
+		if file.Name == name {
+			return file.Hash, nil
+		}
+	}
+
+	return "", os.ErrNotExist
+}
+
+// Extract all SPA files from backup of Spotify `version` to extractPath
+func Extract(backupPath, version, extractPath string) {
 	// TODO: "settings" no longer exists in > 1.1.62, remove it when Linux Spotify is updated.
 	for _, app := range []string{"xpui", "login", "settings"} {
-		appPath := filepath.Join(backupPath, app+".spa")
 		appExtractToFolder := filepath.Join(extractPath, app)
 
-		_, err := os.Stat(appPath)
+		spa, err := ReadFile(backupPath, version, app+".spa")
 		if err != nil {
 			continue
 		}
 
-		err = utils.Unzip(appPath, appExtractToFolder)
+		reader, err := zip.NewReader(bytes.NewReader(spa), int64(len(spa)))
 		if err != nil {
 			utils.Fatal(err)
 		}
+
+		if err = utils.UnzipReader(reader, appExtractToFolder); err != nil {
+			utils.Fatal(err)
+		}
+	}
+}
+
+// Restore writes files of Spotify `version` backup directly to appPath.
+func Restore(backupPath, version, appPath string) error {
+	manifest, err := ReadManifest(backupPath, version)
+	if err != nil {
+		return err
+	}
+
+	utils.CheckExistAndCreate(appPath)
+	return utils.RunParallel(len(manifest.Files), func(i int) error {
+		file := manifest.Files[i]
+		return restoreFile(backupPath, file, filepath.Join(appPath, file.Name))
+	})
+}
+
+func restoreFile(backupPath string, file File, dest string) error {
+	if err := validateFileName(file.Name); err != nil {
+		return err
+	}
+
+	content, err := readContent(backupPath, &file)
+	if err != nil {
+		return err
 	}
+
+	mode := file.Mode | 0200
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = out.Write(content); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	os.Chmod(dest, mode)
+	return os.Chtimes(dest, file.ModTime, file.ModTime)
+}
+
+// Remove deletes backup of Spotify `version`, along with stored files no
+// other backup uses.
+func Remove(backupPath, version string) error {
+	err := os.Remove(ManifestPath(backupPath, version))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return collectGarbage(backupPath)
+}
+
+// collectGarbage deletes objects that are not referenced by any manifest.
+func collectGarbage(backupPath string) error {
+	used, err := usedObjects(backupPath, nil)
+	if err != nil {
+		// Do not risk deleting files of a backup we cannot read.
+		return err
+	}
+
+	objects, err := ioutil.ReadDir(objectsPath(backupPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, object := range objects {
+		if !used[object.Name()] {
+			os.Remove(objectPath(backupPath, object.Name()))
+		}
+	}
+
+	return nil
+}
+
+// usedObjects returns set of objects referenced by manifests, except ones
+// of versions in `skip`.
+func usedObjects(backupPath string, skip map[string]bool) (map[string]bool, error) {
+	manifests, err := List(backupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	for _, manifest := range manifests {
+		if skip[manifest.Version] {
+			continue
+		}
+
+		for _, file := range manifest.Files {
+			for _, hash := range file.Objects() {
+				used[hash] = true
+			}
+		}
+	}
+
+	return used, nil
+}
+
+// List returns manifests of all backups, newest first.
+func List(backupPath string) ([]*Manifest, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(backupPath, "versions"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var manifests []*Manifest
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+
+		manifest, err := ReadManifest(backupPath, strings.TrimSuffix(info.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Created.After(manifests[j].Created)
+	})
+
+	return manifests, nil
+}
+
+// ExclusiveSize returns disk space taken by objects that only backups of
+// `versions` use, which is reclaimed when they are removed.
+func ExclusiveSize(backupPath string, versions []string) (int64, error) {
+	skip := map[string]bool{}
+	for _, version := range versions {
+		skip[version] = true
+	}
+
+	used, err := usedObjects(backupPath, skip)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	counted := map[string]bool{}
+	for _, version := range versions {
+		manifest, err := ReadManifest(backupPath, version)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, file := range manifest.Files {
+			for _, hash := range file.Objects() {
+				if used[hash] || counted[hash] {
+					continue
+				}
+				counted[hash] = true
+
+				if info, err := os.Stat(objectPath(backupPath, hash)); err == nil {
+					size += info.Size()
+				}
+			}
+		}
+	}
+
+	return size, nil
+}
+
+// TempFiles returns leftovers of interrupted backup operations.
+func TempFiles(backupPath string) []string {
+	var list []string
+	for _, dir := range []string{backupPath, objectsPath(backupPath), filepath.Join(backupPath, "versions")} {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			name := info.Name()
+			if strings.HasSuffix(name, ".tmp") {
+				list = append(list, filepath.Join(dir, name))
+			}
+		}
+	}
+
+	return list
+}
+
+// MigrateLegacy moves SPA files left in backupPath by older spicetify
+// versions into backup store as Spotify `version` backup.
+func MigrateLegacy(backupPath, version string) error {
+	fileList, err := ioutil.ReadDir(backupPath)
+	if err != nil {
+		return err
+	}
+
+	hasLooseSPA := false
+	for _, file := range fileList {
+		if file.IsDir() {
+			continue
+		}
+
+		if strings.HasSuffix(file.Name(), ".spa") {
+			hasLooseSPA = true
+		}
+	}
+
+	// Without a known version, loose files are left for a later migration.
+	if !hasLooseSPA || len(version) == 0 {
+		return nil
+	}
+
+	if !Exists(backupPath, version) {
+		if err = Start(backupPath, backupPath, version); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range fileList {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".spa") {
+			os.Remove(filepath.Join(backupPath, file.Name()))
+		}
+	}
+
+	return nil
 }
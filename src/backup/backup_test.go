@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeZip returns zip archive of `files`, name to content. Names starting
+// with "stored/" are not compressed.
+func makeZip(t *testing.T, names []string, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range names {
+		method := zip.Deflate
+		if filepath.Dir(name) == "stored" {
+			method = zip.Store
+		}
+
+		entry, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = entry.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSplitJoinFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   func(t *testing.T) []byte
+		wantParts int
+	}{
+		{
+			name: "zip entries",
+			content: func(t *testing.T) []byte {
+				return makeZip(t, []string{"index.html", "xpui.js", "stored/a.css"}, map[string]string{
+					"index.html":   "<html></html>",
+					"xpui.js":      "console.log('xpui')",
+					"stored/a.css": "body{}",
+				})
+			},
+			wantParts: 3,
+		},
+		{
+			name: "empty entries stay in skeleton",
+			content: func(t *testing.T) []byte {
+				return makeZip(t, []string{"folder/", "stored/empty", "a.js"}, map[string]string{
+					"a.js": "a",
+				})
+			},
+			wantParts: 1,
+		},
+		{
+			name: "empty zip",
+			content: func(t *testing.T) []byte {
+				return makeZip(t, nil, nil)
+			},
+			wantParts: 0,
+		},
+		{
+			name: "not a zip",
+			content: func(t *testing.T) []byte {
+				return []byte("plain content")
+			},
+			wantParts: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := tt.content(t)
+			file := File{Name: "xpui.spa", Hash: hashBytes(content), Size: int64(len(content))}
+			pieces := splitFile(&file, content)
+
+			if tt.wantParts < 0 {
+				if len(file.Skeleton) > 0 || len(pieces) != 1 || !bytes.Equal(pieces[0], content) {
+					t.Fatalf("content is split, want it stored whole")
+				}
+				return
+			}
+
+			if len(file.Parts) != tt.wantParts {
+				t.Fatalf("got %d parts, want %d", len(file.Parts), tt.wantParts)
+			}
+
+			objects := file.Objects()
+			if len(objects) != len(pieces) {
+				t.Fatalf("got %d objects for %d pieces", len(objects), len(pieces))
+			}
+			for i, piece := range pieces {
+				if hashBytes(piece) != objects[i] {
+					t.Fatalf("piece %d does not match object hash", i)
+				}
+			}
+
+			joined, err := joinFile(&file, pieces[0], pieces[1:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(joined, content) {
+				t.Fatalf("joined content differs from original")
+			}
+		})
+	}
+}
+
+func writeApps(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	os.RemoveAll(dir)
+	os.MkdirAll(dir, 0700)
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func countObjects(t *testing.T, backupPath string) int {
+	t.Helper()
+	objects, err := ioutil.ReadDir(objectsPath(backupPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(objects)
+}
+
+func TestStoreSharesEntries(t *testing.T) {
+	root := t.TempDir()
+	appPath := filepath.Join(root, "Apps")
+	backupPath := filepath.Join(root, "Backup")
+
+	names := []string{"index.html", "vendor~xpui.js", "xpui.js"}
+	v1 := map[string][]byte{
+		"xpui.spa": makeZip(t, names, map[string]string{
+			"index.html":     "<html></html>",
+			"vendor~xpui.js": "vendor code",
+			"xpui.js":        "xpui code v1",
+		}),
+		"login.spa": makeZip(t, []string{"login.js"}, map[string]string{"login.js": "login"}),
+	}
+	v2 := map[string][]byte{
+		"xpui.spa": makeZip(t, names, map[string]string{
+			"index.html":     "<html></html>",
+			"vendor~xpui.js": "vendor code",
+			"xpui.js":        "xpui code v2",
+		}),
+		"login.spa": v1["login.spa"],
+	}
+
+	writeApps(t, appPath, v1)
+	if err := Start(appPath, backupPath, "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	afterFirst := countObjects(t, backupPath)
+
+	writeApps(t, appPath, v2)
+	if err := Start(appPath, backupPath, "2.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changed xpui.spa only adds its skeleton and changed entry.
+	if added := countObjects(t, backupPath) - afterFirst; added != 2 {
+		t.Fatalf("second backup added %d objects, want 2", added)
+	}
+
+	for version, files := range map[string]map[string][]byte{"1.0": v1, "2.0": v2} {
+		restorePath := filepath.Join(root, "Restore-"+version)
+		if err := Restore(backupPath, version, restorePath); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range files {
+			got, err := ioutil.ReadFile(filepath.Join(restorePath, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("restored %s of %s differs from original", name, version)
+			}
+		}
+	}
+
+	if err := Remove(backupPath, "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(backupPath, "2.0", "xpui.spa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, v2["xpui.spa"]) {
+		t.Fatalf("xpui.spa of 2.0 differs after removing 1.0")
+	}
+	if countObjects(t, backupPath) != afterFirst {
+		t.Fatalf("objects only used by 1.0 are not removed")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	content := []byte("whole file stored by older spicetify")
+
+	tests := []struct {
+		name    string
+		corrupt bool
+		wantErr bool
+	}{
+		{name: "unsplit file"},
+		{name: "corrupted object", corrupt: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backupPath := t.TempDir()
+			stored := content
+			if tt.corrupt {
+				stored = []byte("something else")
+			}
+			if _, err := storeObject(backupPath, stored); err != nil {
+				t.Fatal(err)
+			}
+
+			// Object is named after hash of original content either way.
+			os.Rename(objectPath(backupPath, hashBytes(stored)), objectPath(backupPath, hashBytes(content)))
+			err := writeManifest(backupPath, &Manifest{
+				Version: "1.0",
+				Created: time.Now(),
+				Files:   []File{{Name: "xpui.spa", Hash: hashBytes(content), Size: int64(len(content))}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadFile(backupPath, "1.0", "xpui.spa")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Fatalf("got %q, want %q", got, content)
+			}
+		})
+	}
+}
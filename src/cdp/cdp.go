@@ -0,0 +1,525 @@
+// Package cdp is a Chrome DevTools Protocol client for Spotify page
+// debugger. Commands of one session can be sent concurrently, events are
+// delivered to subscribers, and persistent clients reconnect when Spotify
+// restarts.
+package cdp
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+	"golang.org/x/net/websocket"
+)
+
+var (
+	// ErrDisconnected is returned by calls on a client whose connection is
+	// lost, including calls waiting for response when it's lost.
+	ErrDisconnected = errors.New("connection to Spotify debugger is lost")
+	// ErrClosed is returned by calls on a closed client.
+	ErrClosed = errors.New("debugger client is closed")
+)
+
+// Reconnected is method of event persistent clients send to subscribers of
+// it after connection is re-established. Spotify page is likely reloaded.
+const Reconnected = "Spicetify.reconnected"
+
+// maxReconnectDelay caps delay between reconnect attempts.
+const maxReconnectDelay = 5 * time.Second
+
+// Target is a page, worker or other target debugging server exposes.
+type Target struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	Title                string `json:"title"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// Targets lists targets of Spotify debugging server.
+func Targets() ([]Target, error) {
+	client := http.Client{Timeout: utils.DebuggerTimeout}
+	res, err := client.Get("http://" + utils.DebuggerAddress() + "/json/list")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Target
+	if err = json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// SpotifyTarget returns Spotify page target.
+func SpotifyTarget() (*Target, error) {
+	list, err := Targets()
+	if err != nil {
+		return nil, errors.New("cannot connect to Spotify debugger at " + utils.DebuggerAddress())
+	}
+
+	var found *Target
+	for i, target := range list {
+		if !strings.Contains(target.URL, "spotify") || len(target.WebSocketDebuggerURL) == 0 {
+			continue
+		}
+		if target.Type == "page" {
+			return &list[i], nil
+		}
+		if found == nil {
+			found = &list[i]
+		}
+	}
+
+	if found == nil {
+		return nil, errors.New("no Spotify page found at " + utils.DebuggerAddress())
+	}
+	return found, nil
+}
+
+// Reachable reports whether Spotify page debugger is up.
+func Reachable() bool {
+	_, err := SpotifyTarget()
+	return err == nil
+}
+
+// Event is a notification of an enabled domain, like
+// "Runtime.consoleAPICalled".
+type Event struct {
+	Method string
+	Params json.RawMessage
+}
+
+// ExceptionDetails describes an exception thrown in Spotify page.
+type ExceptionDetails struct {
+	Text      string `json:"text"`
+	URL       string `json:"url"`
+	Exception struct {
+		Description string `json:"description"`
+	} `json:"exception"`
+}
+
+// Message returns exception description, or its text when it has none.
+func (details *ExceptionDetails) Message() string {
+	if len(details.Exception.Description) > 0 {
+		return details.Exception.Description
+	}
+	return details.Text
+}
+
+type message struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type subscription struct {
+	method string
+	events chan Event
+}
+
+// Client is a session with Spotify page debugger.
+type Client struct {
+	persistent bool
+
+	mutex sync.Mutex
+	conn  *websocket.Conn
+	// connected is closed once conn is set. It's replaced when connection
+	// is lost, so calls can wait for reconnection.
+	connected     chan struct{}
+	closed        bool
+	nextID        int64
+	pending       map[int64]chan *message
+	subscriptions []*subscription
+	// domains enabled by Enable, which are enabled again on reconnect.
+	domains []string
+
+	sendMutex sync.Mutex
+}
+
+// Dial connects to Spotify page debugger. Failed attempts are retried as
+// set by utils.DebuggerRetries and utils.DebuggerBackoff.
+func Dial() (*Client, error) {
+	return dial(false)
+}
+
+// DialPersistent connects like Dial, but client reconnects in background
+// when connection is lost, e.g. when Spotify restarts, until it's closed.
+func DialPersistent() (*Client, error) {
+	return dial(true)
+}
+
+func dial(persistent bool) (*Client, error) {
+	c := &Client{
+		persistent: persistent,
+		connected:  make(chan struct{}),
+		pending:    map[int64]chan *message{},
+	}
+
+	delay := utils.DebuggerBackoff
+	for attempt := 0; ; attempt++ {
+		conn, err := connect()
+		if err == nil {
+			c.attach(conn)
+			return c, nil
+		}
+
+		if attempt >= utils.DebuggerRetries {
+			return nil, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// connect opens Websocket to Spotify page. Target is looked up each time
+// since its URL changes when Spotify restarts.
+func connect() (*websocket.Conn, error) {
+	target, err := SpotifyTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := websocket.NewConfig(target.WebSocketDebuggerURL, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: utils.DebuggerTimeout}
+
+	return websocket.DialConfig(config)
+}
+
+// attach starts using `conn` and reading messages from it.
+func (c *Client) attach(conn *websocket.Conn) {
+	c.mutex.Lock()
+	if c.closed {
+		// Closed while reconnecting.
+		c.mutex.Unlock()
+		conn.Close()
+		return
+	}
+	c.conn = conn
+	close(c.connected)
+	c.mutex.Unlock()
+
+	go c.read(conn)
+}
+
+func (c *Client) read(conn *websocket.Conn) {
+	for {
+		var msg message
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			c.lost(conn)
+			return
+		}
+
+		c.mutex.Lock()
+		if msg.ID != 0 {
+			if pending, ok := c.pending[msg.ID]; ok {
+				delete(c.pending, msg.ID)
+				pending <- &msg
+			}
+		} else if len(msg.Method) > 0 {
+			c.dispatch(Event{Method: msg.Method, Params: msg.Params})
+		}
+		c.mutex.Unlock()
+	}
+}
+
+// dispatch sends event to its subscribers. Events are dropped for
+// subscribers that don't keep up, so reading is never blocked. Client
+// mutex must be held.
+func (c *Client) dispatch(event Event) {
+	for _, sub := range c.subscriptions {
+		if sub.method != event.Method {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// lost handles connection drop: pending calls fail, then persistent client
+// reconnects while others end their subscriptions.
+func (c *Client) lost(conn *websocket.Conn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != conn {
+		return
+	}
+	conn.Close()
+	c.conn = nil
+	c.connected = make(chan struct{})
+
+	for id, pending := range c.pending {
+		close(pending)
+		delete(c.pending, id)
+	}
+
+	if c.closed || !c.persistent {
+		c.endSubscriptions()
+		return
+	}
+
+	go c.reconnect()
+}
+
+func (c *Client) reconnect() {
+	delay := utils.DebuggerBackoff
+	for {
+		time.Sleep(delay)
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+
+		c.mutex.Lock()
+		closed := c.closed
+		c.mutex.Unlock()
+		if closed {
+			return
+		}
+
+		conn, err := connect()
+		if err != nil {
+			continue
+		}
+
+		c.attach(conn)
+
+		c.mutex.Lock()
+		domains := append([]string{}, c.domains...)
+		c.mutex.Unlock()
+		for _, domain := range domains {
+			c.Call(domain+".enable", nil, nil)
+		}
+
+		c.mutex.Lock()
+		c.dispatch(Event{Method: Reconnected})
+		c.mutex.Unlock()
+		return
+	}
+}
+
+// waitConnected returns current connection. Persistent client that is
+// reconnecting waits for it as long as Dial would retry.
+func (c *Client) waitConnected() (*websocket.Conn, error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, ErrClosed
+	}
+	if c.conn != nil || !c.persistent {
+		conn := c.conn
+		c.mutex.Unlock()
+		if conn == nil {
+			return nil, ErrDisconnected
+		}
+		return conn, nil
+	}
+	connected := c.connected
+	c.mutex.Unlock()
+
+	select {
+	case <-connected:
+		return c.waitConnected()
+	case <-time.After(utils.DebuggerTimeout * time.Duration(utils.DebuggerRetries+1)):
+		return nil, ErrDisconnected
+	}
+}
+
+// Call sends command `method` with `params`, which can be nil, and waits
+// for its response. When `result` is not nil, response result is decoded
+// into it. Persistent client sends command again once reconnected when
+// connection is lost before response arrives.
+func (c *Client) Call(method string, params interface{}, result interface{}) error {
+	err := c.call(method, params, result)
+	if err == ErrDisconnected && c.persistent {
+		err = c.call(method, params, result)
+	}
+	return err
+}
+
+func (c *Client) call(method string, params interface{}, result interface{}) error {
+	conn, err := c.waitConnected()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.nextID++
+	id := c.nextID
+	response := make(chan *message, 1)
+	c.pending[id] = response
+	c.mutex.Unlock()
+
+	request := map[string]interface{}{
+		"id":     id,
+		"method": method,
+	}
+	if params != nil {
+		request["params"] = params
+	}
+
+	c.sendMutex.Lock()
+	err = websocket.JSON.Send(conn, request)
+	c.sendMutex.Unlock()
+	if err != nil {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return err
+	}
+
+	msg, ok := <-response
+	if !ok {
+		return ErrDisconnected
+	}
+	if msg.Error != nil {
+		return errors.New(msg.Error.Message)
+	}
+	if result != nil && len(msg.Result) > 0 {
+		return json.Unmarshal(msg.Result, result)
+	}
+	return nil
+}
+
+// Enable enables events of `domain`, e.g. "Runtime". Persistent client
+// enables it again after reconnecting.
+func (c *Client) Enable(domain string) error {
+	if err := c.Call(domain+".enable", nil, nil); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.domains = append(c.domains, domain)
+	c.mutex.Unlock()
+	return nil
+}
+
+// Subscribe returns channel of events named `method`, and a function to
+// stop receiving them. Channel is closed when client is closed or, unless
+// client is persistent, when connection is lost. Domain of events has to be
+// enabled to receive them.
+func (c *Client) Subscribe(method string) (<-chan Event, func()) {
+	sub := &subscription{
+		method: method,
+		events: make(chan Event, 256),
+	}
+
+	c.mutex.Lock()
+	if c.closed || c.conn == nil && !c.persistent {
+		close(sub.events)
+	} else {
+		c.subscriptions = append(c.subscriptions, sub)
+	}
+	c.mutex.Unlock()
+
+	unsubscribe := func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for i, s := range c.subscriptions {
+			if s == sub {
+				c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+				close(sub.events)
+				return
+			}
+		}
+	}
+
+	return sub.events, unsubscribe
+}
+
+// endSubscriptions closes all subscriptions. Client mutex must be held.
+func (c *Client) endSubscriptions() {
+	for _, sub := range c.subscriptions {
+		close(sub.events)
+	}
+	c.subscriptions = nil
+}
+
+// Close ends session and its subscriptions.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.endSubscriptions()
+
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Evaluate evaluates a Javascript expression in Spotify page and returns
+// its result as JSON. Promises are awaited. Result is nil when expression
+// evaluates to undefined.
+func (c *Client) Evaluate(expression string) (json.RawMessage, error) {
+	var res struct {
+		Result struct {
+			Type        string          `json:"type"`
+			Value       json.RawMessage `json:"value"`
+			Description string          `json:"description"`
+		} `json:"result"`
+		ExceptionDetails *ExceptionDetails `json:"exceptionDetails"`
+	}
+
+	err := c.Call("Runtime.evaluate", map[string]interface{}{
+		"expression":    expression,
+		"awaitPromise":  true,
+		"returnByValue": true,
+		// Lets expression use APIs that need user gesture, e.g. fullscreen.
+		"userGesture": true,
+		// Allows top-level await and re-declaring let/const,
+		// like DevTools console does.
+		"replMode": true,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.ExceptionDetails != nil {
+		return nil, errors.New(res.ExceptionDetails.Message())
+	}
+
+	result := res.Result
+	if result.Type == "undefined" {
+		return nil, nil
+	}
+
+	// Values that cannot be serialized, e.g. functions, only come with a
+	// description.
+	if len(result.Value) == 0 {
+		return json.Marshal(result.Description)
+	}
+
+	return result.Value, nil
+}
+
+// Reload reloads Spotify page.
+func (c *Client) Reload() error {
+	return c.Call("Page.reload", nil, nil)
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// installManifest is the part of custom app manifest.json checked on
+// install. "build" is an optional shell command that produces index.js.
+type installManifest struct {
+	Name     json.RawMessage `json:"name"`
+	Build    string          `json:"build"`
+	Subfiles []string        `json:"subfiles"`
+}
+
+var appNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// AppInstall installs custom app from `source`, which is a Github
+// repository URL, a zip archive URL or a local zip file, into user
+// CustomApps folder, runs its build command if manifest declares one and
+// enables it. It returns name of installed app.
+func AppInstall(source string) (string, error) {
+	name, err := installApp(source)
+	if err != nil {
+		return "", err
+	}
+
+	AppToggle([]string{name}, true)
+	return name, nil
+}
+
+// installApp installs custom app from `source` without enabling it.
+func installApp(source string) (string, error) {
+	data, name, subPath, err := readAppArchive(source)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", errors.New("not a valid zip archive")
+	}
+
+	temp, err := ioutil.TempDir("", "spicetify-app-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(temp)
+
+	if err = utils.UnzipReader(reader, temp); err != nil {
+		return "", err
+	}
+
+	folder := filepath.Join(archiveRoot(temp), filepath.FromSlash(subPath))
+	if !isAppFolder(folder) {
+		folder = onlyAppSubfolder(folder)
+		if !isAppFolder(folder) {
+			return "", errors.New("no manifest.json found in custom app archive")
+		}
+		name = filepath.Base(folder)
+	}
+	name = strings.Trim(appNameRe.ReplaceAllString(name, "-"), "-.")
+	if len(name) == 0 {
+		return "", errors.New("cannot determine custom app name")
+	}
+
+	manifest, err := readInstallManifest(folder)
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(userAppsFolder, name)
+	if _, err := os.Stat(dest); err == nil {
+		if !ReadAnswer(`Custom app "`+name+`" is already installed. Replace it? [y/N]: `, false, true) {
+			return "", errors.New("installation cancelled")
+		}
+	}
+
+	if len(manifest.Build) > 0 {
+		// Build command comes from downloaded manifest, never run it
+		// without user explicitly agreeing, even in quiet mode.
+		utils.PrintWarning(`Custom app "` + name + `" wants to run build command:`)
+		log.Println("    " + manifest.Build)
+		if !ReadAnswer("Run it? [y/N]: ", false, false) {
+			return "", errors.New("build command declined, custom app is not installed")
+		}
+
+		utils.PrintBold(`Building custom app "` + name + `":`)
+		if err := runShellCommand(manifest.Build, folder); err != nil {
+			return "", errors.New("build failed: " + err.Error())
+		}
+		utils.PrintGreen("OK")
+	}
+
+	if _, err := os.Stat(filepath.Join(folder, "index.js")); err != nil {
+		return "", errors.New("index.js not found in custom app")
+	}
+	for _, subfile := range manifest.Subfiles {
+		if _, err := os.Stat(filepath.Join(folder, subfile)); err != nil {
+			utils.PrintWarning(`Subfile "` + subfile + `" declared in manifest.json is not found.`)
+		}
+	}
+
+	os.RemoveAll(dest)
+	utils.CheckExistAndCreate(userAppsFolder)
+	if err := utils.Copy(folder, dest, true, nil); err != nil {
+		os.RemoveAll(dest)
+		return "", err
+	}
+
+	return name, nil
+}
+
+// readAppArchive reads zip archive of custom app from `source` and
+// returns it along with default app name and path of app inside archive.
+func readAppArchive(source string) (data []byte, name, subPath string, err error) {
+	if !isRemoteTheme(source) {
+		data, err = ioutil.ReadFile(source)
+		name = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+		return data, name, "", err
+	}
+
+	archiveURL, name, subPath, err := resolveAppSource(source)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	utils.PrintBold("Downloading custom app " + source + ":")
+	res, err := utils.Download(archiveURL)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", "", errors.New("cannot download custom app: " + res.Status)
+	}
+
+	data, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", "", err
+	}
+	utils.PrintGreen("OK")
+
+	return data, name, subPath, nil
+}
+
+// resolveAppSource returns URL to download custom app archive from remote
+// `source`, default app name and path of app inside archive.
+func resolveAppSource(source string) (archiveURL, name, subPath string, err error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	archiveURL = source
+	name = strings.TrimSuffix(filepath.Base(u.Path), filepath.Ext(u.Path))
+	if u.Host == "github.com" {
+		match := githubRepoRe.FindStringSubmatch(u.Path)
+		if match == nil {
+			return "", "", "", errors.New("invalid Github repository URL")
+		}
+		ref := match[3]
+		if len(ref) == 0 {
+			ref = "HEAD"
+		}
+		archiveURL = "https://codeload.github.com/" + match[1] + "/" + match[2] + "/zip/" + ref
+		subPath = strings.Trim(match[4], "/")
+		name = match[2]
+		if len(subPath) > 0 {
+			name = filepath.Base(subPath)
+		}
+	} else if !strings.HasSuffix(strings.ToLower(u.Path), ".zip") {
+		return "", "", "", errors.New("unsupported custom app source, use a Github repository or zip archive URL")
+	}
+
+	return archiveURL, name, subPath, nil
+}
+
+// readInstallManifest parses and validates manifest.json in `folder`.
+func readInstallManifest(folder string) (*installManifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(folder, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest installManifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, errors.New("invalid manifest.json: " + err.Error())
+	}
+
+	// Name is either a string or a map of localized names.
+	var name string
+	var localized map[string]string
+	if json.Unmarshal(manifest.Name, &name) != nil && json.Unmarshal(manifest.Name, &localized) != nil ||
+		len(name) == 0 && len(localized) == 0 {
+		return nil, errors.New(`invalid manifest.json: "name" is missing`)
+	}
+
+	return &manifest, nil
+}
+
+func isAppFolder(folder string) bool {
+	_, err := os.Stat(filepath.Join(folder, "manifest.json"))
+	return err == nil
+}
+
+// onlyAppSubfolder returns the custom app folder in `dir` when there is
+// exactly one, else `dir` itself.
+func onlyAppSubfolder(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return dir
+	}
+
+	found := ""
+	for _, file := range files {
+		folder := filepath.Join(dir, file.Name())
+		if !file.IsDir() || !isAppFolder(folder) {
+			continue
+		}
+		if len(found) > 0 {
+			return dir
+		}
+		found = folder
+	}
+
+	if len(found) == 0 {
+		return dir
+	}
+	return found
+}
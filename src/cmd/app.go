@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/apply"
+	spotifystatus "github.com/khanhas/spicetify-cli/src/status/spotify"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// AppList prints enabled custom apps and other available ones.
+func AppList() {
+	enabled := featureSection.Key("custom_apps").Strings("|")
+	isEnabled := map[string]bool{}
+
+	utils.PrintBold("Enabled:")
+	if len(enabled) == 0 {
+		log.Println("    (none)")
+	}
+	for _, name := range enabled {
+		isEnabled[name] = true
+		if err := validateCustomApp(name); err != nil {
+			log.Println("    " + name + utils.Red(" ("+err.Error()+")"))
+		} else {
+			log.Println("    " + name)
+		}
+	}
+
+	utils.PrintBold("Available:")
+	available := 0
+	for _, name := range availableCustomApps() {
+		if !isEnabled[name] {
+			log.Println("    " + name)
+			available++
+		}
+	}
+	if available == 0 {
+		log.Println("    (none)")
+	}
+}
+
+// availableCustomApps returns names of custom app folders in user and
+// executable folders.
+func availableCustomApps() []string {
+	seen := map[string]bool{}
+	var list []string
+
+	for _, folder := range []string{userAppsFolder, filepath.Join(utils.GetExecutableDir(), "CustomApps")} {
+		fileList, err := ioutil.ReadDir(folder)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range fileList {
+			name := file.Name()
+			if !file.IsDir() || seen[name] {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(folder, name, "index.js")); err != nil {
+				continue
+			}
+			seen[name] = true
+			list = append(list, name)
+		}
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// validateCustomApp checks custom app `name` has its folder, index.js and
+// manifest.json.
+func validateCustomApp(name string) error {
+	appPath, err := getCustomAppPath(name)
+	if err != nil {
+		return errors.New("not found")
+	}
+
+	for _, file := range []string{"index.js", "manifest.json"} {
+		if _, err := os.Stat(filepath.Join(appPath, file)); err != nil {
+			return errors.New(file + " not found")
+		}
+	}
+
+	return nil
+}
+
+// AppToggle enables or disables custom apps in `names`. It returns whether
+// config is changed.
+func AppToggle(names []string, enable bool) bool {
+	key := featureSection.Key("custom_apps")
+	changed := false
+
+	for _, name := range names {
+		if enable {
+			if err := validateCustomApp(name); err != nil {
+				utils.PrintErrorOf(`Custom app "`+name+`": `, err)
+				continue
+			}
+			changed = addToList(key, name) || changed
+		} else {
+			changed = removeFromList(key, name) || changed
+		}
+	}
+
+	if changed {
+		cfg.Write()
+	}
+
+	return changed
+}
+
+// RefreshCustomApps re-runs custom app injection alone: xpui.js is reset
+// from extracted files, then enabled apps are pushed and injected again.
+// It returns false when Spotify is not applied yet, so nothing is done.
+func RefreshCustomApps() (bool, error) {
+	if !spotifystatus.Get(appDestPath).IsApplied() {
+		return false, nil
+	}
+
+	checkStates()
+	InitSetting()
+
+	source := rawFolder
+	if replaceColors {
+		source = themedFolder
+	}
+
+	xpuiDest := filepath.Join(appDestPath, "xpui")
+	if err := utils.CopyFile(filepath.Join(source, "xpui", "xpui.js"), xpuiDest); err != nil {
+		return true, err
+	}
+
+	// Remove files of disabled apps.
+	written := []string{filepath.Join(xpuiDest, "xpui.js")}
+	if matches, err := filepath.Glob(filepath.Join(xpuiDest, "spicetify-routes-*")); err == nil {
+		for _, match := range matches {
+			os.Remove(match)
+		}
+		written = append(written, matches...)
+	}
+
+	utils.PrintBold(`Transferring custom apps:`)
+	customAppsList, errs := pushApps(featureSection.Key("custom_apps").Strings("|")...)
+	printStageResult(errs)
+
+	utils.PrintBold(`Injecting custom apps:`)
+	injectErrs := apply.CustomApps(appDestPath, apply.Flag{
+		CustomApp:     customAppsList,
+		SidebarConfig: featureSection.Key("sidebar_config").MustBool(false),
+	})
+	for _, err := range injectErrs {
+		utils.PrintWarning(err.Error() + ". Skipped.")
+	}
+	printStageResult(injectErrs)
+	errs = append(errs, injectErrs...)
+
+	if err := patchFile("xpui.js"); err != nil {
+		errs = append(errs, err)
+	}
+	updateAppliedRecord(append(written, appOutputFiles(customAppsList...)...)...)
+
+	if len(errs) > 0 {
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return true, errors.New(strings.Join(messages, "; "))
+	}
+
+	return true, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/khanhas/spicetify-cli/src/backup"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// applyInputsHash hashes everything apply output depends on: spicetify
+// version and helpers, config, theme files, enabled extensions with their
+// settings, enabled custom apps and backed up Spotify build.
+func applyInputsHash(spicetifyVersion string) (string, error) {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "spicetify %s\n", spicetifyVersion)
+
+	backupVersion := backupSection.Key("version").String()
+	spotifyBuild, _ := backup.FileHash(backupFolder, backupVersion, "xpui.spa")
+	fmt.Fprintf(hasher, "spotify %s %s\n", backupVersion, spotifyBuild)
+
+	if err := hashInput(hasher, GetConfigPath()); err != nil {
+		return "", err
+	}
+
+	if err := hashInput(hasher, utils.GetJsHelperDir()); err != nil {
+		return "", err
+	}
+
+	if len(themeFolder) > 0 {
+		if err := hashInput(hasher, themeFolder); err != nil {
+			return "", err
+		}
+	}
+
+	for _, name := range expandExtensionList(featureSection.Key("extensions").Strings("|")) {
+		extPath, err := getExtensionPath(name)
+		if err != nil {
+			return "", err
+		}
+		if err = hashInput(hasher, extPath); err != nil {
+			return "", err
+		}
+
+		settingsPath := extensionSettingsPath(filepath.Base(name))
+		if _, err = os.Stat(settingsPath); err == nil {
+			if err = hashInput(hasher, settingsPath); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for _, name := range featureSection.Key("custom_apps").Strings("|") {
+		appPath, err := getCustomAppPath(name)
+		if err != nil {
+			return "", err
+		}
+		if err = hashInput(hasher, appPath); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// hashInput writes path and hash of file at `path`, or of each file in it
+// when it is a folder, to `hasher`. Symlinks are followed.
+func hashInput(hasher hash.Hash, path string) error {
+	root, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			return hashInput(hasher, file)
+		}
+
+		fileHash, err := utils.HashFile(file)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hasher, "%s %s\n", filepath.ToSlash(file), fileHash)
+		return nil
+	})
+}
+
+// IsApplyUpToDate reports whether last apply succeeded with the same inputs
+// and its files in Spotify are intact, so applying again changes nothing.
+func IsApplyUpToDate(spicetifyVersion string) bool {
+	if _, err := os.Stat(applyJournalPath()); err == nil {
+		return false
+	}
+
+	record, err := readAppliedRecord()
+	if err != nil || len(record.Inputs) == 0 {
+		return false
+	}
+
+	if record.SpotifyVersion != utils.GetSpotifyVersion(prefsPath) {
+		return false
+	}
+
+	InitSetting()
+	inputs, err := applyInputsHash(spicetifyVersion)
+	if err != nil || inputs != record.Inputs {
+		return false
+	}
+
+	modified, missing, reinstalled := appliedChanges(record)
+	return len(modified) == 0 && len(missing) == 0 && len(reinstalled) == 0
+}
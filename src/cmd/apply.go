@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/khanhas/spicetify-cli/src/apply"
@@ -14,19 +16,25 @@ import (
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
-// Apply .
-func Apply(spicetifyVersion string) {
+// Apply copies preprocessed assets to Spotify and injects theme, extensions
+// and custom apps. Addons that cannot be injected are skipped; an error
+// summarizing them is returned after everything else is applied.
+func Apply(spicetifyVersion string) error {
 	checkStates()
 	InitSetting()
+	checkWritePermission()
+	closeForWrite()
+
+	journal, interrupted := openApplyJournal()
 
 	// Copy raw assets to Spotify Apps folder if Spotify is never applied
-	// before.
+	// before, or last apply was interrupted and left half-written files.
 	// extractedStock is for preventing copy raw assets 2 times when
 	// replaceColors is false.
-	extractedStock := false
-	if !spotifystatus.Get(appDestPath).IsApplied() {
+	extractedStock := journal.done("raw")
+	if !extractedStock && (interrupted || !spotifystatus.Get(appDestPath).IsApplied()) {
 		utils.PrintBold(`Copying raw assets:`)
-		if err := os.RemoveAll(appDestPath); err != nil {
+		if err := utils.RetryLocked(func() error { return os.RemoveAll(appDestPath) }); err != nil {
 			utils.Fatal(err)
 		}
 		if err := utils.Copy(rawFolder, appDestPath, true, nil); err != nil {
@@ -34,67 +42,127 @@ func Apply(spicetifyVersion string) {
 		}
 		utils.PrintGreen("OK")
 		extractedStock = true
+		journal.complete("raw")
 	}
 
-	if replaceColors {
-		utils.PrintBold(`Overwriting themed assets:`)
-		if err := utils.Copy(themedFolder, appDestPath, true, nil); err != nil {
-			utils.Fatal(err)
+	if !journal.done("assets") {
+		if replaceColors {
+			utils.PrintBold(`Overwriting themed assets:`)
+			if err := utils.Copy(themedFolder, appDestPath, true, nil); err != nil {
+				utils.Fatal(err)
+			}
+			utils.PrintGreen("OK")
+		} else if !extractedStock {
+			utils.PrintBold(`Overwriting raw assets:`)
+			if err := utils.Copy(rawFolder, appDestPath, true, nil); err != nil {
+				utils.Fatal(err)
+			}
+			utils.PrintGreen("OK")
 		}
+		journal.complete("assets")
+	}
+
+	if !journal.done("css") {
+		utils.PrintBold(`Transferring user.css:`)
+		updateCSS()
 		utils.PrintGreen("OK")
-	} else if !extractedStock {
-		utils.PrintBold(`Overwriting raw assets:`)
-		if err := utils.Copy(rawFolder, appDestPath, true, nil); err != nil {
-			utils.Fatal(err)
+
+		if overwriteAssets {
+			utils.PrintBold(`Overwriting custom assets:`)
+			updateAssets()
+			utils.PrintGreen("OK")
 		}
-		utils.PrintGreen("OK")
+
+		if preprocSection.Key("expose_apis").MustBool(false) {
+			utils.CopyFile(
+				filepath.Join(utils.GetJsHelperDir(), "spicetifyWrapper.js"),
+				filepath.Join(appDestPath, "xpui", "helper"))
+		}
+		journal.complete("css")
 	}
 
-	utils.PrintBold(`Transferring user.css:`)
-	updateCSS()
-	utils.PrintGreen("OK")
+	extentionList := expandExtensionList(featureSection.Key("extensions").Strings("|"))
+	customAppsList := featureSection.Key("custom_apps").Strings("|")
+	var failures []error
 
-	if overwriteAssets {
-		utils.PrintBold(`Overwriting custom assets:`)
-		updateAssets()
-		utils.PrintGreen("OK")
+	if journal.done("extensions") {
+		extentionList = journal.Extensions
+	} else {
+		if len(extentionList) > 0 {
+			utils.PrintBold(`Transferring extensions:`)
+			var extErrs []error
+			extentionList, extErrs = pushExtensions(extentionList...)
+			failures = append(failures, extErrs...)
+			printStageResult(extErrs)
+			nodeModuleSymlink()
+		}
+		journal.Extensions = extentionList
+		journal.complete("extensions")
 	}
 
-	if preprocSection.Key("expose_apis").MustBool(false) {
-		utils.CopyFile(
-			filepath.Join(utils.GetJsHelperDir(), "spicetifyWrapper.js"),
-			filepath.Join(appDestPath, "xpui", "helper"))
+	if journal.done("apps") {
+		customAppsList = journal.CustomApps
+	} else {
+		if len(customAppsList) > 0 {
+			utils.PrintBold(`Transferring custom apps:`)
+			var appErrs []error
+			customAppsList, appErrs = pushApps(customAppsList...)
+			failures = append(failures, appErrs...)
+			printStageResult(appErrs)
+		}
+		journal.CustomApps = customAppsList
+		journal.complete("apps")
 	}
 
-	extentionList := featureSection.Key("extensions").Strings("|")
-	customAppsList := featureSection.Key("custom_apps").Strings("|")
+	// Modifications and patches are not idempotent, they must never run
+	// twice on the same files.
+	if !journal.done("modifications") {
+		journal.begin("modifications")
+		utils.PrintBold(`Applying additional modifications:`)
+		optionErrs := apply.AdditionalOptions(appDestPath, additionalOptionFlags(extentionList, customAppsList))
+		for _, err := range optionErrs {
+			utils.PrintWarning(err.Error() + ". Skipped.")
+		}
+		failures = append(failures, optionErrs...)
+		printStageResult(optionErrs)
+		journal.complete("modifications")
+	}
 
-	utils.PrintBold(`Applying additional modifications:`)
-	apply.AdditionalOptions(appDestPath, apply.Flag{
-		Extension:     extentionList,
-		CustomApp:     customAppsList,
-		SidebarConfig: featureSection.Key("sidebar_config").MustBool(false),
-		HomeConfig:    featureSection.Key("home_config").MustBool(false),
-	})
-	utils.PrintGreen("OK")
+	var patchErr error
+	if len(patchSection.Keys()) > 0 && !journal.done("patch") {
+		journal.begin("patch")
+		utils.PrintBold(`Patching:`)
+		if patchErr = Patch(); patchErr == nil {
+			utils.PrintGreen("OK")
+		}
+		journal.complete("patch")
+	}
 
-	if len(extentionList) > 0 {
-		utils.PrintBold(`Transferring extensions:`)
-		pushExtensions(extentionList...)
-		utils.PrintGreen("OK")
-		nodeModuleSymlink()
+	// Running Spotify would overwrite prefs when it quits. It's written on
+	// restart instead.
+	if !isSpotifyRunning() {
+		applyUIScalePref()
 	}
 
-	if len(customAppsList) > 0 {
-		utils.PrintBold(`Transferring custom apps:`)
-		pushApps(customAppsList...)
-		utils.PrintGreen("OK")
+	// Only a fully successful apply can be skipped next time.
+	inputs := ""
+	if len(failures) == 0 && patchErr == nil {
+		inputs, _ = applyInputsHash(spicetifyVersion)
 	}
+	writeAppliedRecord(inputs)
+	clearApplyJournal()
 
-	if len(patchSection.Keys()) > 0 {
-		utils.PrintBold(`Patching:`)
-		Patch()
-		utils.PrintGreen("OK")
+	if len(failures) > 0 {
+		utils.PrintWarning(fmt.Sprintf("Spotify is spiced up, but %d addon(s) could not be applied:", len(failures)))
+		for _, err := range failures {
+			log.Println("    - " + err.Error())
+		}
+		return utils.WithExitCode(utils.ExitAddonFailed, fmt.Errorf("%d addon(s) could not be applied", len(failures)))
+	}
+
+	if patchErr != nil {
+		utils.PrintWarning("Spotify is spiced up, but " + patchErr.Error())
+		return patchErr
 	}
 
 	utils.PrintSuccess("Spotify is spiced up!")
@@ -109,6 +177,33 @@ Modded Spotify cannot be launched using original Shortcut/Start menu tile. To co
 	if spicetifyVersion != backupSpicetifyVersion {
 		utils.PrintInfo(`Preprocessed Spotify data is outdated. Please run "spicetify restore backup apply" to receive new features and bug fixes`)
 	}
+
+	return nil
+}
+
+// additionalOptionFlags returns options from config to apply along with
+// transferred extensions and custom apps.
+func additionalOptionFlags(extensions, customApps []string) apply.Flag {
+	return apply.Flag{
+		Extension:     extensions,
+		CustomApp:     customApps,
+		SidebarConfig: featureSection.Key("sidebar_config").MustBool(false),
+		HomeConfig:    featureSection.Key("home_config").MustBool(false),
+
+		ExtensionSettings: enabledExtensionSettings(extensions),
+		JsSnippets:        enabledJsSnippets(),
+		WindowEffect:      windowEffect(true),
+		RTL:               featureSection.Key("rtl").MustBool(false),
+		UIScale:           uiScale(true),
+		UIScaleFont:       uiScale(false) > 0 && !canWriteUIScalePref(),
+	}
+}
+
+// printStageResult prints "OK" when a stage finishes without error.
+func printStageResult(errs []error) {
+	if len(errs) == 0 {
+		utils.PrintGreen("OK")
+	}
 }
 
 // UpdateTheme updates user.css and overwrites custom assets
@@ -118,14 +213,16 @@ func UpdateTheme() {
 
 	if len(themeFolder) == 0 {
 		utils.PrintWarning(`Nothing is updated: Config "current_theme" is blank.`)
-		os.Exit(1)
+		os.Exit(utils.ExitThemeNotFound)
 	}
 
 	updateCSS()
+	updateAppliedRecord(cssOutputFiles()...)
 	utils.PrintSuccess("Custom CSS is updated")
 
 	if overwriteAssets {
 		updateAssets()
+		updateAppliedRecord(assetOutputFiles()...)
 		utils.PrintSuccess("Custom assets are updated")
 	}
 }
@@ -147,6 +244,15 @@ func updateCSS() {
 	}
 	apply.UserCSS(appDestPath, theme, scheme)
 
+	if featureSection.Key("rtl").MustBool(false) {
+		if err := apply.RTLCSS(appDestPath, theme); err != nil {
+			utils.PrintWarningOf("Cannot write RTL stylesheet: ", err)
+		}
+		if preprocSection.Key("remove_rtl_rule").MustBool(false) {
+			utils.PrintWarning(`Preprocess "remove_rtl_rule" removed Spotify's own RTL styles. Set it to 0 then run "spicetify restore backup apply" for full RTL layout.`)
+		}
+	}
+
 	var configJson spicetifyConfigJson
 	configJson.ThemeName = settingSection.Key("current_theme").MustString("")
 	configJson.SchemeName = settingSection.Key("color_scheme").MustString("")
@@ -179,12 +285,51 @@ func updateAssets() {
 	apply.UserAsset(appDestPath, themeFolder)
 }
 
+// cssOutputFiles returns files in Spotify written by updateCSS.
+func cssOutputFiles() []string {
+	xpuiDest := filepath.Join(appDestPath, "xpui")
+	return []string{
+		filepath.Join(xpuiDest, "user.css"),
+		filepath.Join(xpuiDest, "helper", "rtl.css"),
+		filepath.Join(xpuiDest, "spicetify-config.json"),
+	}
+}
+
+// assetOutputFiles returns files in Spotify written by updateAssets.
+func assetOutputFiles() []string {
+	assetPath := filepath.Join(themeFolder, "assets")
+	xpuiDest := filepath.Join(appDestPath, "xpui")
+
+	var files []string
+	filepath.Walk(assetPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(assetPath, path)
+		files = append(files, filepath.Join(xpuiDest, rel))
+		return nil
+	})
+
+	return files
+}
+
+// extensionOutputFiles returns files in Spotify written by pushExtensions
+// for extensions `list`.
+func extensionOutputFiles(list ...string) []string {
+	var files []string
+	for _, name := range list {
+		files = append(files, filepath.Join(appDestPath, "xpui", "extensions", filepath.Base(name)))
+	}
+	return files
+}
+
 // UpdateAllExtension pushs all extensions to Spotify
 func UpdateAllExtension() {
 	checkStates()
-	list := featureSection.Key("extensions").Strings("|")
+	list := expandExtensionList(featureSection.Key("extensions").Strings("|"))
 	if len(list) > 0 {
 		pushExtensions(list...)
+		updateAppliedRecord(extensionOutputFiles(list...)...)
 		utils.PrintSuccess(utils.PrependTime("All extensions are updated."))
 	} else {
 		utils.PrintError("No extension to update.")
@@ -205,7 +350,7 @@ func checkStates() {
 		} else {
 			utils.PrintError(`You haven't backed up and Spotify cannot be backed up at this state. Please re-install Spotify then run "spicetify backup apply".`)
 		}
-		os.Exit(1)
+		os.Exit(utils.ExitNoBackup)
 
 	} else if backStat.IsOutdated() {
 		utils.PrintWarning("Spotify version and backup version are mismatched.")
@@ -222,9 +367,21 @@ func checkStates() {
 		}
 
 		if !ReadAnswer("Continue anyway? [y/N] ", false, true) {
-			os.Exit(1)
+			os.Exit(utils.ExitBackupOutdated)
+		}
+
+	} else if !isSameBuild(backupVersion) {
+		utils.PrintWarning("Spotify files are different from backup, although version is unchanged.")
+		utils.PrintInfo(`Spotify client possibly just had an new update.`)
+		utils.PrintInfo(`Please run "spicetify backup apply".`)
+
+		if !ReadAnswer("Continue anyway? [y/N] ", false, true) {
+			os.Exit(utils.ExitBackupOutdated)
 		}
 	}
+
+	// Extracted files are only cache and can be removed by "clean".
+	checkExtracted(backupVersion)
 }
 
 func getExtensionPath(name string) (string, error) {
@@ -243,9 +400,61 @@ func getExtensionPath(name string) (string, error) {
 	return "", errors.New("Extension not found")
 }
 
-func pushExtensions(list ...string) {
+// expandExtensionList expands glob patterns in extension list, like
+// "myext-*.js" or "devdir/*.js", against extension folders. Matches in
+// user folder take priority over ones in executable folder.
+func expandExtensionList(list []string) []string {
+	var result []string
+	seen := map[string]bool{}
+
+	for _, entry := range list {
+		if !strings.ContainsAny(entry, "*?[") {
+			if !seen[entry] {
+				seen[entry] = true
+				result = append(result, entry)
+			}
+			continue
+		}
+
+		var matches []string
+		if filepath.IsAbs(entry) {
+			matches, _ = filepath.Glob(entry)
+		} else {
+			for _, folder := range []string{userExtensionsFolder, filepath.Join(utils.GetExecutableDir(), "Extensions")} {
+				found, _ := filepath.Glob(filepath.Join(folder, entry))
+				for _, match := range found {
+					rel, err := filepath.Rel(folder, match)
+					if err == nil {
+						matches = append(matches, filepath.ToSlash(rel))
+					}
+				}
+			}
+		}
+		sort.Strings(matches)
+
+		if len(matches) == 0 {
+			utils.PrintWarning(`Extension pattern "` + entry + `" matches no file.`)
+		}
+
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			result = append(result, match)
+		}
+	}
+
+	return result
+}
+
+// pushExtensions copies extensions to Spotify and returns names of the ones
+// transferred, along with errors of the ones skipped.
+func pushExtensions(list ...string) ([]string, []error) {
 	var err error
 	var dest = filepath.Join(appDestPath, "xpui", "extensions")
+	var pushed []string
+	var errs []error
 
 	for _, v := range list {
 		var extName, extPath string
@@ -258,19 +467,21 @@ func pushExtensions(list ...string) {
 			extPath, err = getExtensionPath(v)
 			if err != nil {
 				utils.PrintError(`Extension "` + extName + `" not found.`)
+				errs = append(errs, &apply.AddonError{Kind: "extension", Name: extName, Err: err})
 				continue
 			}
 		}
 
 		if err = utils.CopyFile(extPath, dest); err != nil {
-			utils.PrintError(err.Error())
+			utils.PrintErrorOf("", err)
+			errs = append(errs, &apply.AddonError{Kind: "extension", Name: extName, Err: err})
 			continue
 		}
 
 		if strings.HasSuffix(extName, ".mjs") {
-			utils.ModifyFile(filepath.Join(dest, extName), func(content string) string {
+			utils.ModifyFile(filepath.Join(dest, filepath.Base(extName)), func(content string) string {
 				lines := strings.Split(content, "\n")
-				for i := 0; i < len(lines); i++ {
+				for i := 0; i < len(lines)-1; i++ {
 					mapping := utils.FindSymbol("", lines[i], []string{
 						`//\s*spicetify_map\{(.+?)\}\{(.+?)\}`,
 					})
@@ -282,7 +493,11 @@ func pushExtensions(list ...string) {
 				return strings.Join(lines, "\n")
 			})
 		}
+
+		pushed = append(pushed, filepath.Base(extName))
 	}
+
+	return pushed, errs
 }
 
 func getCustomAppPath(name string) (string, error) {
@@ -305,69 +520,100 @@ type appManifest struct {
 	Files []string `json:"subfiles"`
 }
 
-func pushApps(list ...string) {
-	for _, app := range list {
-		appName := `spicetify-routes-` + app
+// pushApps writes custom apps files to Spotify and returns names of the
+// ones transferred, along with errors of the ones skipped.
+func pushApps(list ...string) ([]string, []error) {
+	var pushed []string
+	var errs []error
 
-		customAppPath, err := getCustomAppPath(app)
-		if err != nil {
-			utils.PrintError(`Custom app "` + app + `" not found.`)
+	for _, app := range list {
+		if err := pushApp(app); err != nil {
+			utils.PrintErrorOf(`Custom app "`+app+`": `, err)
+			errs = append(errs, &apply.AddonError{Kind: "custom app", Name: app, Err: err})
 			continue
 		}
 
-		jsFile := filepath.Join(customAppPath, "index.js")
-		jsFileContent, err := os.ReadFile(jsFile)
-		if err != nil {
-			utils.PrintError(`Custom app "` + app + `" does not have index.js`)
-			continue
-		}
+		pushed = append(pushed, app)
+	}
 
-		manifestFile := filepath.Join(customAppPath, "manifest.json")
-		manifestFileContent, err := os.ReadFile(manifestFile)
-		if err != nil {
-			manifestFileContent = []byte{'{', '}'}
-		}
-		os.WriteFile(
-			filepath.Join(appDestPath, "xpui", appName+".json"),
-			manifestFileContent,
-			0700)
+	return pushed, errs
+}
 
-		var manifestJson appManifest
-		if err = json.Unmarshal(manifestFileContent, &manifestJson); err == nil {
-			for _, subfile := range manifestJson.Files {
-				subfilePath := filepath.Join(customAppPath, subfile)
-				subfileContent, err := os.ReadFile(subfilePath)
-				if err != nil {
-					continue
-				}
-				jsFileContent = append(jsFileContent, '\n')
-				jsFileContent = append(jsFileContent, subfileContent...)
+// appOutputFiles returns files in Spotify written by pushApps for custom
+// apps `list`.
+func appOutputFiles(list ...string) []string {
+	var files []string
+	for _, app := range list {
+		base := filepath.Join(appDestPath, "xpui", "spicetify-routes-"+app)
+		files = append(files, base+".js", base+".json", base+".css")
+	}
+	return files
+}
+
+func pushApp(app string) error {
+	appName := `spicetify-routes-` + app
+
+	customAppPath, err := getCustomAppPath(app)
+	if err != nil {
+		return err
+	}
+
+	jsFile := filepath.Join(customAppPath, "index.js")
+	jsFileContent, err := os.ReadFile(jsFile)
+	if err != nil {
+		return errors.New("index.js not found")
+	}
+
+	manifestFile := filepath.Join(customAppPath, "manifest.json")
+	manifestFileContent, err := os.ReadFile(manifestFile)
+	if err != nil {
+		manifestFileContent = []byte{'{', '}'}
+	}
+	if err = os.WriteFile(
+		filepath.Join(appDestPath, "xpui", appName+".json"),
+		manifestFileContent,
+		0700); err != nil {
+		return err
+	}
+
+	var manifestJson appManifest
+	if err = json.Unmarshal(manifestFileContent, &manifestJson); err == nil {
+		for _, subfile := range manifestJson.Files {
+			subfilePath := filepath.Join(customAppPath, subfile)
+			subfileContent, err := os.ReadFile(subfilePath)
+			if err != nil {
+				continue
 			}
+			jsFileContent = append(jsFileContent, '\n')
+			jsFileContent = append(jsFileContent, subfileContent...)
 		}
+	}
 
-		jsTemplate := fmt.Sprintf(
-			`(("undefined"!=typeof self?self:global).webpackChunkopen=("undefined"!=typeof self?self:global).webpackChunkopen||[])
+	jsTemplate := fmt.Sprintf(
+		`(("undefined"!=typeof self?self:global).webpackChunkopen=("undefined"!=typeof self?self:global).webpackChunkopen||[])
 .push([["%s"],{"%s":(e,t,n)=>{
 "use strict";n.r(t),n.d(t,{default:()=>render});
 %s
 }}]);`,
-			appName, appName, jsFileContent)
+		appName, appName, jsFileContent)
 
-		os.WriteFile(
-			filepath.Join(appDestPath, "xpui", appName+".js"),
-			[]byte(jsTemplate),
-			0700)
+	if err = os.WriteFile(
+		filepath.Join(appDestPath, "xpui", appName+".js"),
+		[]byte(jsTemplate),
+		0700); err != nil {
+		return err
+	}
 
-		cssFile := filepath.Join(customAppPath, "style.css")
-		cssFileContent, err := os.ReadFile(cssFile)
-		if err != nil {
-			cssFileContent = []byte{}
-		}
-		os.WriteFile(
-			filepath.Join(appDestPath, "xpui", appName+".css"),
-			[]byte(cssFileContent),
-			0700)
+	cssFile := filepath.Join(customAppPath, "style.css")
+	cssFileContent, err := os.ReadFile(cssFile)
+	if err != nil {
+		cssFileContent = []byte{}
 	}
+
+	return os.WriteFile(
+		filepath.Join(appDestPath, "xpui", appName+".css"),
+		[]byte(cssFileContent),
+		0700)
 }
 
 func toTernary(key string) utils.TernaryBool {
@@ -390,3 +636,19 @@ func nodeModuleSymlink() {
 
 	utils.PrintGreen("OK")
 }
+
+func applyLogPath() string {
+	return filepath.Join(spicetifyFolder, "apply.log")
+}
+
+// StartApplyLog starts recording printed messages to apply log, replacing
+// the previous one. It is included in bug reports.
+func StartApplyLog() {
+	file, err := os.Create(applyLogPath())
+	if err != nil {
+		utils.PrintWarningOf("Cannot record apply log: ", err)
+		return
+	}
+
+	utils.TeeLog(file)
+}
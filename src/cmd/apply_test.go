@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandExtensionList(t *testing.T) {
+	folder := t.TempDir()
+	for _, name := range []string{"a.js", "b.js", "myext-1.js", "myext-2.mjs", "dev/c.js", "dev/d.js"} {
+		path := filepath.Join(folder, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0700)
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := userExtensionsFolder
+	userExtensionsFolder = folder
+	defer func() { userExtensionsFolder = old }()
+
+	tests := []struct {
+		name string
+		list []string
+		want []string
+	}{
+		{
+			name: "plain names are kept",
+			list: []string{"b.js", "missing.js", "a.js"},
+			want: []string{"b.js", "missing.js", "a.js"},
+		},
+		{
+			name: "star",
+			list: []string{"myext-*"},
+			want: []string{"myext-1.js", "myext-2.mjs"},
+		},
+		{
+			name: "sub folder",
+			list: []string{"dev/*.js"},
+			want: []string{"dev/c.js", "dev/d.js"},
+		},
+		{
+			name: "question mark and class",
+			list: []string{"?.js", "myext-[2].mjs"},
+			want: []string{"a.js", "b.js", "myext-2.mjs"},
+		},
+		{
+			name: "duplicates are dropped",
+			list: []string{"b.js", "*.js", "a.js"},
+			want: []string{"b.js", "a.js", "myext-1.js"},
+		},
+		{
+			name: "absolute pattern",
+			list: []string{filepath.Join(folder, "dev", "*.js")},
+			want: []string{filepath.Join(folder, "dev", "c.js"), filepath.Join(folder, "dev", "d.js")},
+		},
+		{
+			name: "no match",
+			list: []string{"none-*.js"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandExtensionList(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,11 +5,12 @@ import (
 
 	backupstatus "github.com/khanhas/spicetify-cli/src/status/backup"
 	spotifystatus "github.com/khanhas/spicetify-cli/src/status/spotify"
+	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
 // Auto checks Spotify state, re-backup and apply if needed, then launch
 // Spotify client normally.
-func Auto(spicetifyVersion string) {
+func Auto(spicetifyVersion string) error {
 	backupVersion := backupSection.Key("version").MustString("")
 	spotStat := spotifystatus.Get(appPath)
 	backStat := backupstatus.Get(prefsPath, backupFolder, backupVersion)
@@ -21,14 +22,21 @@ func Auto(spicetifyVersion string) {
 	}
 
 	if !backStat.IsBackuped() {
-		os.Exit(1)
+		os.Exit(utils.ExitNoBackup)
 	}
 
 	if isAppX {
 		spotStat = spotifystatus.Get(appDestPath)
 	}
 
+	var err error
 	if !spotStat.IsApplied() && backStat.IsBackuped() {
-		Apply(spicetifyVersion)
+		err = Apply(spicetifyVersion)
 	}
+
+	if len(settingSection.Key("rotate_schemes").String()) > 0 {
+		Rotate()
+	}
+
+	return err
 }
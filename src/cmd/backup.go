@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 
 	spotifystatus "github.com/khanhas/spicetify-cli/src/status/spotify"
 
@@ -23,54 +26,56 @@ func Backup(spicetifyVersion string) {
 		utils.PrintInfo("Clear current backup:")
 
 		spotStat := spotifystatus.Get(appPath)
-		if spotStat.IsBackupable() {
+		if spotStat.IsBackupable() && backStat.IsOutdated() {
+			// Keep archive of previous Spotify version.
+			clearExtracted()
+			utils.PrintSuccess("Backup is cleared.")
+		} else if spotStat.IsBackupable() {
 			clearBackup()
 
 		} else {
 			utils.PrintWarning(`After clearing backup, Spotify cannot be backed up again.`)
 			utils.PrintInfo(`Please restore first then backup, run "spicetify restore backup" or re-install Spotify then run "spicetify backup".`)
-			os.Exit(1)
+			os.Exit(utils.ExitNoBackup)
 		}
 	}
 
 	utils.PrintBold("Backing up app files:")
 
-	if err := backup.Start(appPath, backupFolder); err != nil {
-		log.Fatal(err)
+	spotifyVersion := utils.GetSpotifyVersion(prefsPath)
+	if err := backup.Start(appPath, backupFolder, spotifyVersion); err != nil {
+		utils.PrintErrorOf("", err)
+		utils.PrintError("Cannot backup app files. Reinstall Spotify and try again.")
+		os.Exit(utils.ExitCodeOf(err))
 	}
+	utils.PrintGreen("OK")
 
-	appList, err := ioutil.ReadDir(backupFolder)
-	if err != nil {
-		log.Fatal(err)
-	}
+	extractBackup(spotifyVersion)
 
-	totalApp := len(appList)
-	if totalApp > 0 {
-		utils.PrintGreen("OK")
-	} else {
-		utils.PrintError("Cannot backup app files. Reinstall Spotify and try again.")
-		os.Exit(1)
+	backupSection.Key("version").SetValue(spotifyVersion)
+	backupSection.Key("with").SetValue(spicetifyVersion)
+	cfg.Write()
+
+	if max := settingSection.Key("max_backups").MustInt(0); max > 0 {
+		utils.PrintBold("Removing old backups:")
+		cleanBackups(max, false)
 	}
+	utils.PrintSuccess("Everything is ready, you can start applying now!")
+}
 
+// extractBackup extracts backup of Spotify `version` to Raw folder and
+// preprocesses its assets into Themed folder.
+func extractBackup(version string) {
 	utils.PrintBold("Extracting:")
-	backup.Extract(backupFolder, rawFolder)
+	backup.Extract(backupFolder, version, rawFolder)
 	utils.PrintGreen("OK")
 
 	utils.PrintBold("Preprocessing:")
 
-	preprocess.Start(
-		rawFolder,
-		preprocess.Flag{
-			DisableSentry:  preprocSection.Key("disable_sentry").MustBool(false),
-			DisableLogging: preprocSection.Key("disable_ui_logging").MustBool(false),
-			RemoveRTL:      preprocSection.Key("remove_rtl_rule").MustBool(false),
-			ExposeAPIs:     preprocSection.Key("expose_apis").MustBool(false),
-			DisableUpgrade: preprocSection.Key("disable_upgrade_check").MustBool(false),
-		},
-	)
+	preprocess.Start(rawFolder, preprocessFlags())
 	utils.PrintGreen("OK")
 
-	err = utils.Copy(rawFolder, themedFolder, true, []string{".html", ".js", ".css"})
+	err := utils.Copy(rawFolder, themedFolder, true, []string{".html", ".js", ".css"})
 	if err != nil {
 		utils.Fatal(err)
 	}
@@ -78,10 +83,63 @@ func Backup(spicetifyVersion string) {
 	preprocess.StartCSS(themedFolder)
 	utils.PrintGreen("OK")
 
-	backupSection.Key("version").SetValue(utils.GetSpotifyVersion(prefsPath))
-	backupSection.Key("with").SetValue(spicetifyVersion)
+	hash, _ := backup.FileHash(backupFolder, version, "xpui.spa")
+	backupSection.Key("xpui_hash").SetValue(hash)
 	cfg.Write()
-	utils.PrintSuccess("Everything is ready, you can start applying now!")
+}
+
+// preprocessFlags returns preprocesses enabled in config.
+func preprocessFlags() preprocess.Flag {
+	return preprocess.Flag{
+		DisableSentry:  preprocSection.Key("disable_sentry").MustBool(false),
+		DisableLogging: preprocSection.Key("disable_ui_logging").MustBool(false),
+		RemoveRTL:      preprocSection.Key("remove_rtl_rule").MustBool(false),
+		ExposeAPIs:     preprocSection.Key("expose_apis").MustBool(false),
+		DisableUpgrade: preprocSection.Key("disable_upgrade_check").MustBool(false),
+	}
+}
+
+// checkExtracted makes sure extracted files come from xpui.spa of current
+// backup, re-extracting them when they are missing or stale.
+func checkExtracted(version string) {
+	if !isExtracted() {
+		utils.PrintInfo("Extracted files are not found. Re-extracting from backup.")
+		extractBackup(version)
+		return
+	}
+
+	hash, err := backup.FileHash(backupFolder, version, "xpui.spa")
+	if err != nil || hash == backupSection.Key("xpui_hash").String() {
+		return
+	}
+
+	utils.PrintWarning("Extracted files do not match current backup. Re-extracting from backup.")
+	for _, folder := range []string{rawFolder, themedFolder} {
+		if err := os.RemoveAll(folder); err != nil {
+			utils.Fatal(err)
+		}
+		os.Mkdir(folder, 0700)
+	}
+	extractBackup(version)
+}
+
+// isSameBuild reports whether stock xpui.spa in Spotify Apps folder, if
+// any, is the one in backup of Spotify `version`. Spotify can update its
+// files without changing version number, which makes backup stale.
+func isSameBuild(version string) bool {
+	installed, err := utils.HashFile(filepath.Join(appPath, "xpui.spa"))
+	if err != nil {
+		return true
+	}
+
+	backedUp, err := backup.FileHash(backupFolder, version, "xpui.spa")
+	return err != nil || installed == backedUp
+}
+
+// isExtracted reports whether Raw folder has extracted files.
+func isExtracted() bool {
+	fileList, err := ioutil.ReadDir(rawFolder)
+	return err == nil && len(fileList) > 0
 }
 
 // Clear clears current backup. Before clearing, it checks whether Spotify is in
@@ -92,19 +150,27 @@ func Clear() {
 	if !spotStat.IsBackupable() {
 		utils.PrintWarning("Before clearing backup, please restore or re-install Spotify to stock state.")
 		if !ReadAnswer("Continue clearing anyway? [y/N]: ", false, true) {
-			os.Exit(1)
+			os.Exit(utils.ExitError)
 		}
 	}
 
 	clearBackup()
 }
 
+// clearBackup removes backup archive of current backup version and
+// extracted files. Archives of other Spotify versions are kept.
 func clearBackup() {
-	if err := os.RemoveAll(backupFolder); err != nil {
+	if err := backup.Remove(backupFolder, backupSection.Key("version").String()); err != nil {
 		utils.Fatal(err)
 	}
-	os.Mkdir(backupFolder, 0700)
 
+	clearExtracted()
+	utils.PrintSuccess("Backup is cleared.")
+}
+
+// clearExtracted removes extracted and preprocessed files and resets
+// backup info in config.
+func clearExtracted() {
 	if err := os.RemoveAll(rawFolder); err != nil {
 		utils.Fatal(err)
 	}
@@ -117,8 +183,8 @@ func clearBackup() {
 
 	backupSection.Key("version").SetValue("")
 	backupSection.Key("with").SetValue("")
+	backupSection.Key("xpui_hash").SetValue("")
 	cfg.Write()
-	utils.PrintSuccess("Backup is cleared.")
 }
 
 // Restore uses backup to revert every changes made by Spicetify.
@@ -133,7 +199,7 @@ func Restore() {
 		if !spotStat.IsBackupable() {
 			utils.PrintWarning(`But Spotify cannot be backed up at this state. Please re-install Spotify then run "spicetify backup"`)
 		}
-		os.Exit(1)
+		os.Exit(utils.ExitNoBackup)
 
 	} else if backStat.IsOutdated() {
 		utils.PrintWarning("Spotify version and backup version are mismatched.")
@@ -143,17 +209,144 @@ func Restore() {
 		}
 
 		if !ReadAnswer("Continue restoring anyway? [y/N] ", false, true) {
-			os.Exit(1)
+			os.Exit(utils.ExitBackupOutdated)
 		}
 	}
 
-	if err := os.RemoveAll(appDestPath); err != nil {
+	checkWritePermission()
+	closeForWrite()
+
+	if err := utils.RetryLocked(func() error { return os.RemoveAll(appDestPath) }); err != nil {
 		utils.Fatal(err)
 	}
 
-	if err := utils.Copy(backupFolder, appDestPath, false, []string{".spa"}); err != nil {
+	err := utils.RetryLocked(func() error {
+		return backup.Restore(backupFolder, backupVersion, appDestPath)
+	})
+	if err != nil {
 		utils.Fatal(err)
 	}
 
+	clearAppliedRecord()
+	clearApplyJournal()
 	utils.PrintSuccess("Spotify is restored.")
 }
+
+// BackupList prints stored backups, newest first, with their sizes.
+// Unique size is disk space freed when that backup is removed.
+func BackupList() {
+	manifests, err := backup.List(backupFolder)
+	if err != nil {
+		utils.Fatal(err)
+	}
+
+	if len(manifests) == 0 {
+		utils.PrintInfo("No backup.")
+		return
+	}
+
+	currentVersion := backupSection.Key("version").String()
+	for _, manifest := range manifests {
+		var size int64
+		for _, file := range manifest.Files {
+			size += file.Size
+		}
+
+		unique, _ := backup.ExclusiveSize(backupFolder, []string{manifest.Version})
+
+		mark := "  "
+		if manifest.Version == currentVersion {
+			mark = utils.Green("* ")
+		}
+
+		log.Println(fmt.Sprintf("%s%-20s %s    %s (%s unique)",
+			mark,
+			manifest.Version,
+			manifest.Created.Local().Format("2006-01-02 15:04"),
+			utils.FormatSize(size),
+			utils.FormatSize(unique)))
+	}
+
+	log.Println("Backup folder: " + utils.FormatSize(utils.DirSize(backupFolder)))
+}
+
+// BackupPrune removes backups except current one and `keep` newest ones.
+func BackupPrune(keep int, dryRun bool) {
+	if dryRun {
+		utils.PrintInfo("Dry run: nothing is removed.")
+	}
+
+	utils.PrintBold("Removing old backups:")
+	size := cleanBackups(keep, dryRun)
+	if size == 0 {
+		return
+	}
+
+	if dryRun {
+		utils.PrintInfo(utils.FormatSize(size) + " can be freed.")
+	} else {
+		utils.PrintSuccess(utils.FormatSize(size) + " freed.")
+	}
+}
+
+// BackupExport writes current backup, or backup of Spotify `version` when
+// it is given, to a single archive at `path`, so it can be imported on
+// another machine with the same Spotify version.
+func BackupExport(path, version, spicetifyVersion string) error {
+	if len(version) == 0 {
+		version = backupSection.Key("version").String()
+	}
+	if len(version) == 0 {
+		return utils.WithExitCode(utils.ExitNoBackup, errors.New(`no backup to export. Run "spicetify backup" first`))
+	}
+	if !backup.Exists(backupFolder, version) {
+		return utils.WithExitCode(utils.ExitNoBackup, errors.New("no backup of Spotify "+version))
+	}
+
+	utils.PrintBold("Exporting backup:")
+	if err := backup.Export(backupFolder, version, spicetifyVersion, path); err != nil {
+		return err
+	}
+	utils.PrintGreen("OK")
+
+	utils.PrintSuccess("Backup of Spotify " + version + " is exported to " + path)
+	return nil
+}
+
+// BackupImport adds backup in archive at `path`, written by BackupExport,
+// to backup store. When it is backup of installed Spotify, it becomes
+// current backup and is extracted, ready to apply.
+func BackupImport(path, spicetifyVersion string) error {
+	utils.PrintBold("Importing backup:")
+	info, err := backup.Import(backupFolder, path, func(version string) bool {
+		utils.PrintWarning("Backup of Spotify " + version + " already exists.")
+		return ReadAnswer("Replace it with imported one? [y/N] ", false, false)
+	})
+	if err != nil {
+		return err
+	}
+	utils.PrintGreen("OK")
+
+	version := info.Manifest.Version
+	utils.PrintInfo(fmt.Sprintf("Backup of Spotify %s, exported %s by spicetify v%s",
+		version, info.Exported.Local().Format("2006-01-02 15:04"), info.Spicetify))
+
+	installed := utils.GetSpotifyVersion(prefsPath)
+	if version != installed {
+		utils.PrintWarning("Installed Spotify version is " + installed + ". Imported backup is stored but not used.")
+		return nil
+	}
+	if !isSameBuild(version) {
+		utils.PrintWarning("Installed Spotify files are different from imported backup. Imported backup is stored but not used.")
+		return nil
+	}
+
+	extractBackup(version)
+
+	backupSection.Key("version").SetValue(version)
+	backupSection.Key("with").SetValue(spicetifyVersion)
+	cfg.Write()
+
+	utils.PrintSuccess("Backup is imported, you can start applying now!")
+	return nil
+}
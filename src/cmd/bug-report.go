@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	backupstatus "github.com/khanhas/spicetify-cli/src/status/backup"
+)
+
+var ansiColorRe = regexp.MustCompile(`\x1B\[[0-9;]*m`)
+
+// BugReport collects versions, Spotify info, status, enabled addons,
+// config and last apply log into `output`, with user paths redacted.
+// When `output` ends with ".zip", config and apply log are also stored as
+// separate files in the archive.
+func BugReport(spicetifyVersion, output string) error {
+	configContent := "(unavailable)"
+	if content, err := ioutil.ReadFile(cfg.GetPath()); err == nil {
+		configContent = redactUserPaths(string(content))
+	}
+
+	applyLog := "(no apply log)"
+	if content, err := ioutil.ReadFile(applyLogPath()); err == nil {
+		applyLog = redactUserPaths(ansiColorRe.ReplaceAllString(string(content), ""))
+	}
+
+	report := bugReportSection("spicetify",
+		"Version:        "+spicetifyVersion+"\n"+
+			"OS:             "+runtime.GOOS+"/"+runtime.GOARCH+"\n"+
+			"Generated:      "+time.Now().Format(time.RFC1123)+"\n")
+	report += bugReportSection("Spotify", redactUserPaths(getSpotifyInfo().String()))
+	report += bugReportSection("Status", bugReportStatus(spicetifyVersion))
+	report += bugReportSection("Addons", bugReportAddons())
+	report += bugReportSection("Config", configContent)
+	report += bugReportSection("Last apply log", applyLog)
+
+	if !strings.HasSuffix(strings.ToLower(output), ".zip") {
+		return ioutil.WriteFile(output, []byte(report), 0644)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for _, entry := range []struct{ name, content string }{
+		{"bug-report.txt", report},
+		{"config-xpui.ini", configContent},
+		{"apply.log", applyLog},
+	} {
+		writer, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write([]byte(entry.content)); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func bugReportSection(title, content string) string {
+	return "## " + title + "\n\n```\n" + strings.TrimRight(content, "\n") + "\n```\n\n"
+}
+
+func bugReportStatus(spicetifyVersion string) string {
+	backupVersion := backupSection.Key("version").String()
+	backStat := backupstatus.Get(prefsPath, backupFolder, backupVersion)
+
+	backupState := "backed up"
+	if backStat.IsEmpty() {
+		backupState = "no backup"
+	} else if backStat.IsOutdated() {
+		backupState = "outdated"
+	}
+
+	preprocessedWith := backupSection.Key("with").String()
+	if len(preprocessedWith) > 0 && preprocessedWith != spicetifyVersion {
+		preprocessedWith += " (outdated)"
+	}
+
+	applied := "never"
+	if record, err := readAppliedRecord(); err == nil {
+		applied = record.Time.Format(time.RFC1123)
+	}
+
+	return "Backup:         " + backupState + "\n" +
+		"Backup version: " + backupVersion + "\n" +
+		"Preprocessed:   " + preprocessedWith + "\n" +
+		"Extracted:      " + yesNo(isExtracted()) + "\n" +
+		"Same build:     " + yesNo(isSameBuild(backupVersion)) + "\n" +
+		"Last applied:   " + applied + "\n"
+}
+
+func bugReportAddons() string {
+	var snippets []string
+	for _, snippet := range enabledJsSnippets() {
+		snippets = append(snippets, snippet.Name)
+	}
+
+	return "Theme:          " + currentThemeName() + "\n" +
+		"Color scheme:   " + settingSection.Key("color_scheme").String() + "\n" +
+		"Extensions:     " + strings.Join(featureSection.Key("extensions").Strings("|"), ", ") + "\n" +
+		"Custom apps:    " + strings.Join(featureSection.Key("custom_apps").Strings("|"), ", ") + "\n" +
+		"JS snippets:    " + strings.Join(snippets, ", ") + "\n"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+// redactUserPaths replaces home folder with "~" and user name in other
+// paths with "<user>".
+func redactUserPaths(text string) string {
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		text = strings.ReplaceAll(text, home, "~")
+		text = strings.ReplaceAll(text, filepath.ToSlash(home), "~")
+	}
+
+	if current, err := user.Current(); err == nil {
+		name := current.Username
+		// Windows user name is in "DOMAIN\user" form.
+		if i := strings.LastIndex(name, `\`); i != -1 {
+			name = name[i+1:]
+		}
+		if len(name) > 0 {
+			re := regexp.MustCompile(`(?i)([\\/])` + regexp.QuoteMeta(name) + `([\\/\s"';]|$)`)
+			text = re.ReplaceAllString(text, "${1}<user>${2}")
+		}
+	}
+
+	return text
+}
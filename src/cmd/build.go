@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/apply"
+	"github.com/khanhas/spicetify-cli/src/preprocess"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Build writes a fully customized copy of stock Spotify Apps folder
+// `source` to `output`, then prints what has to be set in Spotify "prefs"
+// file and launch flags to match config. Detected Spotify install, its
+// prefs and spicetify backups are never touched and nothing is downloaded
+// except remote themes, so output only depends on inputs. It suits package
+// builds, like Nix derivations.
+func Build(source, output string) error {
+	if files, err := ioutil.ReadDir(output); err == nil && len(files) > 0 {
+		return errors.New(`output folder "` + output + `" is not empty`)
+	}
+	if _, err := os.Stat(filepath.Join(source, "xpui.spa")); err != nil {
+		return errors.New(`"` + source + `" is not a stock Spotify Apps folder, no xpui.spa found`)
+	}
+
+	InitSetting()
+	appPath = output
+	appDestPath = output
+
+	utils.PrintBold("Extracting:")
+	if err := extractApps(source, output); err != nil {
+		os.RemoveAll(output)
+		return err
+	}
+	utils.PrintGreen("OK")
+
+	utils.PrintBold("Preprocessing:")
+	flags := preprocessFlags()
+	flags.LocalCSSMap = true
+	preprocess.Start(output, flags)
+	if replaceColors {
+		preprocess.StartCSS(output)
+	}
+	utils.PrintGreen("OK")
+
+	utils.PrintBold(`Transferring user.css:`)
+	updateCSS()
+	if overwriteAssets {
+		updateAssets()
+	}
+	if preprocSection.Key("expose_apis").MustBool(false) {
+		utils.CopyFile(
+			filepath.Join(utils.GetJsHelperDir(), "spicetifyWrapper.js"),
+			filepath.Join(output, "xpui", "helper"))
+	}
+	utils.PrintGreen("OK")
+
+	var failures []error
+
+	extensions := expandExtensionList(featureSection.Key("extensions").Strings("|"))
+	if len(extensions) > 0 {
+		utils.PrintBold(`Transferring extensions:`)
+		var errs []error
+		extensions, errs = pushExtensions(extensions...)
+		failures = append(failures, errs...)
+		printStageResult(errs)
+	}
+
+	customApps := featureSection.Key("custom_apps").Strings("|")
+	if len(customApps) > 0 {
+		utils.PrintBold(`Transferring custom apps:`)
+		var errs []error
+		customApps, errs = pushApps(customApps...)
+		failures = append(failures, errs...)
+		printStageResult(errs)
+	}
+
+	utils.PrintBold(`Applying additional modifications:`)
+	optionErrs := apply.AdditionalOptions(output, additionalOptionFlags(extensions, customApps))
+	for _, err := range optionErrs {
+		utils.PrintWarning(err.Error() + ". Skipped.")
+	}
+	failures = append(failures, optionErrs...)
+	printStageResult(optionErrs)
+
+	var patchErr error
+	if len(patchSection.Keys()) > 0 {
+		utils.PrintBold(`Patching:`)
+		if patchErr = Patch(); patchErr == nil {
+			utils.PrintGreen("OK")
+		}
+	}
+
+	printBuildAdjustments(output)
+
+	if len(failures) > 0 {
+		utils.PrintWarning(fmt.Sprintf("Apps folder is built, but %d addon(s) could not be applied:", len(failures)))
+		for _, err := range failures {
+			log.Println("    - " + err.Error())
+		}
+		return utils.WithExitCode(utils.ExitAddonFailed, fmt.Errorf("%d addon(s) could not be applied", len(failures)))
+	}
+
+	if patchErr != nil {
+		utils.PrintWarning("Apps folder is built, but " + patchErr.Error())
+		return patchErr
+	}
+
+	utils.PrintSuccess("Apps folder is built: " + output)
+	return nil
+}
+
+// extractApps extracts each app package in `source` to a folder of its name
+// in `output`, like Spotify does with apps that are not packed. Other files
+// are copied as they are.
+func extractApps(source, output string) error {
+	files, err := ioutil.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	utils.CheckExistAndCreate(output)
+	for _, file := range files {
+		path := filepath.Join(source, file.Name())
+		if file.IsDir() {
+			if err := utils.Copy(path, filepath.Join(output, file.Name()), true, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(file.Name(), ".spa") {
+			if err := utils.CopyFile(path, output); err != nil {
+				return err
+			}
+			continue
+		}
+
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return errors.New(file.Name() + ": " + err.Error())
+		}
+		err = utils.UnzipReader(&reader.Reader, filepath.Join(output, strings.TrimSuffix(file.Name(), ".spa")))
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printBuildAdjustments prints changes spicetify normally makes outside of
+// Apps folder, which packagers have to make themselves.
+func printBuildAdjustments(output string) {
+	utils.PrintBold("To use built Apps folder:")
+	log.Println(`    Replace "Apps" folder of Spotify with ` + output)
+
+	if scale := uiScale(false); scale > 0 {
+		log.Println(`    Set in "prefs" file: app.browser.zoom-level=` + uiScaleZoom(scale))
+	}
+
+	if launchFlags := settingSection.Key("spotify_launch_flags").Strings("|"); len(launchFlags) > 0 {
+		log.Println("    Launch Spotify with flags: " + strings.Join(launchFlags, " "))
+	}
+
+	if len(featureSection.Key("window_effect").String()) > 0 {
+		log.Println(`    "window_effect" only works when Spotify is launched by "spicetify restart"`)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/backup"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// cleanItem is a file or folder that "clean" removes.
+type cleanItem struct {
+	path string
+	size int64
+}
+
+// Clean removes extracted files, backups of old Spotify versions beyond
+// the `keep` newest ones, orphaned AppX copies and leftover temp files.
+// With dryRun, it only reports what would be removed.
+func Clean(keep int, dryRun bool) {
+	var reclaimed int64
+
+	if dryRun {
+		utils.PrintInfo("Dry run. Nothing is removed.")
+	}
+
+	utils.PrintBold("Extracted files:")
+	extracted := []cleanItem{}
+	for _, folder := range []string{rawFolder, themedFolder} {
+		if size := utils.DirSize(folder); size > 0 {
+			extracted = append(extracted, cleanItem{folder, size})
+		}
+	}
+	reclaimed += cleanItems(extracted, dryRun, true)
+
+	utils.PrintBold("Old backups:")
+	reclaimed += cleanBackups(keep, dryRun)
+
+	utils.PrintBold("Orphaned AppX copy:")
+	appX := []cleanItem{}
+	appXFolder := filepath.Join(spicetifyFolder, "AppX")
+	if !isAppX {
+		if _, err := os.Stat(appXFolder); err == nil {
+			appX = append(appX, cleanItem{appXFolder, utils.DirSize(appXFolder)})
+		}
+	}
+	reclaimed += cleanItems(appX, dryRun, false)
+
+	utils.PrintBold("Temporary files:")
+	temp := []cleanItem{}
+	for _, path := range tempFiles() {
+		temp = append(temp, cleanItem{path, utils.DirSize(path)})
+	}
+	reclaimed += cleanItems(temp, dryRun, false)
+
+	if dryRun {
+		utils.PrintSuccess("Cleaning would reclaim " + utils.FormatSize(reclaimed) + ".")
+	} else {
+		utils.PrintSuccess("Reclaimed " + utils.FormatSize(reclaimed) + ".")
+	}
+}
+
+// cleanItems removes items and returns their total size. With keepRoot,
+// only content of folders is removed.
+func cleanItems(items []cleanItem, dryRun, keepRoot bool) int64 {
+	if len(items) == 0 {
+		utils.PrintGreen("Nothing to clean")
+		return 0
+	}
+
+	var total int64
+	for _, item := range items {
+		log.Println("    " + item.path + " (" + utils.FormatSize(item.size) + ")")
+		total += item.size
+
+		if dryRun {
+			continue
+		}
+
+		if err := os.RemoveAll(item.path); err != nil {
+			utils.PrintErrorOf("", err)
+			continue
+		}
+
+		if keepRoot {
+			os.Mkdir(item.path, 0700)
+		}
+	}
+
+	return total
+}
+
+// cleanBackups removes backups except current one and `keep` newest ones.
+func cleanBackups(keep int, dryRun bool) int64 {
+	manifests, err := backup.List(backupFolder)
+	if err != nil {
+		utils.PrintErrorOf("Cannot read backups: ", err)
+		return 0
+	}
+
+	currentVersion := backupSection.Key("version").String()
+	var stale []string
+	kept := 0
+	for _, manifest := range manifests {
+		if manifest.Version == currentVersion {
+			kept++
+			continue
+		}
+
+		if kept < keep {
+			kept++
+			continue
+		}
+
+		stale = append(stale, manifest.Version)
+	}
+
+	if len(stale) == 0 {
+		utils.PrintGreen("Nothing to remove")
+		return 0
+	}
+
+	size, err := backup.ExclusiveSize(backupFolder, stale)
+	if err != nil {
+		utils.PrintErrorOf("Cannot read backups: ", err)
+		return 0
+	}
+
+	for _, version := range stale {
+		log.Println("    Spotify " + version)
+		if dryRun {
+			continue
+		}
+
+		if err := backup.Remove(backupFolder, version); err != nil {
+			utils.PrintErrorOf("", err)
+		}
+	}
+	log.Println("    Total: " + utils.FormatSize(size))
+
+	return size
+}
+
+// tempFiles returns leftovers of interrupted backups and upgrades.
+func tempFiles() []string {
+	list := backup.TempFiles(backupFolder)
+
+	if matches, err := filepath.Glob(filepath.Join(os.TempDir(), "spicetify-*")); err == nil {
+		for _, match := range matches {
+			if strings.HasSuffix(match, ".zip") || strings.HasSuffix(match, ".tar.gz") {
+				list = append(list, match)
+			}
+		}
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		if exe, err = filepath.EvalSymlinks(exe); err == nil {
+			if _, err := os.Stat(exe + ".old"); err == nil {
+				list = append(list, exe+".old")
+			}
+		}
+	}
+
+	return list
+}
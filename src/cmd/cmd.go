@@ -1,54 +1,190 @@
 package cmd
 
 import (
-	"bufio"
-	"fmt"
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/apply"
+	"github.com/khanhas/spicetify-cli/src/backup"
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
 var (
-	spicetifyFolder         = getSpicetifyFolder()
-	rawFolder, themedFolder = getExtractFolder()
-	backupFolder            = getUserFolder("Backup")
-	userThemesFolder        = getUserFolder("Themes")
-	userExtensionsFolder    = getUserFolder("Extensions")
-	userAppsFolder          = getUserFolder("CustomApps")
-	quiet                   bool
-	isAppX                  = false
-	spotifyPath             string
-	prefsPath               string
-	appPath                 string
-	appDestPath             string
-	cfg                     utils.Config
-	settingSection          *ini.Section
-	backupSection           *ini.Section
-	preprocSection          *ini.Section
-	featureSection          *ini.Section
-	patchSection            *ini.Section
-	themeFolder             string
-	colorCfg                *ini.File
-	colorSection            *ini.Section
-	injectCSS               bool
-	replaceColors           bool
-	overwriteAssets         bool
+	spicetifyFolder      = getSpicetifyFolder()
+	cacheFolder          string
+	rawFolder            string
+	themedFolder         string
+	backupFolder         = getUserFolder("Backup")
+	userThemesFolder     = getUserFolder("Themes")
+	userExtensionsFolder = getUserFolder("Extensions")
+	userAppsFolder       = getUserFolder("CustomApps")
+	configPath           string
+	freshConfig          bool
+	quiet                bool
+	isAppX               = false
+	spotifyPath          string
+	prefsPath            string
+	appPath              string
+	appDestPath          string
+	cfg                  utils.Config
+	settingSection       *ini.Section
+	backupSection        *ini.Section
+	preprocSection       *ini.Section
+	featureSection       *ini.Section
+	patchSection         *ini.Section
+	snippetSection       *ini.Section
+	themeFolder          string
+	colorCfg             *ini.File
+	colorSection         *ini.Section
+	injectCSS            bool
+	replaceColors        bool
+	overwriteAssets      bool
+	// stdinConfig is content of config read from stdin.
+	stdinConfig []byte
 )
 
 // InitConfig gets and parses config file.
-func InitConfig(isQuiet bool) {
+// `path` overrides default config file location. When it is "-", config
+// is read from stdin and changes to it are not saved.
+func InitConfig(isQuiet bool, path string) {
 	quiet = isQuiet
 
-	cfg = utils.ParseConfig(GetConfigPath())
+	if path == "-" {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			utils.Fatal(err)
+		}
+		stdinConfig = content
+
+		cfg, err = utils.ParseConfigContent(content)
+		if err != nil {
+			utils.Fatal(err)
+		}
+	} else {
+		if len(path) > 0 {
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			configPath = path
+		}
+
+		_, err := os.Stat(GetConfigPath())
+		_, legacyErr := os.Stat(utils.LegacyConfigPath(GetConfigPath()))
+		freshConfig = os.IsNotExist(err) && legacyErr != nil
+		cfg = utils.ParseConfig(GetConfigPath())
+	}
+
 	settingSection = cfg.GetSection("Setting")
 	backupSection = cfg.GetSection("Backup")
 	preprocSection = cfg.GetSection("Preprocesses")
 	featureSection = cfg.GetSection("AdditionalOptions")
 	patchSection = cfg.GetSection("Patch")
+	snippetSection = cfg.GetSection("JsSnippets")
+
+	initDebugger()
+	initCacheFolder()
+	utils.DownloadMirror = settingSection.Key("download_mirror").String()
+}
+
+// initCacheFolder sets up folders of regenerable files, like extracted
+// app files, and moves them out of spicetify folder if they are still
+// there from older versions.
+func initCacheFolder() {
+	cacheFolder = settingSection.Key("cache_path").String()
+	if len(cacheFolder) == 0 {
+		cacheFolder = getCacheFolder()
+	}
+	utils.CheckExistAndCreate(cacheFolder)
+	utils.FetchCacheFolder = filepath.Join(cacheFolder, "Fetch")
+
+	extractFolder := filepath.Join(cacheFolder, "Extracted")
+	migrateLegacyCache(filepath.Join(spicetifyFolder, "Extracted"), extractFolder)
+
+	rawFolder = filepath.Join(extractFolder, "Raw")
+	utils.CheckExistAndCreate(rawFolder)
+
+	themedFolder = filepath.Join(extractFolder, "Themed")
+	utils.CheckExistAndCreate(themedFolder)
+}
+
+// getCacheFolder returns default cache location of each platform.
+func getCacheFolder() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "spicetify")
+	case "darwin":
+		parent, isAvailable := os.LookupEnv("XDG_CACHE_HOME")
+		if !isAvailable || len(parent) == 0 {
+			parent = filepath.Join(os.Getenv("HOME"), "Library", "Caches")
+		}
+		return filepath.Join(parent, "spicetify")
+	default:
+		parent, isAvailable := os.LookupEnv("XDG_CACHE_HOME")
+		if !isAvailable || len(parent) == 0 {
+			parent = filepath.Join(os.Getenv("HOME"), ".cache")
+		}
+		return filepath.Join(parent, "spicetify")
+	}
+}
+
+// migrateLegacyCache moves `old` cache folder to `dest`. When `dest` is
+// already in use, `old` is outdated and is removed.
+func migrateLegacyCache(old, dest string) {
+	if filepath.Clean(old) == filepath.Clean(dest) {
+		return
+	}
+
+	if _, err := os.Stat(old); err != nil {
+		return
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		os.RemoveAll(old)
+		return
+	}
+
+	utils.CheckExistAndCreate(filepath.Dir(dest))
+	if err := os.Rename(old, dest); err == nil {
+		return
+	}
+
+	// Rename fails when cache is on a different drive.
+	if err := utils.Copy(old, dest, true, nil); err != nil {
+		utils.PrintWarningOf("Cannot move extracted files to cache folder: ", err)
+		os.RemoveAll(dest)
+		return
+	}
+	os.RemoveAll(old)
+}
+
+// initDebugger points debugger utilities to address set in config.
+func initDebugger() {
+	if port, err := settingSection.Key("debug_port").Int(); err == nil && port > 0 {
+		utils.DebuggerPort = port
+	}
+
+	if host := settingSection.Key("debug_host").String(); len(host) > 0 {
+		utils.DebuggerHost = host
+	}
+	utils.DebuggerExpose = settingSection.Key("debug_expose").MustBool(false)
+
+	if timeout, err := time.ParseDuration(settingSection.Key("debug_timeout").String()); err == nil && timeout > 0 {
+		utils.DebuggerTimeout = timeout
+	}
+
+	if retries, err := settingSection.Key("debug_retries").Int(); err == nil && retries >= 0 {
+		utils.DebuggerRetries = retries
+	}
+
+	if backoff, err := time.ParseDuration(settingSection.Key("debug_backoff").String()); err == nil && backoff > 0 {
+		utils.DebuggerBackoff = backoff
+	}
 }
 
 // InitPaths checks various essential paths' availablities,
@@ -58,11 +194,11 @@ func InitPaths() {
 	spotifyPath = settingSection.Key("spotify_path").String()
 
 	if len(spotifyPath) == 0 {
-		spotifyPath = utils.FindAppPath()
+		spotifyPath = selectCandidate("Spotify installs", utils.FindAppPaths())
 
 		if len(spotifyPath) == 0 {
 			utils.PrintError(`Cannot detect Spotify location. Please manually set "spotify_path" in config-xpui.ini`)
-			os.Exit(1)
+			os.Exit(utils.ExitSpotifyNotFound)
 		}
 
 		settingSection.Key("spotify_path").SetValue(spotifyPath)
@@ -81,7 +217,7 @@ func InitPaths() {
 			return
 		}
 		utils.PrintError(spotifyPath + ` does not exist or is not a valid path. Please manually set "spotify_path" in config-xpui.ini to correct directory of Spotify.`)
-		os.Exit(1)
+		os.Exit(utils.ExitSpotifyNotFound)
 	}
 
 	prefsPath = settingSection.Key("prefs_path").String()
@@ -89,18 +225,26 @@ func InitPaths() {
 	if len(prefsPath) != 0 {
 		if _, err := os.Stat(prefsPath); err != nil {
 			utils.PrintError(prefsPath + ` does not exist or is not a valid path. Please manually set "prefs_path" in config-xpui.ini to correct path of "prefs" file.`)
-			os.Exit(1)
+			os.Exit(utils.ExitPrefsNotFound)
 		}
-	} else if prefsPath = utils.FindPrefFilePath(); len(prefsPath) != 0 {
+	} else if prefsPath = selectCandidate(`Spotify "prefs" files`, prefsCandidates(utils.FindPrefFilePaths())); len(prefsPath) != 0 {
 		settingSection.Key("prefs_path").SetValue(prefsPath)
 		cfg.Write()
 	} else {
 		utils.PrintError(`Cannot detect Spotify "prefs" file location. Please manually set "prefs_path" in config-xpui.ini`)
-		os.Exit(1)
+		os.Exit(utils.ExitPrefsNotFound)
 	}
 
 	appPath = filepath.Join(spotifyPath, "Apps")
 
+	if utils.IsSpotifyPreview(spotifyPath) {
+		utils.PrintInfo("Using Spotify preview build: " + spotifyPath)
+	}
+
+	if err := backup.MigrateLegacy(backupFolder, backupSection.Key("version").String()); err != nil {
+		utils.PrintWarningOf("Cannot compress old backup: ", err)
+	}
+
 	if isAppX {
 		appDestPath = filepath.Join(spicetifyFolder, "AppX")
 	} else {
@@ -116,7 +260,7 @@ func InitSetting() {
 	injectCSS = settingSection.Key("inject_css").MustBool(false)
 	overwriteAssets = settingSection.Key("overwrite_assets").MustBool(false)
 
-	themeName := settingSection.Key("current_theme").String()
+	themeName := currentThemeName()
 
 	if len(themeName) == 0 {
 		injectCSS = false
@@ -128,7 +272,7 @@ func InitSetting() {
 	themeFolder = getThemeFolder(themeName)
 
 	colorPath := filepath.Join(themeFolder, "color.ini")
-	cssPath := filepath.Join(themeFolder, "user.css")
+	cssPath := apply.UserCSSPath(themeFolder)
 	assetsPath := filepath.Join(themeFolder, "assets")
 
 	if replaceColors {
@@ -146,6 +290,14 @@ func InitSetting() {
 		overwriteAssets = err == nil
 	}
 
+	// Single-file theme is only CSS, default colors are used.
+	if cssPath == themeFolder {
+		replaceColors = false
+		colorCfg = nil
+		colorSection = nil
+		return
+	}
+
 	var err error
 	colorCfg, err = ini.InsensitiveLoad(colorPath)
 	if err != nil {
@@ -183,6 +335,10 @@ func InitSetting() {
 
 // GetConfigPath returns location of config file
 func GetConfigPath() string {
+	if len(configPath) > 0 {
+		return configPath
+	}
+
 	return filepath.Join(spicetifyFolder, "config-xpui.ini")
 }
 
@@ -234,34 +390,60 @@ func getUserFolder(name string) string {
 	return dir
 }
 
-func getExtractFolder() (string, string) {
-	dir := getUserFolder("Extracted")
+func getThemeFolder(themeName string) string {
+	if isRemoteTheme(themeName) {
+		folder, err := remoteThemeFolder(themeName)
+		if err != nil {
+			utils.PrintErrorOf(`Cannot use theme "`+themeName+`": `, err)
+			os.Exit(utils.ExitThemeNotFound)
+		}
+		return folder
+	}
+
+	folder, err := findThemeFolder(themeName)
+	if err != nil {
+		utils.PrintError(`Theme "` + themeName + `" not found`)
+		os.Exit(utils.ExitThemeNotFound)
+	}
+
+	return folder
+}
 
-	raw := filepath.Join(dir, "Raw")
-	utils.CheckExistAndCreate(raw)
+// themesFolders returns folders themes are looked up in, by priority.
+// Folders in "theme_paths" come after built-in ones.
+func themesFolders() []string {
+	folders := []string{
+		userThemesFolder,
+		filepath.Join(utils.GetExecutableDir(), "Themes"),
+	}
 
-	themed := filepath.Join(dir, "Themed")
-	utils.CheckExistAndCreate(themed)
+	for _, folder := range settingSection.Key("theme_paths").Strings("|") {
+		folders = append(folders, folder)
+	}
 
-	return raw, themed
+	return folders
 }
 
-func getThemeFolder(themeName string) string {
-	folder := filepath.Join(userThemesFolder, themeName)
-	_, err := os.Stat(folder)
-	if err == nil {
-		return folder
+// findThemeFolder returns folder of theme `themeName`, or its CSS file
+// when it is a single-file theme, named with or without ".css".
+func findThemeFolder(themeName string) (string, error) {
+	for _, parent := range themesFolders() {
+		folder := filepath.Join(parent, themeName)
+		if _, err := os.Stat(folder); err == nil {
+			return folder, nil
+		}
 	}
 
-	folder = filepath.Join(utils.GetExecutableDir(), "Themes", themeName)
-	_, err = os.Stat(folder)
-	if err == nil {
-		return folder
+	if !strings.HasSuffix(themeName, ".css") {
+		for _, parent := range themesFolders() {
+			file := filepath.Join(parent, themeName+".css")
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file, nil
+			}
+		}
 	}
 
-	utils.PrintError(`Theme "` + themeName + `" not found`)
-	os.Exit(1)
-	return ""
+	return "", errors.New(`theme "` + themeName + `" not found`)
 }
 
 // ReadAnswer prints out a yes/no form with string from `info`
@@ -274,9 +456,8 @@ func ReadAnswer(info string, defaultAnswer bool, quietModeAnswer bool) bool {
 		return quietModeAnswer
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(info)
-	text, _ := reader.ReadString('\n')
+	printPrompt(info)
+	text, _ := stdinReader.ReadString('\n')
 	text = strings.Replace(text, "\r", "", 1)
 	text = strings.Replace(text, "\n", "", 1)
 	if len(text) == 0 {
@@ -295,15 +476,15 @@ func CheckUpgrade(version string) {
 		return
 	}
 
-	latestTag, err := FetchLatestTag()
+	latestTag, err := FetchLatestTag(utils.FetchCacheTTL)
 
 	if err != nil {
 		utils.PrintError("Cannot fetch latest release info")
-		utils.PrintError(err.Error())
+		utils.PrintErrorOf("", err)
 		return
 	}
 
-	if latestTag == version {
+	if !isNewerVersion(latestTag, version) {
 		utils.PrintInfo("spicetify up-to-date")
 	} else {
 		utils.PrintWarning("New version available!")
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/cdp"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// EditColorInteractive starts an interactive editor listing every color of
+// current color scheme. Changed values are previewed live in Spotify when
+// its debugger is reachable, and can be saved to current scheme or to a
+// new scheme.
+func EditColorInteractive() {
+	if quiet {
+		utils.PrintError(`Color editor cannot run in quiet mode.`)
+		os.Exit(utils.ExitUsage)
+	}
+
+	if !initCmdColor() {
+		return
+	}
+
+	var previewClient *cdp.Client
+	if cdp.Reachable() {
+		// Persistent, so preview keeps working when Spotify is reloaded by
+		// "spicetify watch -l" in another terminal.
+		previewClient, _ = cdp.DialPersistent()
+	}
+	if previewClient == nil {
+		utils.PrintInfo(`Spotify debugger is not reachable, live preview is disabled.`)
+		utils.PrintInfo(`Run "spicetify watch -l" in another terminal to enable it.`)
+	} else {
+		defer previewClient.Close()
+	}
+
+	keys := editorColorKeys()
+	edited := map[string]string{}
+
+	preview := func(field, value string) {
+		if previewClient == nil {
+			return
+		}
+
+		script := colorSwapScript(map[string]string{field: value})
+		if _, err := previewClient.Evaluate(script); err != nil {
+			utils.PrintWarning("Cannot send preview to Spotify, live preview is disabled.")
+			previewClient.Close()
+			previewClient = nil
+		}
+	}
+
+	clearPreview := func() {
+		if previewClient == nil {
+			return
+		}
+
+		script := ""
+		for field := range edited {
+			script += fmt.Sprintf(
+				`document.documentElement.style.removeProperty("--spice-%s");`+
+					`document.documentElement.style.removeProperty("--spice-rgb-%s");`,
+				field, field)
+		}
+		previewClient.Evaluate(script)
+	}
+
+	printEditorColors(keys)
+	printEditorHelp()
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		printPrompt(utils.Bold("[" + colorSection.Name() + "]> "))
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			clearPreview()
+			log.Println()
+			return
+		}
+
+		args := strings.Fields(text)
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "list", "ls":
+			printEditorColors(keys)
+
+		case "help", "?":
+			printEditorHelp()
+
+		case "save":
+			saveEditedScheme(colorSection)
+			edited = map[string]string{}
+
+		case "save-as":
+			if len(args) < 2 {
+				utils.PrintError(`Usage: save-as <scheme name>`)
+				continue
+			}
+			if newSection := saveEditedSchemeAs(args[1]); newSection != nil {
+				colorSection = newSection
+				edited = map[string]string{}
+			}
+
+		case "quit", "exit", "q":
+			if len(edited) > 0 &&
+				!ReadAnswer("Discard unsaved changes? [y/N] ", false, true) {
+				continue
+			}
+			clearPreview()
+			return
+
+		default:
+			if len(args) < 2 {
+				utils.PrintError(`Usage: <color name | number> <value>`)
+				continue
+			}
+
+			field := args[0]
+			if index, err := strconv.Atoi(field); err == nil {
+				if index < 1 || index > len(keys) {
+					utils.PrintError("Color number is out of range.")
+					continue
+				}
+				field = keys[index-1]
+			}
+
+			if _, err := colorSection.GetKey(field); err != nil &&
+				len(utils.BaseColorList[field]) == 0 {
+				utils.PrintWarning(`Color "` + field + `" unchanged: Not found.`)
+				continue
+			}
+
+			value := utils.ParseColor(args[1]).Hex()
+			colorSection.Key(field).SetValue(value)
+			edited[field] = value
+			preview(field, value)
+			log.Println(formatName(field) + formatColor(value))
+		}
+	}
+}
+
+// editorColorKeys returns base color names, followed by extra color names
+// declared in current scheme.
+func editorColorKeys() []string {
+	keys := append([]string{}, utils.BaseColorOrder...)
+
+	for _, v := range colorSection.Keys() {
+		if len(utils.BaseColorList[v.Name()]) == 0 {
+			keys = append(keys, v.Name())
+		}
+	}
+
+	return keys
+}
+
+func printEditorColors(keys []string) {
+	for index, k := range keys {
+		colorString := colorSection.Key(k).String()
+		name := k
+
+		if len(colorString) == 0 {
+			colorString = utils.BaseColorList[k]
+			name += " (*)"
+		}
+
+		log.Println(fmt.Sprintf("%3d ", index+1) + formatName(name) + formatColor(colorString))
+	}
+}
+
+func printEditorHelp() {
+	log.Println(`
+<color name | number> <value>   Change color value. <value> can be in hex
+                                or decimal (rrr,ggg,bbb) format.
+list                            Print all colors.
+save                            Save changes to current scheme.
+save-as <scheme name>           Save colors as a new scheme and use it.
+quit                            Exit editor.`)
+}
+
+func saveEditedScheme(section *ini.Section) {
+	colorPath := filepath.Join(themeFolder, "color.ini")
+	if err := colorCfg.SaveTo(colorPath); err != nil {
+		utils.PrintErrorOf("", err)
+		return
+	}
+
+	utils.PrintSuccess(`Color scheme "` + section.Name() + `" is saved.`)
+	utils.PrintInfo(`Run "spicetify update" to apply new colors`)
+}
+
+func saveEditedSchemeAs(name string) *ini.Section {
+	if _, err := colorCfg.GetSection(name); err == nil {
+		if !ReadAnswer(`Scheme "`+name+`" already exists. Overwrite? [y/N] `, false, true) {
+			return nil
+		}
+		colorCfg.DeleteSection(name)
+	}
+
+	newSection, err := colorCfg.NewSection(name)
+	if err != nil {
+		utils.PrintErrorOf("", err)
+		return nil
+	}
+
+	for _, key := range colorSection.Keys() {
+		newSection.NewKey(key.Name(), key.Value())
+	}
+
+	// Keep unsaved edits out of the scheme we started from.
+	if original, err := ini.InsensitiveLoad(filepath.Join(themeFolder, "color.ini")); err == nil {
+		if originalSection, err := original.GetSection(colorSection.Name()); err == nil {
+			for _, key := range colorSection.Keys() {
+				if originalKey, err := originalSection.GetKey(key.Name()); err == nil {
+					key.SetValue(originalKey.Value())
+				} else {
+					colorSection.DeleteKey(key.Name())
+				}
+			}
+		}
+	}
+
+	saveEditedScheme(newSection)
+
+	settingSection.Key("color_scheme").SetValue(name)
+	cfg.Write()
+	changeSuccess("color_scheme", name)
+
+	return newSection
+}
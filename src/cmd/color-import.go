@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// colorImportFormats are theme formats "color import" reads.
+var colorImportFormats = []string{"vscode", "iterm", "windows-terminal"}
+
+// vscodeColorMap lists VS Code theme colors used for each spicetify color,
+// first one found wins.
+var vscodeColorMap = map[string][]string{
+	"text":               {"editor.foreground", "foreground"},
+	"subtext":            {"descriptionForeground", "tab.inactiveForeground", "editorLineNumber.foreground"},
+	"main":               {"editor.background"},
+	"sidebar":            {"sideBar.background", "activityBar.background", "editor.background"},
+	"player":             {"statusBar.background", "panel.background", "editor.background"},
+	"card":               {"editorWidget.background", "dropdown.background", "input.background"},
+	"shadow":             {"widget.shadow"},
+	"selected-row":       {"list.activeSelectionForeground", "editor.foreground", "foreground"},
+	"button":             {"button.background", "focusBorder", "textLink.foreground"},
+	"button-active":      {"button.hoverBackground", "button.background", "textLink.activeForeground"},
+	"button-disabled":    {"disabledForeground", "editorLineNumber.foreground"},
+	"tab-active":         {"tab.activeBackground", "list.hoverBackground", "list.inactiveSelectionBackground"},
+	"notification":       {"notificationsInfoIcon.foreground", "editorInfo.foreground", "textLink.foreground"},
+	"notification-error": {"errorForeground", "editorError.foreground"},
+	"misc":               {"editorLineNumber.foreground", "descriptionForeground"},
+}
+
+// terminalPalette is colors of a terminal theme. Ansi holds 16 colors:
+// black, red, green, yellow, blue, magenta, cyan, white, then their bright
+// versions.
+type terminalPalette struct {
+	name       string
+	background string
+	foreground string
+	selection  string
+	ansi       [16]string
+}
+
+// ColorImport converts editor or terminal theme `file` to a new color
+// scheme of current theme, named `name`, and switches to it. `format` is
+// one of colorImportFormats, blank detects it from file. Blank `name` uses
+// theme name in file.
+func ColorImport(format, file, name string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	if len(format) == 0 {
+		format = detectColorFormat(file, content)
+	}
+
+	var colors map[string]string
+	var themeName string
+	switch format {
+	case "vscode":
+		colors, themeName, err = vscodeColors(content)
+	case "iterm":
+		var palette *terminalPalette
+		if palette, err = itermPalette(content); err == nil {
+			colors, themeName = palette.colors(), palette.name
+		}
+	case "windows-terminal":
+		var palette *terminalPalette
+		if palette, err = windowsTerminalPalette(content, name); err == nil {
+			colors, themeName = palette.colors(), palette.name
+		}
+	default:
+		return utils.WithExitCode(utils.ExitUsage, errors.New(`unknown format "`+format+`", use one of: `+strings.Join(colorImportFormats, ", ")))
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(name) == 0 {
+		name = themeName
+	}
+	if len(name) == 0 {
+		name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	if !initCmdColor() {
+		os.Exit(utils.ExitThemeNotFound)
+	}
+
+	if _, err := colorCfg.GetSection(name); err == nil {
+		if !ReadAnswer(`Scheme "`+name+`" already exists. Overwrite? [y/N] `, false, true) {
+			return nil
+		}
+		colorCfg.DeleteSection(name)
+	}
+
+	section, err := colorCfg.NewSection(name)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range utils.BaseColorOrder {
+		value, ok := colors[key]
+		if !ok {
+			value = utils.BaseColorList[key]
+			utils.PrintWarning(fmt.Sprintf(`No color found for "%s", using default %s.`, key, value))
+		}
+		section.NewKey(key, value)
+		log.Println(formatName(key) + formatColor(value))
+	}
+
+	saveEditedScheme(section)
+
+	settingSection.Key("color_scheme").SetValue(name)
+	cfg.Write()
+	changeSuccess("color_scheme", name)
+	return nil
+}
+
+// detectColorFormat guesses format of theme file from its extension and
+// content.
+func detectColorFormat(file string, content []byte) string {
+	if strings.EqualFold(filepath.Ext(file), ".itermcolors") || strings.Contains(string(content), "<plist") {
+		return "iterm"
+	}
+
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(stripJSONComments(content), &probe) == nil {
+		if _, ok := probe["colors"]; ok {
+			return "vscode"
+		}
+		if _, ok := probe["tokenColors"]; ok {
+			return "vscode"
+		}
+	}
+
+	return "windows-terminal"
+}
+
+func vscodeColors(content []byte) (map[string]string, string, error) {
+	var theme struct {
+		Name   string            `json:"name"`
+		Colors map[string]string `json:"colors"`
+	}
+	if err := json.Unmarshal(stripJSONComments(content), &theme); err != nil {
+		return nil, "", errors.New("invalid VS Code theme: " + err.Error())
+	}
+	if len(theme.Colors) == 0 {
+		return nil, "", errors.New(`invalid VS Code theme: no "colors" found`)
+	}
+
+	colors := map[string]string{}
+	for key, sources := range vscodeColorMap {
+		for _, source := range sources {
+			if value, ok := theme.Colors[source]; ok && len(value) > 0 {
+				colors[key] = utils.ParseColor(value).Hex()
+				break
+			}
+		}
+	}
+
+	return colors, theme.Name, nil
+}
+
+// colors maps terminal palette to spicetify colors.
+func (p *terminalPalette) colors() map[string]string {
+	source := map[string]string{
+		"text":               p.foreground,
+		"subtext":            p.ansi[7],
+		"main":               p.background,
+		"sidebar":            p.ansi[0],
+		"player":             p.ansi[0],
+		"card":               p.selection,
+		"shadow":             "000000",
+		"selected-row":       p.foreground,
+		"button":             p.ansi[2],
+		"button-active":      p.ansi[10],
+		"button-disabled":    p.ansi[8],
+		"tab-active":         p.selection,
+		"notification":       p.ansi[4],
+		"notification-error": p.ansi[1],
+		"misc":               p.ansi[8],
+	}
+
+	colors := map[string]string{}
+	for key, value := range source {
+		if len(value) > 0 {
+			colors[key] = utils.ParseColor(value).Hex()
+		}
+	}
+	return colors
+}
+
+// itermPalette reads iTerm2 ".itermcolors" plist.
+func itermPalette(content []byte) (*terminalPalette, error) {
+	var plist struct {
+		Dict struct {
+			Items []itermItem `xml:",any"`
+		} `xml:"dict"`
+	}
+	if err := xml.Unmarshal(content, &plist); err != nil {
+		return nil, errors.New("invalid iTerm colors file: " + err.Error())
+	}
+
+	palette := &terminalPalette{}
+	items := plist.Dict.Items
+	for i := 0; i+1 < len(items); i += 2 {
+		key, value := items[i].Content, items[i+1].rgb()
+		switch key {
+		case "Background Color":
+			palette.background = value
+		case "Foreground Color":
+			palette.foreground = value
+		case "Selection Color":
+			palette.selection = value
+		default:
+			var index int
+			if _, err := fmt.Sscanf(key, "Ansi %d Color", &index); err == nil && index >= 0 && index < 16 {
+				palette.ansi[index] = value
+			}
+		}
+	}
+
+	if len(palette.background) == 0 || len(palette.foreground) == 0 {
+		return nil, errors.New("invalid iTerm colors file: no background or foreground color")
+	}
+	return palette, nil
+}
+
+// itermItem is a "key" or "dict" element of iTerm plist. Color dicts hold
+// components as key and real pairs, in 0-1 range.
+type itermItem struct {
+	XMLName xml.Name
+	Content string `xml:",chardata"`
+	Items   []struct {
+		XMLName xml.Name
+		Content string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+func (item itermItem) rgb() string {
+	components := map[string]float64{}
+	for i := 0; i+1 < len(item.Items); i += 2 {
+		var value float64
+		fmt.Sscan(item.Items[i+1].Content, &value)
+		components[item.Items[i].Content] = value
+	}
+
+	return fmt.Sprintf("%d,%d,%d",
+		int(components["Red Component"]*255+0.5),
+		int(components["Green Component"]*255+0.5),
+		int(components["Blue Component"]*255+0.5))
+}
+
+// windowsTerminalPalette reads a Windows Terminal color scheme, either
+// alone or from "schemes" of settings.json. With settings.json, scheme
+// `name` is picked, or the first one.
+func windowsTerminalPalette(content []byte, name string) (*terminalPalette, error) {
+	type scheme struct {
+		Name                string `json:"name"`
+		Background          string `json:"background"`
+		Foreground          string `json:"foreground"`
+		SelectionBackground string `json:"selectionBackground"`
+		Black               string `json:"black"`
+		Red                 string `json:"red"`
+		Green               string `json:"green"`
+		Yellow              string `json:"yellow"`
+		Blue                string `json:"blue"`
+		Purple              string `json:"purple"`
+		Cyan                string `json:"cyan"`
+		White               string `json:"white"`
+		BrightBlack         string `json:"brightBlack"`
+		BrightRed           string `json:"brightRed"`
+		BrightGreen         string `json:"brightGreen"`
+		BrightYellow        string `json:"brightYellow"`
+		BrightBlue          string `json:"brightBlue"`
+		BrightPurple        string `json:"brightPurple"`
+		BrightCyan          string `json:"brightCyan"`
+		BrightWhite         string `json:"brightWhite"`
+	}
+
+	var settings struct {
+		scheme
+		Schemes []scheme `json:"schemes"`
+	}
+	if err := json.Unmarshal(stripJSONComments(content), &settings); err != nil {
+		return nil, errors.New("invalid Windows Terminal scheme: " + err.Error())
+	}
+
+	s := settings.scheme
+	if len(settings.Schemes) > 0 {
+		s = settings.Schemes[0]
+		for _, candidate := range settings.Schemes {
+			if strings.EqualFold(candidate.Name, name) {
+				s = candidate
+			}
+		}
+	}
+
+	if len(s.Background) == 0 || len(s.Foreground) == 0 {
+		return nil, errors.New("invalid Windows Terminal scheme: no background or foreground color")
+	}
+
+	selection := s.SelectionBackground
+	if len(selection) == 0 {
+		selection = s.BrightBlack
+	}
+
+	return &terminalPalette{
+		name:       s.Name,
+		background: s.Background,
+		foreground: s.Foreground,
+		selection:  selection,
+		ansi: [16]string{
+			s.Black, s.Red, s.Green, s.Yellow, s.Blue, s.Purple, s.Cyan, s.White,
+			s.BrightBlack, s.BrightRed, s.BrightGreen, s.BrightYellow,
+			s.BrightBlue, s.BrightPurple, s.BrightCyan, s.BrightWhite,
+		},
+	}, nil
+}
+
+var trailingCommaRe = regexp.MustCompile(`,(\s*[}\]])`)
+
+// stripJSONComments removes comments and trailing commas, which VS Code
+// and Windows Terminal allow in their JSON files.
+func stripJSONComments(content []byte) []byte {
+	var out []byte
+	inString := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				out = append(out, content[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if c == '/' && i+1 < len(content) && content[i+1] == '/' {
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			out = append(out, '\n')
+			continue
+		}
+		if c == '/' && i+1 < len(content) && content[i+1] == '*' {
+			end := strings.Index(string(content[i+2:]), "*/")
+			if end == -1 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		out = append(out, c)
+	}
+
+	return trailingCommaRe.ReplaceAll(out, []byte("$1"))
+}
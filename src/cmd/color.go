@@ -84,7 +84,7 @@ func DisplayColors() {
 func initCmdColor() bool {
 	var err error
 
-	themeName := settingSection.Key("current_theme").String()
+	themeName := currentThemeName()
 
 	if len(themeName) == 0 {
 		utils.PrintError(`Config "current_theme" is blank.`)
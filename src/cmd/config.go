@@ -16,11 +16,15 @@ func EditConfig(args []string) {
 		value := args[1]
 
 		switch field {
-		case "extensions", "custom_apps":
+		case "extensions", "custom_apps", "js_snippets":
 			arrayType(featureSection, field, value)
+		case "rotate_list", "theme_paths":
+			arrayType(settingSection, field, value)
 		case "spotify_launch_flags":
 			continue
-		case "prefs_path", "spotify_path", "current_theme", "color_scheme":
+		case "window_effect", "ui_scale":
+			stringType(featureSection, field, value)
+		case "prefs_path", "spotify_path", "current_theme", "theme_source", "color_scheme", "rotate_schemes", "debug_port", "debug_host", "debug_timeout", "debug_retries", "debug_backoff", "cache_path", "download_mirror", "update_channel", "max_backups":
 			stringType(settingSection, field, value)
 
 		default:
@@ -53,7 +57,7 @@ func DisplayAllConfig() {
 	utils.PrintBold("AdditionFeatures")
 	for _, key := range featureSection.Keys() {
 		name := key.Name()
-		if name == "extensions" || name == "custom_apps" || name == "spotify_launch_flags" {
+		if name == "extensions" || name == "custom_apps" || name == "js_snippets" || name == "spotify_launch_flags" {
 			list := key.Strings("|")
 			listLen := len(list)
 			if listLen == 0 {
@@ -75,7 +79,7 @@ func DisplayConfig(field string) {
 	key := searchField(field)
 
 	name := key.Name()
-	if name == "extensions" || name == "custom_apps" {
+	if name == "extensions" || name == "custom_apps" || name == "rotate_list" {
 		list := key.Strings("|")
 		for _, ext := range list {
 			log.Println(ext)
@@ -95,7 +99,7 @@ func searchField(field string) *ini.Key {
 			key, err = featureSection.GetKey(field)
 			if err != nil {
 				unchangeWarning(field, `Not a valid field.`)
-				os.Exit(1)
+				os.Exit(utils.ExitUsage)
 			}
 		}
 	}
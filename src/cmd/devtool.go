@@ -1,14 +1,47 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 
 	"github.com/go-ini/ini"
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
-// SetDevTool enables/disables developer mode of Spotify client
+// SetDevTool enables/disables developer mode of Spotify client.
+// Besides the prefs flag, Spotify's offline.bnk is patched so DevTools
+// can be opened from inside the client without launch flags. Original
+// bytes are kept to restore stock behavior when disabling.
 func SetDevTool(enable bool) {
+	if isSpotifyRunning() {
+		utils.PrintInfo("Closing Spotify to change developer mode...")
+		quitSpotify()
+	}
+
+	if err := setDevToolPref(enable); err != nil {
+		utils.Fatal(err)
+	}
+
+	if err := patchOfflineBnk(enable); err != nil {
+		utils.PrintWarningOf("Cannot patch offline.bnk: ", err)
+		utils.PrintInfo("DevTools can still be opened after Spotify is launched with developer mode flag.")
+	}
+
+	if enable {
+		utils.PrintSuccess("DevTool enabled!")
+	} else {
+		utils.PrintSuccess("DevTool disabled!")
+	}
+}
+
+func setDevToolPref(enable bool) error {
 	pref, err := ini.LoadSources(
 		ini.LoadOptions{
 			PreserveSurroundedQuote: true,
@@ -33,11 +66,96 @@ func SetDevTool(enable bool) {
 	}
 
 	ini.PrettyFormat = false
-	if pref.SaveTo(prefsPath) == nil {
+	return pref.SaveTo(prefsPath)
+}
+
+// getOfflineBnkPath returns location of Spotify's offline.bnk, where
+// client feature flags are cached.
+func getOfflineBnkPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		if isAppX {
+			return filepath.Join(filepath.Dir(prefsPath), "offline.bnk")
+		}
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "Spotify", "offline.bnk")
+	case "linux":
+		if isFlatpak() {
+			return filepath.Join(os.Getenv("HOME"), ".var", "app", flatpakID, "cache", "spotify", "offline.bnk")
+		}
+		return filepath.Join(os.Getenv("HOME"), ".cache", "spotify", "offline.bnk")
+	case "darwin":
+		if len(prefsPath) > 0 {
+			return filepath.Join(filepath.Dir(prefsPath), "PersistentCache", "offline.bnk")
+		}
+		return filepath.Join(os.Getenv("HOME"), "Library", "Application Support", "Spotify", "PersistentCache", "offline.bnk")
+	}
+
+	return ""
+}
+
+func devToolStatePath() string {
+	return filepath.Join(spicetifyFolder, "devtools.json")
+}
+
+// patchOfflineBnk flips "app-developer" feature flags in offline.bnk.
+// Enabling saves original bytes, which are written back when disabling.
+func patchOfflineBnk(enable bool) error {
+	bnkPath := getOfflineBnkPath()
+	content, err := ioutil.ReadFile(bnkPath)
+	if err != nil {
+		return err
+	}
+
+	keyword := []byte("app-developer")
+	first := bytes.Index(content, keyword)
+	last := bytes.LastIndex(content, keyword)
+	if first == -1 {
+		return errors.New(`"app-developer" flag not found. Log in to Spotify once then try again`)
+	}
+
+	// Flag value is right after the keyword, with different padding
+	// in first and last occurrence.
+	positions := []int{first + len(keyword) + 1, last + len(keyword) + 2}
+	for _, pos := range positions {
+		if pos >= len(content) {
+			return errors.New("unexpected offline.bnk format")
+		}
+	}
+
+	original := map[string]byte{}
+	if stateContent, err := ioutil.ReadFile(devToolStatePath()); err == nil {
+		json.Unmarshal(stateContent, &original)
+	}
+
+	if !enable && len(original) == 0 {
+		return errors.New("original flags are unknown, offline.bnk is left unchanged. Delete it to reset Spotify feature flags")
+	}
+
+	for _, pos := range positions {
+		key := strconv.Itoa(pos)
 		if enable {
-			utils.PrintSuccess("DevTool enabled!")
-		} else {
-			utils.PrintSuccess("DevTool disabled!")
+			if _, ok := original[key]; !ok {
+				original[key] = content[pos]
+			}
+			content[pos] = '2'
+		} else if value, ok := original[key]; ok {
+			content[pos] = value
 		}
 	}
+
+	if err = ioutil.WriteFile(bnkPath, content, 0644); err != nil {
+		return err
+	}
+
+	if !enable {
+		utils.CheckExistAndDelete(devToolStatePath())
+		return nil
+	}
+
+	stateContent, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(devToolStatePath(), stateContent, 0700)
 }
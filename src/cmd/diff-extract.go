@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+const (
+	// diffContext is number of unchanged lines shown around changes.
+	diffContext = 3
+	// diffSplitLength is line length from which minified lines are split
+	// into statements, so one change doesn't print whole file.
+	diffSplitLength = 300
+)
+
+// DiffExtract prints a unified diff between Raw and Themed versions of
+// `file`, which is a path relative to extracted folder, e.g.
+// "xpui/xpui.js", or relative to xpui folder.
+func DiffExtract(file string) error {
+	if !isExtracted() {
+		return errors.New(`no extracted files. Run "spicetify backup" first`)
+	}
+
+	file = filepath.FromSlash(file)
+	if _, err := os.Stat(filepath.Join(rawFolder, file)); err != nil {
+		file = filepath.Join("xpui", file)
+	}
+
+	rawPath := filepath.Join(rawFolder, file)
+	themedPath := filepath.Join(themedFolder, file)
+	raw, err := ioutil.ReadFile(rawPath)
+	if err != nil {
+		return utils.WithExitCode(utils.ExitUsage, errors.New(`cannot find "`+filepath.ToSlash(file)+`" in extracted files`))
+	}
+	themed, err := ioutil.ReadFile(themedPath)
+	if err != nil {
+		return errors.New(`cannot find "` + filepath.ToSlash(file) + `" in themed files. Run "spicetify backup" again`)
+	}
+
+	diff := utils.UnifiedDiff(diffSplitLines(string(raw)), diffSplitLines(string(themed)), diffContext)
+	if diff == nil {
+		utils.PrintInfo("No difference.")
+		return nil
+	}
+
+	log.Println(utils.Bold("--- Raw/" + filepath.ToSlash(file)))
+	log.Println(utils.Bold("+++ Themed/" + filepath.ToSlash(file)))
+	for _, line := range diff {
+		log.Println(line)
+	}
+
+	return nil
+}
+
+// diffSplitLines splits content into lines. Minified lines are further
+// split after every ";", "{" and "}".
+func diffSplitLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if len(line) < diffSplitLength {
+			lines = append(lines, line)
+			continue
+		}
+
+		start := 0
+		for i := 0; i < len(line); i++ {
+			switch line[i] {
+			case ';', '{', '}':
+				lines = append(lines, line[start:i+1])
+				start = i + 1
+			}
+		}
+		if start < len(line) {
+			lines = append(lines, line[start:])
+		}
+	}
+	return lines
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+
+	"github.com/khanhas/spicetify-cli/src/cdp"
+)
+
+// errNoDebugger is returned when Spotify is not running with remote
+// debugging server on.
+var errNoDebugger = errors.New(`cannot connect to Spotify debugger. Start Spotify with "spicetify watch -l" or add "--remote-debugging-port" to "spotify_launch_flags"`)
+
+// Eval evaluates a Javascript expression in running Spotify client and
+// prints its result as JSON.
+func Eval(expression string) error {
+	return evaluateAndPrint(expression)
+}
+
+// Run injects a Javascript file into running Spotify client and executes
+// it once. Nothing is installed, so the script is gone after Spotify reloads.
+func Run(scriptPath string) error {
+	content, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.ToSlash(filepath.Base(scriptPath))
+	// Names script in DevTools Sources panel so it can be debugged.
+	script := string(content) + "\n//# sourceURL=spicetify-run/" + name
+
+	return evaluateAndPrint(script)
+}
+
+// dialSpotify connects to Spotify page debugger, or returns errNoDebugger
+// when Spotify is not running with it on.
+func dialSpotify() (*cdp.Client, error) {
+	if !cdp.Reachable() {
+		return nil, errNoDebugger
+	}
+	return cdp.Dial()
+}
+
+func evaluateAndPrint(expression string) error {
+	client, err := dialSpotify()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	result, err := client.Evaluate(expression)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err = json.Indent(&out, result, "", "    "); err != nil {
+		log.Println(string(result))
+		return nil
+	}
+
+	log.Println(out.String())
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/apply"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Extension settings are stored as one JSON object of string values per
+// extension, in "ExtensionSettings" folder, and injected into Spotify so
+// they survive clearing Spotify data, unlike localStorage.
+
+func extensionSettingsPath(name string) string {
+	return filepath.Join(spicetifyFolder, "ExtensionSettings", filepath.Base(name)+".json")
+}
+
+func readExtensionSettings(name string) map[string]string {
+	settings := map[string]string{}
+	content, err := ioutil.ReadFile(extensionSettingsPath(name))
+	if err != nil {
+		return settings
+	}
+
+	if err = json.Unmarshal(content, &settings); err != nil {
+		utils.PrintWarningOf(`Cannot read settings of extension "`+name+`": `, err)
+	}
+
+	return settings
+}
+
+func writeExtensionSettings(name string, settings map[string]string) error {
+	path := extensionSettingsPath(name)
+	if len(settings) == 0 {
+		utils.CheckExistAndDelete(path)
+		return nil
+	}
+
+	content, err := json.MarshalIndent(settings, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	utils.CheckExistAndCreate(filepath.Dir(path))
+	return ioutil.WriteFile(path, content, 0700)
+}
+
+// ExtConfig prints settings of extension `name`, or changes them with
+// "key=value" pairs. Blank value, "key=", removes the key. It returns
+// whether settings are changed.
+func ExtConfig(name string, pairs []string) bool {
+	resolved, ok := resolveExtensionName(name)
+	if !ok {
+		utils.PrintError(`Extension "` + name + `" not found.`)
+		os.Exit(utils.ExitUsage)
+	}
+	name = filepath.Base(resolved)
+
+	settings := readExtensionSettings(name)
+	if len(pairs) == 0 {
+		keys := make([]string, 0, len(settings))
+		for key := range settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			log.Println(key + " = " + settings[key])
+		}
+		return false
+	}
+
+	for _, pair := range pairs {
+		eq := strings.Index(pair, "=")
+		if eq < 1 {
+			utils.PrintError(`"` + pair + `" is not in "key=value" format.`)
+			os.Exit(utils.ExitUsage)
+		}
+
+		key, value := pair[:eq], pair[eq+1:]
+		if len(value) == 0 {
+			delete(settings, key)
+			utils.PrintSuccess(name + ": " + key + " is removed")
+		} else {
+			settings[key] = value
+			utils.PrintSuccess(name + ": " + key + " = " + value)
+		}
+	}
+
+	if err := writeExtensionSettings(name, settings); err != nil {
+		utils.Fatal(err)
+	}
+
+	return true
+}
+
+// enabledExtensionSettings returns settings of extensions in `list`.
+func enabledExtensionSettings(list []string) map[string]map[string]string {
+	result := map[string]map[string]string{}
+	for _, name := range list {
+		name = filepath.Base(name)
+		if settings := readExtensionSettings(name); len(settings) > 0 {
+			result[name] = settings
+		}
+	}
+
+	return result
+}
+
+// RefreshExtensionSettings rewrites settings helper when it is already
+// injected, so changes only need a Spotify reload.
+func RefreshExtensionSettings() {
+	helper := filepath.Join(appDestPath, "xpui", "helper", "extensionSettings.js")
+	if _, err := os.Stat(helper); err != nil {
+		utils.PrintInfo(`Run "spicetify apply" to apply new settings`)
+		return
+	}
+
+	list := expandExtensionList(featureSection.Key("extensions").Strings("|"))
+	if err := apply.ExtensionSettings(appDestPath, enabledExtensionSettings(list)); err != nil {
+		utils.PrintErrorOf("", err)
+		return
+	}
+	updateAppliedRecord(helper)
+	utils.PrintInfo("Reload or restart Spotify to apply new settings")
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// ExtList prints enabled extensions, in injection order, and other
+// available extensions.
+func ExtList() {
+	enabled := featureSection.Key("extensions").Strings("|")
+	isEnabled := map[string]bool{}
+
+	utils.PrintBold("Enabled:")
+	if len(enabled) == 0 {
+		log.Println("    (none)")
+	}
+	for _, name := range enabled {
+		isEnabled[name] = true
+		if strings.ContainsAny(name, "*?[") {
+			matches := expandExtensionList([]string{name})
+			for _, match := range matches {
+				isEnabled[match] = true
+			}
+			log.Println("    " + name + " (" + strings.Join(matches, ", ") + ")")
+			continue
+		}
+
+		if _, err := getExtensionPath(name); err != nil && !filepath.IsAbs(name) {
+			log.Println("    " + name + utils.Red(" (not found)"))
+		} else {
+			log.Println("    " + name)
+		}
+	}
+
+	utils.PrintBold("Available:")
+	available := 0
+	for _, name := range availableExtensions() {
+		if !isEnabled[name] {
+			log.Println("    " + name)
+			available++
+		}
+	}
+	if available == 0 {
+		log.Println("    (none)")
+	}
+}
+
+// availableExtensions returns names of extension files in user and
+// executable folders.
+func availableExtensions() []string {
+	seen := map[string]bool{}
+	var list []string
+
+	for _, folder := range []string{userExtensionsFolder, filepath.Join(utils.GetExecutableDir(), "Extensions")} {
+		fileList, err := ioutil.ReadDir(folder)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range fileList {
+			name := file.Name()
+			if file.IsDir() || seen[name] ||
+				!(strings.HasSuffix(name, ".js") || strings.HasSuffix(name, ".mjs")) {
+				continue
+			}
+			seen[name] = true
+			list = append(list, name)
+		}
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// resolveExtensionName returns file name of extension `name`, which can be
+// written without ".js" or ".mjs".
+func resolveExtensionName(name string) (string, bool) {
+	if filepath.IsAbs(name) {
+		_, err := os.Stat(name)
+		return name, err == nil
+	}
+
+	candidates := []string{name}
+	if !strings.HasSuffix(name, ".js") && !strings.HasSuffix(name, ".mjs") {
+		candidates = append(candidates, name+".js", name+".mjs")
+	}
+
+	for _, candidate := range candidates {
+		if _, err := getExtensionPath(candidate); err == nil {
+			return candidate, true
+		}
+	}
+
+	return name, false
+}
+
+// ExtToggle enables or disables extensions in `names`. It returns whether
+// config is changed.
+func ExtToggle(names []string, enable bool) bool {
+	key := featureSection.Key("extensions")
+	changed := false
+
+	for _, name := range names {
+		if enable && strings.ContainsAny(name, "*?[") {
+			changed = addToList(key, name) || changed
+		} else if enable {
+			resolved, ok := resolveExtensionName(name)
+			if !ok {
+				utils.PrintError(`Extension "` + name + `" not found.`)
+				continue
+			}
+			changed = addToList(key, resolved) || changed
+		} else {
+			resolved := name
+			if !listContains(key, resolved) {
+				resolved, _ = resolveExtensionName(name)
+			}
+			changed = removeFromList(key, resolved) || changed
+		}
+	}
+
+	if changed {
+		cfg.Write()
+	}
+
+	return changed
+}
+
+// addToList appends value to end of a "|" separated list key, keeping
+// order of existing entries.
+func addToList(key *ini.Key, value string) bool {
+	if listContains(key, value) {
+		unchangeWarning(key.Name(), value+" is already in the list.")
+		return false
+	}
+
+	list := append(key.Strings("|"), value)
+	key.SetValue(strings.Join(list, "|"))
+	changeSuccess(key.Name(), key.String())
+	return true
+}
+
+// removeFromList removes value from a "|" separated list key.
+func removeFromList(key *ini.Key, value string) bool {
+	if !listContains(key, value) {
+		unchangeWarning(key.Name(), value+" is not on the list.")
+		return false
+	}
+
+	var list []string
+	for _, entry := range key.Strings("|") {
+		if entry != value {
+			list = append(list, entry)
+		}
+	}
+
+	key.SetValue(strings.Join(list, "|"))
+	changeSuccess(key.Name(), key.String())
+	return true
+}
+
+func listContains(key *ini.Key, value string) bool {
+	for _, entry := range key.Strings("|") {
+		if entry == value {
+			return true
+		}
+	}
+
+	return false
+}
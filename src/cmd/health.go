@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/cdp"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+const (
+	healthTimeout = 60 * time.Second
+	// healthListenTime is how long uncaught exceptions are collected after
+	// xpui is rendered, so extensions have time to start.
+	healthListenTime = 5 * time.Second
+)
+
+// exposedAPIs are checked when "expose_apis" is on.
+var exposedAPIs = []string{"Player", "React", "ReactDOM", "URI", "Platform"}
+
+// HealthCheck waits for Spotify started with debugger on to render xpui,
+// then checks that Spicetify APIs are exposed, no uncaught exception is
+// thrown and enabled extensions are loaded.
+func HealthCheck() error {
+	utils.PrintBold("Checking Spotify health:")
+	client, ok := waitForXpui(healthTimeout)
+	if !ok {
+		return utils.WithExitCode(utils.ExitHealthCheck, fmt.Errorf("Spotify does not render in %s. It may show a blank screen", healthTimeout))
+	}
+	defer client.Close()
+
+	var problems []string
+
+	exceptions, err := uncaughtExceptions(client, healthListenTime)
+	if err != nil {
+		return err
+	}
+	for _, exception := range exceptions {
+		problems = append(problems, "Uncaught exception: "+exception)
+	}
+
+	if preprocSection.Key("expose_apis").MustBool(false) {
+		missing, err := evaluateStrings(client, exposedAPIs, `names => names.filter(name => typeof Spicetify !== "object" || Spicetify[name] == null)`)
+		if err != nil {
+			return err
+		}
+		for _, name := range missing {
+			problems = append(problems, "Spicetify."+name+" is not exposed")
+		}
+	}
+
+	var extensions []string
+	for _, name := range expandExtensionList(featureSection.Key("extensions").Strings("|")) {
+		extensions = append(extensions, filepath.Base(name))
+	}
+	if len(extensions) > 0 {
+		missing, err := evaluateStrings(client, extensions, `names => {
+			const loaded = performance.getEntriesByType("resource").map(entry => entry.name);
+			return names.filter(name => !loaded.some(url => url.endsWith("/" + name)));
+		}`)
+		if err != nil {
+			return err
+		}
+		for _, name := range missing {
+			problems = append(problems, `Extension "`+name+`" is not loaded`)
+		}
+	}
+
+	if len(problems) == 0 {
+		utils.PrintGreen("OK")
+		return nil
+	}
+
+	for _, problem := range problems {
+		log.Println("    - " + problem)
+	}
+	return utils.WithExitCode(utils.ExitHealthCheck, errors.New("Spotify failed health check"))
+}
+
+// evaluateStrings calls Javascript `function` in Spotify page with `args`
+// and returns strings array it returns.
+func evaluateStrings(client *cdp.Client, args []string, function string) ([]string, error) {
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.Evaluate("(" + function + ")(" + string(argsJSON) + ")")
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	err = json.Unmarshal(result, &values)
+	return values, err
+}
+
+// uncaughtExceptions listens to Spotify page for `duration` and returns
+// uncaught exceptions, each with its first line and source URL. Exceptions
+// thrown before listening are included, as debugger replays them when
+// Runtime domain is enabled.
+func uncaughtExceptions(client *cdp.Client, duration time.Duration) ([]string, error) {
+	events, unsubscribe := client.Subscribe("Runtime.exceptionThrown")
+	defer unsubscribe()
+
+	if err := client.Enable("Runtime"); err != nil {
+		return nil, err
+	}
+
+	var exceptions []string
+	timeout := time.After(duration)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return exceptions, cdp.ErrDisconnected
+			}
+
+			var params struct {
+				ExceptionDetails cdp.ExceptionDetails `json:"exceptionDetails"`
+			}
+			if json.Unmarshal(event.Params, &params) != nil {
+				continue
+			}
+
+			details := params.ExceptionDetails
+			message := strings.SplitN(details.Message(), "\n", 2)[0]
+			if len(details.URL) > 0 {
+				message += " (" + details.URL + ")"
+			}
+			exceptions = append(exceptions, message)
+
+		case <-timeout:
+			return exceptions, nil
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// resumeApply makes Apply continue an interrupted apply instead of asking.
+var resumeApply = false
+
+// applyJournal records apply steps as they complete, so an apply that is
+// killed midway can be detected and resumed on next run.
+type applyJournal struct {
+	Started        time.Time `json:"started"`
+	SpotifyVersion string    `json:"spotify_version"`
+	Steps          []string  `json:"steps"`
+	// Extensions and custom apps that were pushed, needed by later steps
+	// when their own steps are skipped on resume.
+	Extensions []string `json:"extensions"`
+	CustomApps []string `json:"custom_apps"`
+	// Running is a step that is not idempotent and has started but not
+	// completed. Files it touched are in unknown state.
+	Running string `json:"running,omitempty"`
+}
+
+// SetApplyResume makes next apply resume an interrupted one.
+func SetApplyResume(resume bool) {
+	resumeApply = resume
+}
+
+func applyJournalPath() string {
+	return filepath.Join(spicetifyFolder, "apply-journal.json")
+}
+
+func readApplyJournal() (*applyJournal, error) {
+	content, err := ioutil.ReadFile(applyJournalPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var journal applyJournal
+	if err = json.Unmarshal(content, &journal); err != nil {
+		return nil, err
+	}
+
+	return &journal, nil
+}
+
+// openApplyJournal returns journal of interrupted apply when user wants to
+// resume it, or starts a new one. `interrupted` reports whether previous
+// apply was interrupted, so its half-written files need to be replaced.
+func openApplyJournal() (journal *applyJournal, interrupted bool) {
+	version := utils.GetSpotifyVersion(prefsPath)
+	previous, err := readApplyJournal()
+
+	if err != nil {
+		if resumeApply {
+			utils.PrintInfo("No interrupted apply to resume. Applying from start.")
+		}
+	} else if previous.SpotifyVersion != version {
+		utils.PrintWarning("Last apply was interrupted, but Spotify is updated since then. Applying from start.")
+		interrupted = true
+	} else if len(previous.Running) > 0 {
+		// Modifications and patches cannot run twice on the same files,
+		// so everything is copied again.
+		utils.PrintWarning(`Last apply was interrupted during step "` + previous.Running + `", which cannot be resumed. Applying from start.`)
+		interrupted = true
+	} else {
+		if !resumeApply {
+			utils.PrintWarning("Last apply was interrupted after " + previous.lastStep() + ".")
+		}
+		if resumeApply || ReadAnswer("Resume it? Otherwise apply starts over. [Y/n]: ", true, false) {
+			utils.PrintInfo("Resuming apply after " + previous.lastStep() + ".")
+			return previous, false
+		}
+		interrupted = true
+	}
+
+	journal = &applyJournal{
+		Started:        time.Now(),
+		SpotifyVersion: version,
+		Steps:          []string{},
+	}
+	journal.save()
+	return journal, interrupted
+}
+
+// lastStep describes last completed step for messages.
+func (j *applyJournal) lastStep() string {
+	if len(j.Steps) == 0 {
+		return "it started"
+	}
+	return `step "` + j.Steps[len(j.Steps)-1] + `"`
+}
+
+// done reports whether `step` is completed.
+func (j *applyJournal) done(step string) bool {
+	for _, s := range j.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// begin marks not idempotent `step` as started and saves journal, so an
+// apply interrupted during it is started over instead of resumed.
+func (j *applyJournal) begin(step string) {
+	j.Running = step
+	j.save()
+}
+
+// complete marks `step` as completed and saves journal.
+func (j *applyJournal) complete(step string) {
+	j.Steps = append(j.Steps, step)
+	j.Running = ""
+	j.save()
+}
+
+// save writes journal to a temporary file then renames it, so a crash
+// while saving never leaves a broken journal.
+func (j *applyJournal) save() {
+	content, err := json.MarshalIndent(j, "", "    ")
+	if err != nil {
+		utils.PrintWarningOf("Cannot write apply journal: ", err)
+		return
+	}
+
+	path := applyJournalPath()
+	file, err := os.Create(path + ".tmp")
+	if err == nil {
+		_, err = file.Write(content)
+		if err == nil {
+			err = file.Sync()
+		}
+		file.Close()
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		utils.PrintWarningOf("Cannot write apply journal: ", err)
+	}
+}
+
+// clearApplyJournal removes journal after apply finishes or Spotify is
+// restored.
+func clearApplyJournal() {
+	utils.CheckExistAndDelete(applyJournalPath())
+}
+
+// RecoverInterruptedApply checks whether last apply was interrupted and
+// offers to resume it or restore Spotify. Nothing is done unless someone
+// at a terminal agrees. It returns whether Spotify is changed and needs a
+// restart.
+func RecoverInterruptedApply(spicetifyVersion string) bool {
+	journal, err := readApplyJournal()
+	if err != nil {
+		return false
+	}
+
+	utils.PrintWarning("Last apply was interrupted after " + journal.lastStep() + ". Spotify may be partly modified.")
+	if !IsInteractive() {
+		utils.PrintInfo(`Run "spicetify apply --resume" to finish it or "spicetify restore" to undo it.`)
+		return false
+	}
+
+	if ReadAnswer("Resume apply now? [Y/n]: ", true, false) {
+		resumeApply = true
+		if err := Apply(spicetifyVersion); err != nil {
+			utils.PrintErrorOf("", err)
+		}
+		return true
+	}
+
+	if ReadAnswer("Restore Spotify to original state instead? [y/N]: ", false, false) {
+		Restore()
+		return true
+	}
+
+	utils.PrintInfo(`Run "spicetify apply --resume" to finish it or "spicetify restore" to undo it.`)
+	return false
+}
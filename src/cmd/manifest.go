@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// setupManifest declares desired customization state. Omitted fields are
+// left as they are in config.
+type setupManifest struct {
+	Theme      *manifestTheme `json:"theme"`
+	Scheme     *string        `json:"scheme"`
+	Extensions []string       `json:"extensions"`
+	CustomApps []string       `json:"custom_apps"`
+	// Preprocesses maps "Preprocesses" toggles to on or off.
+	Preprocesses map[string]interface{} `json:"preprocesses"`
+	// Patch replaces all find/replace patches in "Patch" section.
+	Patch map[string]string `json:"patch"`
+}
+
+// manifestTheme is either a theme name or an object with theme name and
+// source URL to download it from. Source pinned to a commit, like
+// "https://github.com/user/repo/tree/<commit>/Theme", is reproducible.
+type manifestTheme struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+func (t *manifestTheme) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		if isRemoteTheme(name) {
+			t.Source = name
+		} else {
+			t.Name = name
+		}
+		return nil
+	}
+
+	type plain manifestTheme
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// manifestAction is a download or install manifest needs. Actions only run
+// after whole manifest is validated.
+type manifestAction struct {
+	name string
+	run  func() error
+}
+
+// ApplyManifest reads manifest at `manifestPath` and changes config to
+// match it: theme and extensions from URLs are downloaded and custom apps
+// installed when they are missing, while enabled ones not listed are
+// disabled. Nothing is downloaded or installed when manifest has any
+// error, and config is not changed when any of them fails. Running it
+// again with the same manifest changes nothing.
+func ApplyManifest(manifestPath string) error {
+	manifest, err := readSetupManifest(manifestPath)
+	if err != nil {
+		err = errors.New("Cannot read manifest " + manifestPath + ": " + err.Error())
+		utils.PrintErrorOf("", err)
+		return utils.WithExitCode(utils.ExitUsage, err)
+	}
+
+	utils.PrintBold("Reading manifest " + manifestPath + ":")
+	values := map[string]string{}
+	var problems []string
+	var actions []manifestAction
+
+	if manifest.Theme != nil || manifest.Scheme != nil {
+		action, err := manifestThemeValues(manifest, values)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if action != nil {
+			actions = append(actions, *action)
+		}
+	}
+
+	if manifest.Extensions != nil {
+		var names []string
+		for _, entry := range manifest.Extensions {
+			name, action, err := manifestExtension(entry)
+			if err != nil {
+				problems = append(problems, `Extension "`+entry+`": `+err.Error())
+				continue
+			}
+			names = append(names, name)
+			if action != nil {
+				actions = append(actions, *action)
+			}
+		}
+		values["extensions"] = strings.Join(names, "|")
+	}
+
+	// Installed custom app can be named differently than its URL suggests,
+	// so list is joined after installing.
+	var appNames []string
+	if manifest.CustomApps != nil {
+		appNames = []string{}
+		for _, entry := range manifest.CustomApps {
+			name, err := manifestCustomApp(entry)
+			if err != nil {
+				problems = append(problems, `Custom app "`+entry+`": `+err.Error())
+				continue
+			}
+			appNames = append(appNames, name)
+			if !isRemoteTheme(entry) || validateCustomApp(name) == nil {
+				continue
+			}
+
+			index, source := len(appNames)-1, entry
+			actions = append(actions, manifestAction{
+				name: `Custom app "` + entry + `"`,
+				run: func() error {
+					installed, err := installApp(source)
+					appNames[index] = installed
+					return err
+				},
+			})
+		}
+	}
+
+	for name, value := range manifest.Preprocesses {
+		if !preprocSection.HasKey(name) {
+			problems = append(problems, `Unknown preprocess "`+name+`"`)
+			continue
+		}
+		enabled, err := strconv.ParseBool(fmt.Sprint(value))
+		if err != nil {
+			problems = append(problems, `Preprocess "`+name+`" is either true or false`)
+			continue
+		}
+		values[name] = "0"
+		if enabled {
+			values[name] = "1"
+		}
+	}
+
+	if len(problems) == 0 {
+		for _, action := range actions {
+			if err := action.run(); err != nil {
+				problems = append(problems, action.name+": "+err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Println("    - " + problem)
+		}
+		err := errors.New("Manifest cannot be applied. Config is not changed.")
+		utils.PrintErrorOf("", err)
+		return err
+	}
+	utils.PrintGreen("OK")
+
+	if appNames != nil {
+		values["custom_apps"] = strings.Join(appNames, "|")
+	}
+
+	changed := false
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if setManifestValue(searchField(key), values[key]) {
+			changed = true
+		}
+	}
+
+	if manifest.Patch != nil && setManifestPatch(manifest.Patch) {
+		changed = true
+	}
+
+	if changed {
+		cfg.Write()
+		utils.PrintSuccess("Config matches manifest.")
+	} else {
+		utils.PrintInfo("Config already matches manifest.")
+	}
+
+	return nil
+}
+
+// readSetupManifest parses manifest in YAML or JSON.
+func readSetupManifest(manifestPath string) (*setupManifest, error) {
+	content, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data := stripJSONComments(content)
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		document, err := utils.ParseYAML(content)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(document); err != nil {
+			return nil, err
+		}
+	}
+
+	var manifest setupManifest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// manifestThemeValues validates theme and scheme of manifest and puts
+// config values for them in `values`. Theme from an URL that is not
+// downloaded yet is returned as action, which validates scheme after
+// downloading it.
+func manifestThemeValues(manifest *setupManifest, values map[string]string) (*manifestAction, error) {
+	theme := manifest.Theme
+	if theme == nil {
+		theme = &manifestTheme{
+			Name:   settingSection.Key("current_theme").String(),
+			Source: settingSection.Key("theme_source").String(),
+		}
+	}
+
+	if manifest.Theme != nil {
+		if len(theme.Name) > 0 {
+			values["current_theme"] = theme.Name
+		}
+		values["theme_source"] = theme.Source
+	}
+
+	if len(theme.Source) == 0 {
+		folder, err := findThemeFolder(theme.Name)
+		if err != nil {
+			return nil, err
+		}
+		return nil, manifestSchemeValue(manifest, folder, values)
+	}
+
+	if folder, cached := cachedRemoteTheme(theme.Source); cached {
+		return nil, manifestSchemeValue(manifest, folder, values)
+	}
+
+	if _, _, err := resolveRemoteTheme(theme.Source); err != nil {
+		return nil, err
+	}
+
+	return &manifestAction{
+		name: `Theme "` + theme.Source + `"`,
+		run: func() error {
+			folder, err := remoteThemeFolder(theme.Source)
+			if err != nil {
+				return err
+			}
+			return manifestSchemeValue(manifest, folder, values)
+		},
+	}, nil
+}
+
+// manifestSchemeValue validates scheme of manifest against schemes of
+// theme in `folder` and puts config value for it in `values`.
+func manifestSchemeValue(manifest *setupManifest, folder string, values map[string]string) error {
+	if manifest.Scheme == nil {
+		return nil
+	}
+
+	scheme := *manifest.Scheme
+	if len(scheme) > 0 {
+		schemes := themeSchemes(folder)
+		found := findScheme(schemes, scheme)
+		if len(found) == 0 {
+			return errors.New(`color scheme "` + scheme + `" not found in theme. Available: ` + strings.Join(schemes, ", "))
+		}
+		scheme = found
+	}
+	values["color_scheme"] = scheme
+	return nil
+}
+
+// manifestExtension returns name of extension `entry`. When it is an URL
+// and not downloaded yet, action downloading it into user Extensions
+// folder is returned too.
+func manifestExtension(entry string) (string, *manifestAction, error) {
+	if !isRemoteTheme(entry) {
+		if strings.ContainsAny(entry, "*?[") {
+			return entry, nil, nil
+		}
+		name, ok := resolveExtensionName(entry)
+		if !ok {
+			return "", nil, errors.New("not found")
+		}
+		return name, nil, nil
+	}
+
+	u, err := url.Parse(entry)
+	if err != nil {
+		return "", nil, err
+	}
+	name := path.Base(u.Path)
+	if !strings.HasSuffix(name, ".js") && !strings.HasSuffix(name, ".mjs") {
+		return "", nil, errors.New("URL does not point to a .js or .mjs file")
+	}
+
+	dest := filepath.Join(userExtensionsFolder, name)
+	if _, err := os.Stat(dest); err == nil {
+		return name, nil, nil
+	}
+
+	downloadURL := entry
+	if match := githubBlobRe.FindStringSubmatch(u.Path); u.Host == "github.com" && match != nil {
+		downloadURL = "https://raw.githubusercontent.com/" + match[1] + "/" + match[2] + "/" + match[3]
+	}
+
+	return name, &manifestAction{
+		name: `Extension "` + entry + `"`,
+		run: func() error {
+			utils.PrintInfo("Downloading extension " + entry)
+			res, err := utils.Download(downloadURL)
+			if err != nil {
+				return err
+			}
+			defer res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				return errors.New("cannot download: " + res.Status)
+			}
+
+			content, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(dest, content, 0644)
+		},
+	}, nil
+}
+
+// manifestCustomApp validates custom app `entry` and returns its name. An
+// URL is not installed here, its name is the one installApp would give it.
+func manifestCustomApp(entry string) (string, error) {
+	if !isRemoteTheme(entry) {
+		return entry, validateCustomApp(entry)
+	}
+
+	_, name, _, err := resolveAppSource(entry)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(appNameRe.ReplaceAllString(name, "-"), "-."), nil
+}
+
+// setManifestValue sets `key` to `value` and reports whether it changed.
+func setManifestValue(key *ini.Key, value string) bool {
+	if key.String() == value {
+		return false
+	}
+
+	key.SetValue(value)
+	log.Println("    " + key.Name() + " = " + value)
+	return true
+}
+
+// setManifestPatch replaces "Patch" section with `patches` and reports
+// whether it changed.
+func setManifestPatch(patches map[string]string) bool {
+	current := map[string]string{}
+	for _, key := range patchSection.Keys() {
+		current[key.Name()] = key.String()
+	}
+
+	same := len(current) == len(patches)
+	for name, value := range patches {
+		if existing, ok := current[name]; !ok || existing != value {
+			same = false
+		}
+	}
+	if same {
+		return false
+	}
+
+	for name := range current {
+		patchSection.DeleteKey(name)
+	}
+
+	names := make([]string, 0, len(patches))
+	for name := range patches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		patchSection.NewKey(name, patches[name])
+	}
+
+	log.Println("    [Patch] is replaced")
+	return true
+}
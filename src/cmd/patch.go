@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -9,8 +10,16 @@ import (
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
-func Patch() {
+// Patch applies find/replace patches in "Patch" config section.
+// Patches that cannot be applied are skipped and counted in returned error.
+func Patch() error {
+	return patchFile("")
+}
+
+// patchFile applies patches of file `only`, or all patches when it is blank.
+func patchFile(only string) error {
 	keys := patchSection.Keys()
+	failed := 0
 
 	re := regexp.MustCompile(`^([\w\d\-\.]+)_find_(\d+)$`)
 	for _, key := range keys {
@@ -21,11 +30,16 @@ func Patch() {
 		}
 
 		name := matches[1]
+		if len(only) > 0 && name != only {
+			continue
+		}
+
 		assetPath := filepath.Join(appPath, "xpui", name)
 		index := matches[2]
 
 		if _, err := os.Stat(assetPath); err != nil {
 			utils.PrintError("File name \"" + name + "\" is not found.")
+			failed++
 			continue
 		}
 
@@ -39,12 +53,14 @@ func Patch() {
 			utils.PrintInfo("Correct key name for replace string are")
 			utils.PrintInfo("    \"" + replOnceName + "\"")
 			utils.PrintInfo("    \"" + replName + "\"")
+			failed++
 			continue
 		}
 
 		patchRegexp, errReg := regexp.Compile(key.String())
 		if errReg != nil {
 			utils.PrintError("Cannot compile find RegExp for patch \"" + keyName + "\"")
+			failed++
 			continue
 		}
 
@@ -63,4 +79,9 @@ func Patch() {
 
 		utils.PrintSuccess("\"" + keyName + "\" is patched")
 	}
+
+	if failed > 0 {
+		return utils.WithExitCode(utils.ExitPatchFailed, fmt.Errorf("%d patch(es) could not be applied", failed))
+	}
+	return nil
 }
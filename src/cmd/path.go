@@ -53,7 +53,7 @@ func ExtensionPath(name string) (string, error) {
 
 // ExtensionAllPath returns paths of all extension files
 func ExtensionAllPath() (string, error) {
-	exts := featureSection.Key("extensions").Strings("|")
+	exts := expandExtensionList(featureSection.Key("extensions").Strings("|"))
 	results := []string{}
 	for _, v := range exts {
 		path, err := getExtensionPath(v)
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// checkWritePermission makes sure Spotify app files can be modified before
+// anything is written. When they cannot, on Windows, spicetify offers to
+// run the same command again as administrator; elsewhere, it prints how to
+// grant permission. Either way, current process stops.
+func checkWritePermission() {
+	err := testWritable(appDestPath)
+	if err == nil {
+		return
+	}
+
+	if !os.IsPermission(err) {
+		utils.Fatal(err)
+	}
+
+	utils.PrintError("Permission denied: cannot write to " + appDestPath)
+
+	if runtime.GOOS == "windows" {
+		if !isElevated() && ReadAnswer("Run this command again as administrator? [Y/n] ", true, false) {
+			os.Exit(runElevated())
+		}
+
+		utils.PrintInfo(`Run spicetify from a terminal opened with "Run as administrator", or reinstall Spotify for current user only from https://www.spotify.com/download/`)
+		os.Exit(utils.ExitPermission)
+	}
+
+	utils.PrintInfo("Grant your user write permission to Spotify folder then run spicetify again:")
+	utils.PrintInfo(`sudo chmod a+wr "` + spotifyPath + `"`)
+	utils.PrintInfo(`sudo chmod a+wr -R "` + appPath + `"`)
+	os.Exit(utils.ExitPermission)
+}
+
+// testWritable creates then removes a temporary file in `dir`.
+func testWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(dir, ".spicetify-")
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	return os.Remove(file.Name())
+}
+
+// isElevated reports whether spicetify runs as administrator on Windows.
+func isElevated() bool {
+	return exec.Command("net", "session").Run() == nil
+}
+
+// runElevated runs spicetify with the same arguments as administrator,
+// which shows UAC prompt, waits for it to finish, prints its output and
+// returns its exit code. Elevated process has its own hidden console, so
+// its output goes through a file and its prompts take default answers.
+// Config read from stdin is passed on in a file. Elevated cmd.exe starts
+// in system folder, so it changes to current folder first for relative
+// paths in arguments.
+func runElevated() int {
+	exe, err := os.Executable()
+	if err != nil {
+		utils.Fatal(err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		utils.Fatal(err)
+	}
+
+	temp, err := ioutil.TempDir("", "spicetify-elevated-")
+	if err != nil {
+		utils.Fatal(err)
+	}
+	defer os.RemoveAll(temp)
+
+	args, err := elevatedArgs(os.Args[1:], temp)
+	if err != nil {
+		utils.Fatal(err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		utils.Fatal(err)
+	}
+
+	outputPath := filepath.Join(temp, "output.log")
+	command := elevatedCommand(cwd, exe, args, outputPath)
+	script := "$p = Start-Process -FilePath cmd.exe -ArgumentList " + psQuote(`/S /C "`+command+`"`) +
+		" -Verb RunAs -WindowStyle Hidden -Wait -PassThru; exit $p.ExitCode"
+
+	utils.PrintInfo("Waiting for elevated spicetify to finish...")
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	err = cmd.Run()
+
+	if output, readErr := ioutil.ReadFile(outputPath); readErr == nil {
+		log.Print(string(output))
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode()
+		}
+		utils.Fatal(err)
+	}
+
+	return 0
+}
+
+// elevatedCommand returns cmd.exe command line running `exe` with `args`
+// in folder `cwd`, without input, and writing all output to `outputPath`.
+func elevatedCommand(cwd, exe string, args []string, outputPath string) string {
+	command := "cd /d " + cmdQuote(cwd) + " && " + cmdQuote(exe)
+	for _, arg := range args {
+		command += " " + cmdQuote(arg)
+	}
+	return command + " < NUL > " + cmdQuote(outputPath) + " 2>&1"
+}
+
+// elevatedArgs returns `args` for elevated spicetify. Config read from
+// stdin is written to a file in `temp` and passed by its path instead.
+func elevatedArgs(args []string, temp string) ([]string, error) {
+	result := make([]string, 0, len(args))
+	configFile := ""
+	stdinConfig := func() (string, error) {
+		if len(configFile) == 0 {
+			configFile = filepath.Join(temp, "config-xpui.ini")
+			if err := ioutil.WriteFile(configFile, stdinConfig, 0600); err != nil {
+				return "", err
+			}
+		}
+		return configFile, nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--config=-":
+			path, err := stdinConfig()
+			if err != nil {
+				return nil, err
+			}
+			arg = "--config=" + path
+		case arg == "--config" && i+1 < len(args) && args[i+1] == "-":
+			path, err := stdinConfig()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, arg)
+			arg = path
+			i++
+		}
+		result = append(result, arg)
+	}
+
+	return result, nil
+}
+
+// cmdQuote quotes `s` as an argument in cmd.exe command line.
+func cmdQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// psQuote quotes `s` as a PowerShell literal string.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
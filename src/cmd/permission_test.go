@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestElevatedCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "no arguments",
+			want: `cd /d "C:\Users\me\dir" && "C:\spicetify\spicetify.exe" < NUL > "C:\Temp\out.log" 2>&1`,
+		},
+		{
+			name: "relative paths stay relative to current folder",
+			args: []string{"--config", `work\config.ini`, "backup", "import", "backup.zip"},
+			want: `cd /d "C:\Users\me\dir" && "C:\spicetify\spicetify.exe" "--config" "work\config.ini" "backup" "import" "backup.zip" < NUL > "C:\Temp\out.log" 2>&1`,
+		},
+		{
+			name: "quotes are escaped",
+			args: []string{"config", "current_theme", `a "b"`},
+			want: `cd /d "C:\Users\me\dir" && "C:\spicetify\spicetify.exe" "config" "current_theme" "a \"b\"" < NUL > "C:\Temp\out.log" 2>&1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := elevatedCommand(`C:\Users\me\dir`, `C:\spicetify\spicetify.exe`, tt.args, `C:\Temp\out.log`)
+			if got != tt.want {
+				t.Fatalf("got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElevatedArgs(t *testing.T) {
+	temp := t.TempDir()
+	config := filepath.Join(temp, "config-xpui.ini")
+
+	old := stdinConfig
+	stdinConfig = []byte("[Setting]\n")
+	defer func() { stdinConfig = old }()
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "unchanged",
+			args: []string{"--config", "work.ini", "apply"},
+			want: []string{"--config", "work.ini", "apply"},
+		},
+		{
+			name: "stdin config",
+			args: []string{"--config", "-", "apply"},
+			want: []string{"--config", config, "apply"},
+		},
+		{
+			name: "stdin config with equal sign",
+			args: []string{"--config=-", "apply"},
+			want: []string{"--config=" + config, "apply"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := elevatedArgs(tt.args, temp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if content, err := ioutil.ReadFile(config); err != nil || string(content) != "[Setting]\n" {
+		t.Fatalf("stdin config is not written: %q, %v", content, err)
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/khanhas/spicetify-cli/src/cdp"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// playerActions maps "player" sub commands to Spicetify.Player methods.
+var playerActions = map[string]string{
+	"play":  "play",
+	"pause": "pause",
+	"next":  "next",
+	"prev":  "back",
+}
+
+const playerReadyScript = `typeof Spicetify === "object" && !!Spicetify.Player && !!Spicetify.Player.origin`
+
+const nowPlayingScript = `(() => {
+	const player = Spicetify.Player;
+	const track = player.data && player.data.track;
+	if (!track) return null;
+	const metadata = track.metadata || {};
+	return {
+		uri: track.uri,
+		title: metadata.title || "",
+		artist: metadata.artist_name || "",
+		album: metadata.album_title || "",
+		image_url: metadata.image_url || "",
+		playing: player.isPlaying(),
+		position: Math.round(player.getProgress() || 0),
+		duration: Math.round(player.getDuration() || 0),
+	};
+})()`
+
+type nowPlaying struct {
+	URI      string `json:"uri"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	ImageURL string `json:"image_url"`
+	Playing  bool   `json:"playing"`
+	// Position and Duration are in milliseconds.
+	Position int64 `json:"position"`
+	Duration int64 `json:"duration"`
+}
+
+// Player controls playback of running Spotify client through exposed
+// Spicetify.Player API. Action is "play", "pause", "next", "prev" or
+// "now-playing", which prints current track.
+func Player(action string, asJSON bool) error {
+	method, ok := playerActions[action]
+	if !ok && action != "now-playing" {
+		return utils.WithExitCode(utils.ExitUsage, errors.New(`unknown player command "`+action+`"`))
+	}
+
+	client, err := dialSpotify()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ready, err := client.Evaluate(playerReadyScript)
+	if err != nil {
+		return err
+	}
+	if string(ready) != "true" {
+		return errors.New(`Spicetify.Player is not available. Set "expose_apis" to 1 then run "spicetify backup apply"`)
+	}
+
+	if ok {
+		_, err = client.Evaluate("Spicetify.Player." + method + "()")
+		return err
+	}
+
+	return printNowPlaying(client, asJSON)
+}
+
+func printNowPlaying(client *cdp.Client, asJSON bool) error {
+	result, err := client.Evaluate(nowPlayingScript)
+	if err != nil {
+		return err
+	}
+
+	var track *nowPlaying
+	if result != nil {
+		if err = json.Unmarshal(result, &track); err != nil {
+			return err
+		}
+	}
+
+	if asJSON {
+		// One line, so status bars can read each update as a line.
+		content, err := json.Marshal(track)
+		if err != nil {
+			return err
+		}
+		log.Println(string(content))
+		return nil
+	}
+
+	if track == nil {
+		log.Println("Nothing is playing")
+		return nil
+	}
+
+	state := "paused"
+	if track.Playing {
+		state = "playing"
+	}
+	log.Println(fmt.Sprintf("%s - %s [%s %s/%s]",
+		track.Artist, track.Title, state,
+		formatTrackTime(track.Position), formatTrackTime(track.Duration)))
+	return nil
+}
+
+// formatTrackTime formats `ms` milliseconds as "m:ss".
+func formatTrackTime(ms int64) string {
+	seconds := ms / 1000
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
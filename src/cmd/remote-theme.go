@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// remoteThemesFolder stores downloaded themes, one folder per source URL.
+func remoteThemesFolder() string {
+	return filepath.Join(cacheFolder, "RemoteThemes")
+}
+
+// isRemoteTheme reports whether theme name is an URL to download theme from.
+func isRemoteTheme(name string) bool {
+	return strings.HasPrefix(name, "https://") || strings.HasPrefix(name, "http://")
+}
+
+// currentThemeName returns "theme_source" when it is set, else "current_theme".
+func currentThemeName() string {
+	if source := settingSection.Key("theme_source").String(); len(source) > 0 {
+		return source
+	}
+
+	return settingSection.Key("current_theme").String()
+}
+
+var (
+	githubRepoRe = regexp.MustCompile(`^/([^/]+)/([^/]+?)(?:\.git)?(?:/tree/([^/]+)(/.*)?)?/?$`)
+	githubBlobRe = regexp.MustCompile(`^/([^/]+)/([^/]+)/blob/(.+)$`)
+	gistRe       = regexp.MustCompile(`^/(?:[^/]+/)?[0-9a-fA-F]+/?$`)
+)
+
+// isRemoteCSS reports whether theme source is a single CSS file.
+func isRemoteCSS(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".css")
+}
+
+// resolveRemoteTheme returns URL to download and path of theme inside
+// downloaded archive. Supported are Github repository URLs, optionally
+// pointing to a branch and sub folder with "/tree/<branch>/<path>", Github
+// gists, direct links to zip archives and to single CSS files, including
+// Github "/blob/" links.
+func resolveRemoteTheme(source string) (archiveURL, subPath string, err error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	lowerPath := strings.ToLower(u.Path)
+	if strings.HasSuffix(lowerPath, ".css") {
+		if match := githubBlobRe.FindStringSubmatch(u.Path); u.Host == "github.com" && match != nil {
+			return "https://raw.githubusercontent.com/" + match[1] + "/" + match[2] + "/" + match[3], "", nil
+		}
+		return source, "", nil
+	}
+
+	if strings.HasSuffix(lowerPath, ".zip") {
+		return source, "", nil
+	}
+
+	if u.Host == "gist.github.com" {
+		if !gistRe.MatchString(u.Path) {
+			return "", "", errors.New("invalid Github gist URL")
+		}
+		return "https://gist.github.com" + strings.TrimSuffix(u.Path, "/") + "/download", "", nil
+	}
+
+	if u.Host != "github.com" {
+		return "", "", errors.New("unsupported theme source, use a Github repository, gist, zip archive or CSS file URL")
+	}
+
+	match := githubRepoRe.FindStringSubmatch(u.Path)
+	if match == nil {
+		return "", "", errors.New("invalid Github repository URL")
+	}
+
+	ref := match[3]
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+
+	archiveURL = "https://codeload.github.com/" + match[1] + "/" + match[2] + "/zip/" + ref
+	return archiveURL, strings.Trim(match[4], "/"), nil
+}
+
+// remoteThemeCacheName converts theme source URL to a folder name.
+func remoteThemeCacheName(source string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(source, "https://"), "http://")
+	return regexp.MustCompile(`[^A-Za-z0-9._-]+`).ReplaceAllString(strings.Trim(name, "/"), "_")
+}
+
+// cachedRemoteTheme returns where theme from `source` is cached and
+// whether it is already downloaded there.
+func cachedRemoteTheme(source string) (string, bool) {
+	dest := filepath.Join(remoteThemesFolder(), remoteThemeCacheName(source))
+	if isRemoteCSS(source) && !strings.HasSuffix(dest, ".css") {
+		dest += ".css"
+	}
+	return dest, isThemeFolder(dest)
+}
+
+// remoteThemeFolder returns folder of theme downloaded from `source`,
+// or its CSS file for single-file theme, downloading it first when it is
+// not cached yet.
+func remoteThemeFolder(source string) (string, error) {
+	dest, cached := cachedRemoteTheme(source)
+	if cached {
+		return dest, nil
+	}
+
+	archiveURL, subPath, err := resolveRemoteTheme(source)
+	if err != nil {
+		return "", err
+	}
+
+	utils.PrintBold("Downloading theme " + source + ":")
+	res, err := utils.Download(archiveURL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.New("cannot download theme: " + res.Status)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if isRemoteCSS(source) {
+		if bytes.Contains(bytes.ToLower(data[:utils.MinInt(len(data), 512)]), []byte("<html")) {
+			return "", errors.New("downloaded file is a web page, not CSS. Use link to raw file")
+		}
+
+		utils.CheckExistAndCreate(remoteThemesFolder())
+		if err = ioutil.WriteFile(dest, data, 0600); err != nil {
+			return "", err
+		}
+		utils.PrintGreen("OK")
+		return dest, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", errors.New("downloaded theme is not a valid zip archive")
+	}
+
+	temp, err := ioutil.TempDir("", "spicetify-theme-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(temp)
+
+	if err = utils.UnzipReader(reader, temp); err != nil {
+		return "", err
+	}
+
+	root := archiveRoot(temp)
+	folder := filepath.Join(root, filepath.FromSlash(subPath))
+	if len(subPath) == 0 && !isThemeFolder(folder) {
+		folder = onlyThemeSubfolder(folder)
+	}
+	// A gist or archive with only one CSS file is a single-file theme.
+	css := onlyCSSFile(folder)
+	if !isThemeFolder(folder) && len(css) == 0 {
+		return "", errors.New("no color.ini, user.css or assets found in downloaded theme")
+	}
+
+	os.RemoveAll(dest)
+	utils.CheckExistAndCreate(remoteThemesFolder())
+	if isThemeFolder(folder) {
+		err = utils.Copy(folder, dest, true, nil)
+	} else {
+		err = utils.CopyFile(css, dest)
+		if err == nil {
+			err = os.Rename(filepath.Join(dest, filepath.Base(css)), filepath.Join(dest, "user.css"))
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dest)
+		return "", err
+	}
+	utils.PrintGreen("OK")
+
+	return dest, nil
+}
+
+// onlyCSSFile returns path of the only CSS file in `dir`, if there is
+// exactly one.
+func onlyCSSFile(dir string) string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.css"))
+	if err != nil || len(matches) != 1 {
+		return ""
+	}
+
+	return matches[0]
+}
+
+// archiveRoot returns the only folder in `dir` when archive files are
+// wrapped in one, like Github archives are.
+func archiveRoot(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 || !files[0].IsDir() {
+		return dir
+	}
+
+	return filepath.Join(dir, files[0].Name())
+}
+
+// onlyThemeSubfolder returns the theme folder in `dir` when there is
+// exactly one, else `dir` itself.
+func onlyThemeSubfolder(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return dir
+	}
+
+	found := ""
+	for _, file := range files {
+		folder := filepath.Join(dir, file.Name())
+		if !file.IsDir() || !isThemeFolder(folder) {
+			continue
+		}
+		if len(found) > 0 {
+			return dir
+		}
+		found = folder
+	}
+
+	if len(found) == 0 {
+		return dir
+	}
+	return found
+}
+
+// RefreshRemoteThemes removes downloaded themes so they are fetched again
+// on next use.
+func RefreshRemoteThemes() {
+	os.RemoveAll(remoteThemesFolder())
+}
@@ -1,22 +1,198 @@
 package cmd
 
 import (
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/cdp"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+const (
+	flatpakID    = "com.spotify.Client"
+	quitTimeout  = 10 * time.Second
+	kioskTimeout = 60 * time.Second
 )
 
-// RestartSpotify .
+// RestartSpotify gracefully quits running Spotify client then relaunches it.
+// When "kiosk_mode" is on, it is relaunched in kiosk mode.
 func RestartSpotify(flags ...string) {
+	if settingSection.Key("kiosk_mode").MustBool(false) {
+		RestartKiosk(flags...)
+		return
+	}
+
+	restart(flags...)
+}
+
+func restart(flags ...string) {
 	launchFlag := settingSection.Key("spotify_launch_flags").Strings("|")
 	if len(launchFlag) > 0 {
 		flags = append(flags, launchFlag...)
 	}
 
+	quitSpotify()
+	applyUIScalePref()
+	launchSpotify(flags...)
+}
+
+// RestartKiosk relaunches Spotify with debugger on, then injects kiosk
+// payload, which makes client fullscreen and hides idle cursor.
+func RestartKiosk(flags ...string) {
+	script, err := ioutil.ReadFile(filepath.Join(utils.GetJsHelperDir(), "kiosk.js"))
+	if err != nil {
+		utils.Fatal(err)
+	}
+
+	restart(append(flags, utils.DebuggerFlags()...)...)
+	utils.PrintInfo("Waiting for Spotify to start in kiosk mode...")
+
+	// Wait until xpui is rendered before injecting.
+	client, ok := waitForXpui(kioskTimeout)
+	if !ok {
+		utils.PrintError("Spotify does not respond. Kiosk payload is not injected.")
+		return
+	}
+	defer client.Close()
+
+	if _, err := client.Evaluate(string(script)); err != nil {
+		utils.PrintErrorOf("Cannot inject kiosk payload: ", err)
+		return
+	}
+	utils.PrintSuccess("Spotify is in kiosk mode")
+}
+
+// waitForXpui waits until Spotify started with debugger on renders xpui,
+// up to `timeout`. It returns client connected to Spotify page, which
+// caller has to close, and whether xpui is rendered.
+func waitForXpui(timeout time.Duration) (*cdp.Client, bool) {
+	var client *cdp.Client
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if client == nil && cdp.Reachable() {
+			client, _ = cdp.Dial()
+		}
+
+		if client != nil {
+			ready, err := client.Evaluate(`document.readyState === "complete" && !!document.querySelector(".Root__top-container")`)
+			if err == nil && string(ready) == "true" {
+				return client, true
+			}
+			if err == cdp.ErrDisconnected {
+				// Page is replaced while Spotify starts up.
+				client.Close()
+				client = nil
+			}
+		}
+
+		time.Sleep(utils.INTERVAL)
+	}
+
+	if client != nil {
+		client.Close()
+	}
+	return nil, false
+}
+
+// closedForWrite is set when Spotify is closed to unlock its files.
+var closedForWrite = false
+
+// closeForWrite offers to close running Spotify client before its files
+// are written. On Windows, running client locks files it loaded and
+// writing to them fails midway.
+func closeForWrite() {
+	if runtime.GOOS != "windows" || closedForWrite || !isSpotifyRunning() {
+		return
+	}
+
+	if !ReadAnswer("Spotify is running and its files may be locked. Close it now? [Y/n] ", true, true) {
+		utils.PrintWarning("Spotify is still running. Writing locked files is retried but could fail.")
+		return
+	}
+
+	quitSpotify()
+	closedForWrite = true
+}
+
+// RelaunchIfClosed launches Spotify again when it was closed to unlock
+// its files and is not started since.
+func RelaunchIfClosed() {
+	if !closedForWrite || isSpotifyRunning() {
+		return
+	}
+
+	launchFlag := settingSection.Key("spotify_launch_flags").Strings("|")
+	launchSpotify(launchFlag...)
+}
+
+// quitSpotify asks Spotify client to close itself and waits for it to exit.
+// Client is killed if it is still running after quitTimeout.
+func quitSpotify() {
+	switch runtime.GOOS {
+	case "windows":
+		exec.Command("taskkill", "/IM", "spotify.exe").Run()
+	case "linux":
+		if isFlatpak() {
+			exec.Command("flatpak", "kill", flatpakID).Run()
+		} else {
+			exec.Command("pkill", "-TERM", "-x", spotifyProcessName()).Run()
+		}
+	case "darwin":
+		exec.Command("osascript", "-e", `quit app "`+strings.TrimSuffix(filepath.Base(darwinAppBundle()), ".app")+`"`).Run()
+	}
+
+	deadline := time.Now().Add(quitTimeout)
+	for isSpotifyRunning() {
+		if time.Now().After(deadline) {
+			utils.PrintWarning("Spotify does not respond to quit request. Killing it.")
+			killSpotify()
+			break
+		}
+		time.Sleep(utils.INTERVAL)
+	}
+}
+
+// killSpotify force kills Spotify client processes, matched by exact name.
+func killSpotify() {
 	switch runtime.GOOS {
 	case "windows":
 		exec.Command("taskkill", "/F", "/IM", "spotify.exe").Run()
+	case "linux", "darwin":
+		exec.Command("pkill", "-KILL", "-x", spotifyProcessName()).Run()
+	}
+}
+
+// isSpotifyRunning reports whether any Spotify client process is running.
+func isSpotifyRunning() bool {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("tasklist", "/FI", "IMAGENAME eq spotify.exe", "/NH").Output()
+		return err == nil && strings.Contains(strings.ToLower(string(out)), "spotify.exe")
+	case "linux", "darwin":
+		return exec.Command("pgrep", "-x", spotifyProcessName()).Run() == nil
+	}
+
+	return false
+}
+
+// spotifyProcessName returns exact process name of Spotify client on Linux
+// and macOS. Names are matched exactly, so other programs with "spotify"
+// in their names, like spotifyd or spotify-tui, are left alone.
+func spotifyProcessName() string {
+	if runtime.GOOS == "darwin" {
+		return "Spotify"
+	}
+	return "spotify"
+}
+
+func launchSpotify(flags ...string) {
+	switch runtime.GOOS {
+	case "windows":
 		if isAppX {
 			ps, _ := exec.LookPath("powershell.exe")
 			exe := filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "WindowsApps", "Spotify.exe")
@@ -25,12 +201,66 @@ func RestartSpotify(flags ...string) {
 		} else {
 			exec.Command(filepath.Join(spotifyPath, "spotify.exe"), flags...).Start()
 		}
+		startWindowEffect()
 	case "linux":
-		exec.Command("pkill", "spotify").Run()
-		exec.Command(filepath.Join(spotifyPath, "spotify"), flags...).Start()
+		if isFlatpak() {
+			flags = append([]string{"run", flatpakID}, flags...)
+			exec.Command("flatpak", flags...).Start()
+		} else if isSnap() {
+			flags = append([]string{"run", "spotify"}, flags...)
+			exec.Command("snap", flags...).Start()
+		} else {
+			exec.Command(filepath.Join(spotifyPath, "spotify"), flags...).Start()
+		}
 	case "darwin":
-		exec.Command("pkill", "Spotify").Run()
-		flags = append([]string{"-a", "/Applications/Spotify.app"}, flags...)
+		flags = append([]string{"-a", darwinAppBundle(), "--args"}, flags...)
 		exec.Command("open", flags...).Start()
 	}
 }
+
+func isFlatpak() bool {
+	return strings.Contains(spotifyPath, "flatpak")
+}
+
+func isSnap() bool {
+	return strings.HasPrefix(spotifyPath, "/snap/")
+}
+
+// darwinAppBundle returns location of Spotify app bundle, which contains
+// spotifyPath "<bundle>/Contents/Resources".
+func darwinAppBundle() string {
+	if strings.HasSuffix(filepath.Dir(spotifyPath), "Contents") {
+		return filepath.Dir(filepath.Dir(spotifyPath))
+	}
+	return "/Applications/Spotify.app"
+}
+
+// installKindNames are human readable names of install kinds.
+var installKindNames = map[string]string{
+	"appx":      "Microsoft Store",
+	"flatpak":   "Flatpak",
+	"snap":      "Snap",
+	"installer": "standard",
+}
+
+// installKind returns short identifier of how Spotify client is installed:
+// "appx", "flatpak", "snap" or "installer".
+func installKind() string {
+	if isAppX {
+		return "appx"
+	} else if isFlatpak() {
+		return "flatpak"
+	} else if isSnap() {
+		return "snap"
+	}
+	return "installer"
+}
+
+// describeInstall returns human readable name of install `kind`.
+func describeInstall(kind string, preview bool) string {
+	name := installKindNames[kind]
+	if preview {
+		name += ", preview build"
+	}
+	return name
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Rotate switches current theme or color scheme to the next entry of
+// "rotate_list" when rotation period set in "rotate_schemes" is due.
+// Colors are swapped live when Spotify debugger is reachable, otherwise
+// they take effect on next Spotify launch.
+func Rotate() {
+	mode := settingSection.Key("rotate_schemes").String()
+	if len(mode) == 0 {
+		utils.PrintInfo(`Rotation is disabled. Set "rotate_schemes" to "daily", "startup" or a duration like "1h".`)
+		return
+	}
+
+	last := readLastRotation()
+	if !isRotationDue(mode, last) {
+		utils.PrintInfo("Rotation is not due yet. Last rotation: " + last.Format(time.RFC1123))
+		return
+	}
+
+	list := rotationList()
+	if len(list) < 2 {
+		utils.PrintError(`Nothing to rotate. Add at least 2 entries to "rotate_list".`)
+		return
+	}
+
+	currentTheme := currentThemeName()
+	currentScheme := settingSection.Key("color_scheme").String()
+
+	next := list[0]
+	for index, entry := range list {
+		theme, scheme := parseRotationEntry(entry, currentTheme)
+		if theme == currentTheme && strings.EqualFold(scheme, currentScheme) {
+			next = list[(index+1)%len(list)]
+			break
+		}
+	}
+
+	nextTheme, nextScheme := parseRotationEntry(next, currentTheme)
+	if nextTheme != currentTheme {
+		// Theme in "theme_source" would still be used over the new one.
+		settingSection.Key("current_theme").SetValue(nextTheme)
+		settingSection.Key("theme_source").SetValue("")
+	}
+	settingSection.Key("color_scheme").SetValue(nextScheme)
+	cfg.Write()
+	writeLastRotation(time.Now())
+
+	utils.PrintSuccess(`Rotated to theme "` + nextTheme + `", scheme "` + nextScheme + `"`)
+
+	UpdateTheme()
+
+	client, err := dialSpotify()
+	if err == errNoDebugger {
+		utils.PrintInfo("Spotify debugger is not reachable, changes take effect on next Spotify launch.")
+		return
+	}
+
+	if err == nil {
+		defer client.Close()
+		if nextTheme == currentTheme && colorSection != nil {
+			if _, err := client.Evaluate(colorSwapScript(colorSection.KeysHash())); err == nil {
+				utils.PrintSuccess("Colors are swapped live")
+				return
+			}
+		} else if client.Reload() == nil {
+			utils.PrintSuccess("Spotify reloaded")
+			return
+		}
+	}
+
+	utils.PrintWarning("Could not reach Spotify debugger, changes take effect on next Spotify launch.")
+}
+
+// rotationList returns entries of "rotate_list". When the list is blank,
+// every scheme of current theme is used.
+func rotationList() []string {
+	list := settingSection.Key("rotate_list").Strings("|")
+	if len(list) > 0 {
+		return list
+	}
+
+	themeName := currentThemeName()
+	if len(themeName) == 0 {
+		return list
+	}
+
+	colors, err := ini.InsensitiveLoad(filepath.Join(getThemeFolder(themeName), "color.ini"))
+	if err != nil {
+		return list
+	}
+
+	for _, section := range colors.Sections()[1:] {
+		list = append(list, section.Name())
+	}
+
+	return list
+}
+
+// parseRotationEntry splits a rotation entry in "theme:scheme", "theme:" or
+// "scheme" format. Plain scheme entries belong to currentTheme. Theme can
+// be an URL, so entry is split on the last ":" not followed by a "/".
+func parseRotationEntry(entry, currentTheme string) (string, string) {
+	if index := strings.LastIndex(entry, ":"); index > -1 && !strings.Contains(entry[index+1:], "/") {
+		return entry[:index], entry[index+1:]
+	}
+
+	if isRemoteTheme(entry) {
+		return entry, ""
+	}
+
+	return currentTheme, entry
+}
+
+func isRotationDue(mode string, last time.Time) bool {
+	now := time.Now()
+
+	switch mode {
+	case "startup":
+		return true
+	case "daily":
+		return last.Year() != now.Year() || last.YearDay() != now.YearDay()
+	}
+
+	period, err := time.ParseDuration(mode)
+	if err != nil {
+		utils.PrintError(`"` + mode + `" is not a valid value for "rotate_schemes". Use "daily", "startup" or a duration like "1h".`)
+		os.Exit(utils.ExitUsage)
+	}
+
+	return now.Sub(last) >= period
+}
+
+func colorSwapScript(scheme map[string]string) string {
+	script := ""
+	for k, v := range scheme {
+		color := utils.ParseColor(v)
+		script += fmt.Sprintf(
+			`document.documentElement.style.setProperty("--spice-%s","#%s");`+
+				`document.documentElement.style.setProperty("--spice-rgb-%s","%s");`,
+			k, color.Hex(), k, color.RGB())
+	}
+
+	return script
+}
+
+func rotationStatePath() string {
+	return filepath.Join(spicetifyFolder, "rotate-state")
+}
+
+func readLastRotation() time.Time {
+	content, err := os.ReadFile(rotationStatePath())
+	if err != nil {
+		return time.Time{}
+	}
+
+	unix, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(unix, 0)
+}
+
+func writeLastRotation(t time.Time) {
+	content := []byte(strconv.FormatInt(t.Unix(), 10))
+	if err := os.WriteFile(rotationStatePath(), content, 0700); err != nil {
+		utils.PrintWarningOf("Cannot save rotation state: ", err)
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import "testing"
+
+func TestParseRotationEntry(t *testing.T) {
+	tests := []struct {
+		entry      string
+		wantTheme  string
+		wantScheme string
+	}{
+		{"dark", "Current", "dark"},
+		{"Sleek:nord", "Sleek", "nord"},
+		{"Sleek:", "Sleek", ""},
+		{"https://github.com/user/theme:dark", "https://github.com/user/theme", "dark"},
+		{"https://github.com/user/theme:", "https://github.com/user/theme", ""},
+		{"https://github.com/user/theme", "https://github.com/user/theme", ""},
+		{"http://localhost:8080/theme.zip:dark", "http://localhost:8080/theme.zip", "dark"},
+		{"http://localhost:8080/theme.zip", "http://localhost:8080/theme.zip", ""},
+	}
+
+	for _, tt := range tests {
+		theme, scheme := parseRotationEntry(tt.entry, "Current")
+		if theme != tt.wantTheme || scheme != tt.wantScheme {
+			t.Errorf("parseRotationEntry(%q) = %q, %q, want %q, %q", tt.entry, theme, scheme, tt.wantTheme, tt.wantScheme)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// candidateSelection is 1-based index of candidate to pick when multiple
+// Spotify installs or "prefs" files are found. 0 means asking user.
+var candidateSelection = 0
+
+// stdinReader is shared between prompts so input buffered by one prompt
+// is not lost for the next one.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// SetCandidateSelection makes path detection pick `n`th candidate without
+// asking, when there are multiple ones.
+func SetCandidateSelection(n int) {
+	candidateSelection = n
+}
+
+// selectCandidate returns the only candidate, or lets user pick one of
+// them. Returns blank string if there is none.
+func selectCandidate(kind string, candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	} else if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	if candidateSelection > 0 {
+		if candidateSelection > len(candidates) {
+			utils.PrintError(fmt.Sprintf(`"--select %d" is out of range: found %d %s.`, candidateSelection, len(candidates), kind))
+			os.Exit(utils.ExitUsage)
+		}
+		return candidates[candidateSelection-1]
+	}
+
+	utils.PrintInfo("Found multiple " + kind + ":")
+	for i, candidate := range candidates {
+		log.Println(fmt.Sprintf("    %d. %s", i+1, candidate))
+	}
+
+	if quiet {
+		utils.PrintWarning(`Using the first one. Use "--select <n>" to pick another.`)
+		return candidates[0]
+	}
+
+	return candidates[readChoice(len(candidates), 0)]
+}
+
+// readChoice asks user to pick one of `count` numbered options and returns
+// its 0-based index. Empty input or closed stdin picks `defaultIndex`.
+func readChoice(count, defaultIndex int) int {
+	for {
+		printPrompt(fmt.Sprintf("Select one [1-%d] (default %d): ", count, defaultIndex+1))
+		text, err := stdinReader.ReadString('\n')
+		text = strings.TrimSpace(text)
+		if len(text) == 0 {
+			return defaultIndex
+		}
+
+		if n, convErr := strconv.Atoi(text); convErr == nil && n >= 1 && n <= count {
+			return n - 1
+		}
+
+		// No more input, e.g. stdin is closed.
+		if err != nil {
+			return defaultIndex
+		}
+	}
+}
+
+// readLine asks user for a line of text. Empty input or closed stdin
+// returns `defaultValue`.
+func readLine(info, defaultValue string) string {
+	printPrompt(info)
+	text, _ := stdinReader.ReadString('\n')
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return defaultValue
+	}
+	return text
+}
+
+// IsInteractive reports whether stdin is a terminal, so someone can answer
+// prompts. Closed or piped stdin would take default answers unseen.
+func IsInteractive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printPrompt prints prompt `info`. In JSON log, stdout only has events,
+// so prompts go to stderr.
+func printPrompt(info string) {
+	if utils.IsJSONLog() {
+		fmt.Fprint(os.Stderr, info)
+		return
+	}
+	fmt.Print(info)
+}
+
+// prefsCandidates narrows "prefs" file candidates down to the one belonging
+// to Spotify install at spotifyPath, when it can be told.
+func prefsCandidates(candidates []string) []string {
+	var matches []string
+	for _, prefs := range candidates {
+		if strings.HasPrefix(prefs, spotifyPath) ||
+			(isFlatpak() && strings.Contains(prefs, flatpakID)) ||
+			(isSnap() && strings.Contains(prefs, "snap/spotify")) {
+			matches = append(matches, prefs)
+		}
+	}
+
+	if len(matches) == 1 {
+		return matches
+	}
+	return candidates
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/apply"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// SnippetList prints JS snippets in "JsSnippets" config section and
+// whether they are enabled.
+func SnippetList() {
+	keys := snippetSection.Keys()
+	if len(keys) == 0 {
+		utils.PrintInfo(`No JS snippet. Add one with "spicetify snippet add <name> <code>".`)
+		return
+	}
+
+	enabled := map[string]bool{}
+	for _, name := range featureSection.Key("js_snippets").Strings("|") {
+		enabled[name] = true
+	}
+
+	for _, key := range keys {
+		mark := "  "
+		if enabled[key.Name()] {
+			mark = utils.Green("* ")
+		}
+
+		code := strings.SplitN(key.String(), "\n", 2)[0]
+		if len(code) > 60 {
+			code = code[:57] + "..."
+		}
+		log.Println(mark + utils.Bold(key.Name()) + "    " + code)
+	}
+}
+
+// SnippetAdd stores JS snippet `name` and enables it. `code` can be a path
+// to a Javascript file, whose content is used.
+func SnippetAdd(name, code string) {
+	if strings.ContainsAny(name, "|= ") {
+		utils.PrintError(`Snippet name cannot contain "|", "=" or spaces.`)
+		os.Exit(utils.ExitUsage)
+	}
+
+	if strings.HasSuffix(code, ".js") {
+		if content, err := ioutil.ReadFile(code); err == nil {
+			code = string(content)
+		}
+	}
+
+	snippetSection.Key(name).SetValue(code)
+	utils.PrintSuccess(`JS snippet "` + name + `" is saved`)
+
+	key := featureSection.Key("js_snippets")
+	if !listContains(key, name) {
+		addToList(key, name)
+	} else {
+		utils.PrintInfo(`Run "spicetify apply" to apply new config`)
+	}
+	cfg.Write()
+}
+
+// SnippetRemove deletes JS snippet `name`.
+func SnippetRemove(name string) {
+	if !snippetSection.HasKey(name) {
+		utils.PrintError(`JS snippet "` + name + `" not found.`)
+		os.Exit(utils.ExitUsage)
+	}
+
+	snippetSection.DeleteKey(name)
+	key := featureSection.Key("js_snippets")
+	if listContains(key, name) {
+		removeFromList(key, name)
+	}
+	cfg.Write()
+	utils.PrintSuccess(`JS snippet "` + name + `" is removed`)
+}
+
+// SnippetToggle enables or disables JS snippets in `names`.
+func SnippetToggle(names []string, enable bool) {
+	key := featureSection.Key("js_snippets")
+	for _, name := range names {
+		if enable {
+			if !snippetSection.HasKey(name) {
+				utils.PrintError(`JS snippet "` + name + `" not found.`)
+				continue
+			}
+			addToList(key, name)
+		} else {
+			removeFromList(key, name)
+		}
+	}
+	cfg.Write()
+}
+
+// enabledJsSnippets returns enabled JS snippets, in "js_snippets" order.
+func enabledJsSnippets() []apply.JsSnippet {
+	var snippets []apply.JsSnippet
+	for _, name := range featureSection.Key("js_snippets").Strings("|") {
+		if !snippetSection.HasKey(name) {
+			utils.PrintWarning(`JS snippet "` + name + `" not found. Skipped.`)
+			continue
+		}
+
+		snippets = append(snippets, apply.JsSnippet{
+			Name: name,
+			Code: snippetSection.Key(name).String(),
+		})
+	}
+
+	return snippets
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// spotifyCacheFolder returns folder Spotify keeps its streaming and
+// browser caches in.
+func spotifyCacheFolder() string {
+	switch runtime.GOOS {
+	case "windows":
+		if isAppX {
+			// prefs is in "<package>/LocalState/Spotify", cache in "<package>/LocalCache/Spotify".
+			return filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(prefsPath))), "LocalCache", "Spotify")
+		}
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "Spotify")
+	case "linux":
+		if isFlatpak() {
+			return filepath.Join(os.Getenv("HOME"), ".var", "app", flatpakID, "cache", "spotify")
+		}
+		parent := os.Getenv("XDG_CACHE_HOME")
+		if len(parent) == 0 {
+			parent = filepath.Join(os.Getenv("HOME"), ".cache")
+		}
+		return filepath.Join(parent, "spotify")
+	case "darwin":
+		return filepath.Join(os.Getenv("HOME"), "Library", "Caches", "com.spotify.client")
+	}
+
+	return ""
+}
+
+// spotifyCacheSubfolders are regenerable caches. Other files in cache
+// folder, like offline.bnk, browser localStorage and downloaded songs
+// storage, are kept.
+var spotifyCacheSubfolders = []string{
+	"Data",
+	filepath.Join("Browser", "Cache"),
+	filepath.Join("Browser", "Code Cache"),
+	filepath.Join("Browser", "GPUCache"),
+}
+
+func loadSpotifyPrefs() (*ini.File, error) {
+	if len(prefsPath) == 0 {
+		return nil, errors.New(`"prefs_path" is not set`)
+	}
+
+	return ini.LoadSources(ini.LoadOptions{PreserveSurroundedQuote: true}, prefsPath)
+}
+
+// spotifyStorageLocation returns location of downloaded songs set in
+// Spotify prefs, or blank string when default one is used.
+func spotifyStorageLocation() string {
+	pref, err := loadSpotifyPrefs()
+	if err != nil {
+		return ""
+	}
+
+	return strings.Trim(pref.Section("").Key("storage.location").String(), `"`)
+}
+
+// SpotifyDataSize prints size of Spotify cache and storage folders.
+func SpotifyDataSize() {
+	cacheFolder := spotifyCacheFolder()
+	var total int64
+	for _, name := range spotifyCacheSubfolders {
+		total += utils.DirSize(filepath.Join(cacheFolder, name))
+	}
+
+	log.Println("Cache:    " + utils.FormatSize(total) + " (" + cacheFolder + ")")
+	log.Println("Total:    " + utils.FormatSize(utils.DirSize(cacheFolder)))
+
+	if location := spotifyStorageLocation(); len(location) > 0 {
+		log.Println("Storage:  " + utils.FormatSize(utils.DirSize(location)) + " (" + location + ")")
+	}
+}
+
+// SpotifyDataClearCache removes Spotify streaming and browser caches.
+func SpotifyDataClearCache() {
+	if isSpotifyRunning() {
+		utils.PrintInfo("Closing Spotify to clear its cache...")
+		quitSpotify()
+	}
+
+	cacheFolder := spotifyCacheFolder()
+	var reclaimed int64
+	for _, name := range spotifyCacheSubfolders {
+		folder := filepath.Join(cacheFolder, name)
+		size := utils.DirSize(folder)
+		if err := os.RemoveAll(folder); err != nil {
+			utils.PrintErrorOf("", err)
+			continue
+		}
+		reclaimed += size
+	}
+
+	utils.PrintSuccess("Spotify cache is cleared. Reclaimed " + utils.FormatSize(reclaimed) + ".")
+}
+
+// SpotifyDataSetLocation sets folder Spotify stores downloaded songs in.
+func SpotifyDataSetLocation(location string) error {
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return err
+	}
+
+	utils.CheckExistAndCreate(location)
+	if _, err := os.Stat(location); err != nil {
+		return err
+	}
+
+	// Spotify writes prefs on exit, which would revert our change.
+	if isSpotifyRunning() {
+		utils.PrintInfo("Closing Spotify to change its storage location...")
+		quitSpotify()
+	}
+
+	pref, err := loadSpotifyPrefs()
+	if err != nil {
+		return err
+	}
+
+	pref.Section("").Key("storage.location").SetValue(`"` + location + `"`)
+	ini.PrettyFormat = false
+	if err = pref.SaveTo(prefsPath); err != nil {
+		return err
+	}
+
+	utils.PrintSuccess("Spotify storage location is set to " + location)
+	utils.PrintInfo("Songs downloaded before are not moved. Download them again or move them manually.")
+	return nil
+}
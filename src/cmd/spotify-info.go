@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+
+	"github.com/khanhas/spicetify-cli/src/backup"
+	"github.com/khanhas/spicetify-cli/src/cdp"
+	spotifystatus "github.com/khanhas/spicetify-cli/src/status/spotify"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+type spotifyInfo struct {
+	Version           string `json:"version"`
+	InstallType       string `json:"install_type"`
+	Preview           bool   `json:"preview"`
+	State             string `json:"state"`
+	SpotifyPath       string `json:"spotify_path"`
+	PrefsPath         string `json:"prefs_path"`
+	XpuiHash          string `json:"xpui_hash"`
+	DebuggerAddress   string `json:"debugger_address"`
+	DebuggerReachable bool   `json:"debugger_reachable"`
+}
+
+// SpotifyInfo prints detected Spotify client information, as text or as
+// JSON when `asJSON` is true.
+func SpotifyInfo(asJSON bool) {
+	info := getSpotifyInfo()
+
+	if asJSON {
+		content, err := json.MarshalIndent(info, "", "    ")
+		if err != nil {
+			utils.Fatal(err)
+		}
+		log.Println(string(content))
+		return
+	}
+
+	log.Print(info.String())
+}
+
+func getSpotifyInfo() spotifyInfo {
+	info := spotifyInfo{
+		Version:         utils.GetSpotifyVersion(prefsPath),
+		InstallType:     installKind(),
+		Preview:         utils.IsSpotifyPreview(spotifyPath),
+		State:           spotifyState(),
+		SpotifyPath:     spotifyPath,
+		PrefsPath:       prefsPath,
+		XpuiHash:        xpuiHash(),
+		DebuggerAddress: utils.DebuggerAddress(),
+	}
+	info.DebuggerReachable = cdp.Reachable()
+	return info
+}
+
+// String formats info as human readable lines.
+func (info spotifyInfo) String() string {
+	debugger := "not reachable"
+	if info.DebuggerReachable {
+		debugger = "reachable"
+	}
+	hash := info.XpuiHash
+	if len(hash) == 0 {
+		hash = "unknown"
+	}
+
+	return "Version:        " + info.Version + "\n" +
+		"Install type:   " + describeInstall(info.InstallType, info.Preview) + "\n" +
+		"State:          " + info.State + "\n" +
+		"Install path:   " + info.SpotifyPath + "\n" +
+		"Prefs path:     " + info.PrefsPath + "\n" +
+		"xpui hash:      " + hash + "\n" +
+		"Remote debug:   " + info.DebuggerAddress + " (" + debugger + ")\n"
+}
+
+func spotifyState() string {
+	status := spotifystatus.Get(appPath)
+	if status.IsStock() {
+		return "stock"
+	} else if status.IsMixed() {
+		return "mixed"
+	} else if status.IsApplied() {
+		return "applied"
+	}
+	return "invalid"
+}
+
+// xpuiHash returns hash of installed xpui.spa. When Spotify is applied,
+// it's hash of original xpui.spa in backup.
+func xpuiHash() string {
+	if hash, err := utils.HashFile(filepath.Join(appPath, "xpui.spa")); err == nil {
+		return hash
+	}
+
+	version := backupSection.Key("version").String()
+	if hash, err := backup.FileHash(backupFolder, version, "xpui.spa"); err == nil {
+		return hash
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// storageDump is content of a localStorage export file.
+type storageDump struct {
+	Exported time.Time         `json:"exported"`
+	Entries  map[string]string `json:"entries"`
+}
+
+const storageExportScript = `Object.fromEntries(Object.keys(localStorage).map(k => [k, localStorage.getItem(k)]))`
+
+// addonStorageKeys returns entries whose keys belong to spicetify, current
+// theme, enabled extensions or custom apps, judged by key prefix, along
+// with number of entries left out.
+func addonStorageKeys(entries map[string]string) (map[string]string, int) {
+	owners := []string{"spicetify", settingSection.Key("current_theme").String()}
+	for _, name := range expandExtensionList(featureSection.Key("extensions").Strings("|")) {
+		name = filepath.Base(name)
+		owners = append(owners, strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	owners = append(owners, featureSection.Key("custom_apps").Strings("|")...)
+
+	var prefixes []string
+	for _, owner := range owners {
+		if prefix := storageKeyNorm(owner); len(prefix) > 0 {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	result := map[string]string{}
+	for key, value := range entries {
+		norm := storageKeyNorm(key)
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(norm, prefix) {
+				result[key] = value
+				break
+			}
+		}
+	}
+
+	return result, len(entries) - len(result)
+}
+
+// storageKeyNorm lowercases `key` and drops non alphanumeric characters,
+// so "full-app-display:config" matches extension "fullAppDisplay.js".
+func storageKeyNorm(key string) string {
+	return storageKeyRe.ReplaceAllString(strings.ToLower(key), "")
+}
+
+var storageKeyRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// StorageExport dumps localStorage of running Spotify client, where
+// extensions and themes keep their settings, to file at `path`. Only
+// entries of spicetify, current theme, enabled extensions and custom apps
+// are exported unless `all` is set.
+func StorageExport(path string, all bool) error {
+	client, err := dialSpotify()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	result, err := client.Evaluate(storageExportScript)
+	if err != nil {
+		return err
+	}
+
+	dump := storageDump{Exported: time.Now()}
+	if err = json.Unmarshal(result, &dump.Entries); err != nil {
+		return err
+	}
+
+	skipped := 0
+	if !all {
+		dump.Entries, skipped = addonStorageKeys(dump.Entries)
+	}
+
+	content, err := json.MarshalIndent(dump, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(path, content, 0600); err != nil {
+		return err
+	}
+
+	utils.PrintSuccess(strconv.Itoa(len(dump.Entries)) + " localStorage entries are exported to " + path)
+	printSkippedStorage(skipped)
+	return nil
+}
+
+// StorageImport writes localStorage entries in file at `path` back to
+// running Spotify client, then reloads it. Existing entries with other
+// keys are kept. Like StorageExport, only addon entries are written unless
+// `all` is set.
+func StorageImport(path string, all bool) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var dump storageDump
+	if err = json.Unmarshal(content, &dump); err != nil {
+		return err
+	}
+
+	skipped := 0
+	if !all {
+		dump.Entries, skipped = addonStorageKeys(dump.Entries)
+	}
+
+	if len(dump.Entries) == 0 {
+		printSkippedStorage(skipped)
+		return errors.New("no entry found in " + path)
+	}
+
+	client, err := dialSpotify()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	entries, err := json.Marshal(dump.Entries)
+	if err != nil {
+		return err
+	}
+
+	script := `(entries => { for (const k in entries) localStorage.setItem(k, entries[k]); })(` + string(entries) + `)`
+	if _, err = client.Evaluate(script); err != nil {
+		return err
+	}
+
+	utils.PrintSuccess(strconv.Itoa(len(dump.Entries)) + " localStorage entries are imported")
+	printSkippedStorage(skipped)
+
+	if err = client.Reload(); err != nil {
+		utils.PrintInfo("Reload Spotify to apply imported settings")
+	} else {
+		utils.PrintSuccess("Spotify reloaded")
+	}
+
+	return nil
+}
+
+func printSkippedStorage(skipped int) {
+	if skipped > 0 {
+		utils.PrintInfo(strconv.Itoa(skipped) + ` entries not belonging to spicetify, current theme, enabled extensions or custom apps are skipped. Use "--all" to include them.`)
+	}
+}
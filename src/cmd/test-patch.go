@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/backup"
+	"github.com/khanhas/spicetify-cli/src/preprocess"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+const (
+	// testPatchContext is how many characters around a match are previewed.
+	testPatchContext = 60
+	// testPatchMaxPreviews limits previews printed per file.
+	testPatchMaxPreviews = 3
+)
+
+// sourceFile is a JS file patches are tested against.
+type sourceFile struct {
+	name    string
+	content string
+}
+
+// TestPatch runs a patch without writing anything and reports where it
+// matches. `rule` is one of:
+//   - name of a built-in patch, e.g. "expose_apis_main" or one of its
+//     parts like "expose_apis_main.player", tested against original files
+//     in backup since Raw files are already preprocessed;
+//   - a "<file>_find_<n>" key in "Patch" config section;
+//   - a regular expression, with optional `replacement`.
+//
+// The last two are tested against extracted Raw files, like they are run
+// on apply.
+func TestPatch(rule, replacement string) error {
+	var builtin []preprocess.ChunkPatch
+	for _, patch := range preprocess.BuiltinPatches() {
+		if patch.Name == rule || strings.HasPrefix(patch.Name, rule+".") {
+			builtin = append(builtin, patch)
+		}
+	}
+	if len(builtin) > 0 {
+		files, err := backupJSFiles()
+		if err != nil {
+			return err
+		}
+		for _, patch := range builtin {
+			testModifyPatch(patch, files)
+		}
+		return nil
+	}
+
+	files, err := rawJSFiles()
+	if err != nil {
+		return err
+	}
+
+	find := rule
+	onlyFile := ""
+	replaceAll := true
+	if key, err := patchSection.GetKey(rule); err == nil {
+		matches := regexp.MustCompile(`^([\w\d\-\.]+)_find_(\d+)$`).FindStringSubmatch(rule)
+		if matches == nil {
+			return errors.New(`"` + rule + `" is not a "<file>_find_<n>" key`)
+		}
+
+		find = key.String()
+		onlyFile = matches[1]
+		if repl, err := patchSection.GetKey(matches[1] + "_repl_all_" + matches[2]); err == nil {
+			replacement = repl.String()
+		} else if repl, err := patchSection.GetKey(matches[1] + "_repl_" + matches[2]); err == nil {
+			replacement = repl.String()
+			replaceAll = false
+		}
+	}
+
+	re, err := regexp.Compile(find)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, file := range files {
+		if len(onlyFile) > 0 && path.Base(file.name) != onlyFile {
+			continue
+		}
+
+		locations := re.FindAllStringSubmatchIndex(file.content, -1)
+		if len(locations) == 0 {
+			continue
+		}
+		if !replaceAll {
+			locations = locations[:1]
+		}
+		total += len(locations)
+
+		utils.PrintBold(fmt.Sprintf("%s: %d match(es)", file.name, len(locations)))
+		for i, loc := range locations {
+			if i == testPatchMaxPreviews {
+				log.Println(fmt.Sprintf("    ... %d more", len(locations)-i))
+				break
+			}
+
+			replaced := string(re.ExpandString(nil, replacement, file.content, loc))
+			printPatchPreview(file.content, loc[0], loc[1], replaced)
+		}
+	}
+
+	if total == 0 {
+		utils.PrintWarning("No match.")
+	} else {
+		utils.PrintSuccess(fmt.Sprintf("%d match(es) in total. Nothing is written.", total))
+	}
+
+	return nil
+}
+
+// testModifyPatch reports files changed by built-in patch. Changes are
+// shown as one region spanning from first to last changed character.
+func testModifyPatch(patch preprocess.ChunkPatch, files []sourceFile) {
+	chunks := make([]preprocess.Chunk, len(files))
+	for i, file := range files {
+		chunks[i] = preprocess.Chunk{Name: file.name, Content: file.content}
+	}
+	preprocess.ApplyPatches(chunks, []preprocess.ChunkPatch{patch})
+
+	changed := 0
+	for i, file := range files {
+		patched := chunks[i].Content
+		if patched == file.content {
+			continue
+		}
+		changed++
+
+		start := 0
+		for start < len(file.content) && start < len(patched) && file.content[start] == patched[start] {
+			start++
+		}
+		end, patchedEnd := len(file.content), len(patched)
+		for end > start && patchedEnd > start && file.content[end-1] == patched[patchedEnd-1] {
+			end--
+			patchedEnd--
+		}
+
+		utils.PrintBold(file.name + ":")
+		printPatchPreview(file.content, start, end, patched[start:patchedEnd])
+	}
+
+	if changed == 0 {
+		utils.PrintWarning(`"` + patch.Name + `" matches no file.`)
+	} else {
+		utils.PrintSuccess(fmt.Sprintf(`"%s" changes %d file(s). Nothing is written.`, patch.Name, changed))
+	}
+}
+
+// printPatchPreview prints position of content[start:end] and a preview
+// of it being replaced by `replaced`, with some context around.
+func printPatchPreview(content string, start, end int, replaced string) {
+	line := strings.Count(content[:start], "\n") + 1
+	column := start - strings.LastIndex(content[:start], "\n")
+
+	before := content[utils.MaxInt(0, start-testPatchContext):start]
+	after := content[end:utils.MinInt(len(content), end+testPatchContext)]
+	if i := strings.LastIndex(before, "\n"); i != -1 {
+		before = before[i+1:]
+	}
+	if i := strings.Index(after, "\n"); i != -1 {
+		after = after[:i]
+	}
+	removed := content[start:end]
+	if len(removed) > 2*testPatchContext {
+		removed = removed[:testPatchContext] + " ... " + removed[len(removed)-testPatchContext:]
+	}
+	if len(replaced) > 2*testPatchContext {
+		replaced = replaced[:testPatchContext] + " ... " + replaced[len(replaced)-testPatchContext:]
+	}
+
+	log.Println(fmt.Sprintf("    line %d, column %d (offset %d):", line, column, start))
+	log.Println("    " + before + utils.Red(removed) + after)
+	log.Println("    " + before + utils.Green(replaced) + after)
+}
+
+// rawJSFiles returns JS files in extracted Raw folder.
+func rawJSFiles() ([]sourceFile, error) {
+	if !isExtracted() {
+		return nil, errors.New(`no extracted files. Run "spicetify backup" first`)
+	}
+
+	var files []sourceFile
+	xpuiFolder := filepath.Join(rawFolder, "xpui")
+	matches, _ := filepath.Glob(filepath.Join(xpuiFolder, "*.js"))
+	sort.Strings(matches)
+	for _, match := range matches {
+		content, err := ioutil.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFile{"xpui/" + filepath.Base(match), string(content)})
+	}
+
+	return files, nil
+}
+
+// backupJSFiles returns original JS files of xpui from current backup.
+func backupJSFiles() ([]sourceFile, error) {
+	version := backupSection.Key("version").String()
+	if len(version) == 0 || !backup.Exists(backupFolder, version) {
+		return nil, errors.New(`no backup. Run "spicetify backup" first`)
+	}
+
+	data, err := backup.ReadFile(backupFolder, version, "xpui.spa")
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []sourceFile
+	for _, file := range reader.File {
+		if path.Ext(file.Name) != ".js" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFile{"xpui/" + file.Name, string(content)})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// themeInfo describes an installed theme.
+type themeInfo struct {
+	name    string
+	folder  string
+	schemes []string
+}
+
+// listThemes returns installed themes, sorted by name. Themes in user
+// folder take priority over ones with the same name in executable folder.
+func listThemes() []themeInfo {
+	seen := map[string]bool{}
+	var themes []themeInfo
+
+	for _, parent := range themesFolders() {
+		fileList, err := ioutil.ReadDir(parent)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range fileList {
+			name := file.Name()
+			if !file.IsDir() {
+				if !strings.HasSuffix(name, ".css") {
+					continue
+				}
+				name = strings.TrimSuffix(name, ".css")
+			}
+
+			if seen[name] {
+				continue
+			}
+
+			folder := filepath.Join(parent, file.Name())
+			if !isThemeFolder(folder) {
+				continue
+			}
+
+			seen[name] = true
+			themes = append(themes, themeInfo{
+				name:    name,
+				folder:  folder,
+				schemes: themeSchemes(folder),
+			})
+		}
+	}
+
+	sort.Slice(themes, func(i, j int) bool {
+		return strings.ToLower(themes[i].name) < strings.ToLower(themes[j].name)
+	})
+
+	return themes
+}
+
+// isThemeFolder reports whether folder has any theme file, or is a
+// single-file theme.
+func isThemeFolder(folder string) bool {
+	if info, err := os.Stat(folder); err == nil && !info.IsDir() {
+		return strings.HasSuffix(folder, ".css")
+	}
+
+	for _, name := range []string{"color.ini", "user.css", "assets"} {
+		if _, err := os.Stat(filepath.Join(folder, name)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// themeSchemes returns color scheme names in theme's color.ini.
+func themeSchemes(folder string) []string {
+	colors, err := ini.InsensitiveLoad(filepath.Join(folder, "color.ini"))
+	if err != nil {
+		return nil
+	}
+
+	var schemes []string
+	for _, section := range colors.Sections()[1:] {
+		schemes = append(schemes, section.Name())
+	}
+
+	return schemes
+}
+
+// ThemeList prints installed themes and their color schemes. Current theme
+// and scheme are marked with "*".
+func ThemeList() {
+	themes := listThemes()
+	if len(themes) == 0 {
+		utils.PrintInfo("No theme is installed. Put themes in " + userThemesFolder)
+		return
+	}
+
+	currentTheme := settingSection.Key("current_theme").String()
+	currentScheme := settingSection.Key("color_scheme").String()
+
+	for _, theme := range themes {
+		isCurrent := theme.name == currentTheme
+		line := "  " + theme.name
+		if isCurrent {
+			line = utils.Green("* ") + utils.Bold(theme.name)
+		}
+		log.Println(line + " (" + theme.folder + ")")
+
+		for index, scheme := range theme.schemes {
+			mark := "    - "
+			if isCurrent && (strings.EqualFold(scheme, currentScheme) ||
+				(len(currentScheme) == 0 && index == 0)) {
+				mark = "    * "
+			}
+			log.Println(mark + scheme)
+		}
+	}
+}
+
+// ThemeUse validates theme `name` and color `scheme` then sets them as
+// current ones. When scheme is blank, current scheme is kept if the new
+// theme has it, else the first scheme is used.
+// `name` can also be URL of a remote theme, which is downloaded first.
+func ThemeUse(name, scheme string) error {
+	var folder string
+	var err error
+	if isRemoteTheme(name) {
+		folder, err = remoteThemeFolder(name)
+	} else {
+		folder, err = findThemeFolder(name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !isThemeFolder(folder) {
+		return errors.New(`"` + folder + `" has no color.ini, user.css or assets`)
+	}
+
+	schemes := themeSchemes(folder)
+	if len(scheme) == 0 {
+		scheme = settingSection.Key("color_scheme").String()
+		if len(findScheme(schemes, scheme)) == 0 {
+			scheme = ""
+		}
+	} else if len(schemes) == 0 {
+		return errors.New(`theme "` + name + `" has no color scheme`)
+	} else if found := findScheme(schemes, scheme); len(found) > 0 {
+		scheme = found
+	} else {
+		return errors.New(`color scheme "` + scheme + `" not found in theme "` + name + `". Available: ` + strings.Join(schemes, ", "))
+	}
+
+	settingSection.Key("current_theme").SetValue(name)
+	settingSection.Key("theme_source").SetValue("")
+	settingSection.Key("color_scheme").SetValue(scheme)
+	cfg.Write()
+
+	if len(scheme) > 0 {
+		utils.PrintSuccess(`Theme is set to "` + name + `", scheme "` + scheme + `"`)
+	} else {
+		utils.PrintSuccess(`Theme is set to "` + name + `"`)
+	}
+
+	return nil
+}
+
+func findScheme(schemes []string, scheme string) string {
+	for _, s := range schemes {
+		if strings.EqualFold(s, scheme) {
+			return s
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/go-ini/ini"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Zoom range Spotify client accepts.
+const (
+	minUIScale = 0.5
+	maxUIScale = 3
+)
+
+// uiScale returns scale factor set in "ui_scale", or 0 when it is blank.
+// Invalid values are reported when `warn` is true.
+func uiScale(warn bool) float64 {
+	value := featureSection.Key("ui_scale").String()
+	if len(value) == 0 {
+		return 0
+	}
+
+	scale, err := strconv.ParseFloat(value, 64)
+	if err != nil || scale < minUIScale || scale > maxUIScale {
+		if warn {
+			utils.PrintWarning(`Config "ui_scale" is a number from 0.5 to 3, e.g. 1.25. Skipped.`)
+		}
+		return 0
+	}
+	return scale
+}
+
+// applyUIScalePref writes "ui_scale" to Spotify zoom level in "prefs".
+// Spotify saves prefs when it quits, so it has to be closed first.
+func applyUIScalePref() {
+	scale := uiScale(false)
+	if scale == 0 || len(prefsPath) == 0 {
+		return
+	}
+
+	pref, err := ini.LoadSources(
+		ini.LoadOptions{
+			PreserveSurroundedQuote: true,
+		},
+		prefsPath)
+	if err != nil {
+		utils.PrintWarningOf("Cannot set zoom level: ", err)
+		return
+	}
+
+	zoom := uiScaleZoom(scale)
+	key := pref.Section("").Key("app.browser.zoom-level")
+	if key.String() == zoom {
+		return
+	}
+	key.SetValue(zoom)
+
+	ini.PrettyFormat = false
+	if err = pref.SaveTo(prefsPath); err != nil {
+		utils.PrintWarningOf("Cannot set zoom level: ", err)
+	}
+}
+
+// canWriteUIScalePref reports whether zoom level can be written to "prefs".
+// When it cannot, root font size is scaled instead, so client is not zoomed
+// twice when it can.
+func canWriteUIScalePref() bool {
+	if len(prefsPath) == 0 {
+		utils.PrintWarning(`"prefs" file is not found, "ui_scale" only scales text.`)
+		return false
+	}
+
+	file, err := os.OpenFile(prefsPath, os.O_WRONLY, 0)
+	if err != nil {
+		utils.PrintWarningOf(`Cannot write "prefs" file, "ui_scale" only scales text: `, err)
+		return false
+	}
+	file.Close()
+	return true
+}
+
+// uiScaleZoom converts scale factor to Spotify zoom level, in percent.
+func uiScaleZoom(scale float64) string {
+	return strconv.Itoa(int(math.Round(scale * 100)))
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/backup"
+	backupstatus "github.com/khanhas/spicetify-cli/src/status/backup"
+	spotifystatus "github.com/khanhas/spicetify-cli/src/status/spotify"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Uninstall brings Spotify back to stock state and removes everything
+// spicetify has written outside of its own folders, including autostart
+// entries running it. With purge, or when user agrees, spicetify config,
+// user and cache folders are deleted too.
+func Uninstall(purge bool) {
+	checkWritePermission()
+
+	if isSpotifyRunning() {
+		utils.PrintInfo("Closing Spotify...")
+		quitSpotify()
+	}
+
+	utils.PrintBold("Restoring Spotify:")
+	if err := restoreStock(); err != nil {
+		utils.PrintErrorOf("", err)
+		utils.PrintInfo("Re-install Spotify to get back to stock state.")
+	} else {
+		utils.PrintGreen("OK")
+	}
+	clearAppliedRecord()
+	clearApplyJournal()
+
+	if _, err := os.Stat(devToolStatePath()); err == nil {
+		utils.PrintBold("Disabling developer tools:")
+		if err := setDevToolPref(false); err != nil {
+			utils.PrintErrorOf("", err)
+		} else if err := patchOfflineBnk(false); err != nil {
+			utils.PrintErrorOf("", err)
+		} else {
+			utils.PrintGreen("OK")
+		}
+	}
+
+	if entries := autostartEntries(); len(entries) > 0 {
+		utils.PrintWarning("Found autostart entries running spicetify:")
+		printFileList(entries)
+		if purge || ReadAnswer("Remove them? [Y/n] ", true, false) {
+			utils.PrintBold("Removing autostart entries:")
+			if err := removeAutostart(entries); err != nil {
+				utils.PrintErrorOf("", err)
+			} else {
+				utils.PrintGreen("OK")
+			}
+		}
+	}
+
+	if !purge {
+		purge = ReadAnswer("Delete spicetify config, themes, extensions, custom apps and backups too? [y/N] ", false, false)
+	}
+
+	if purge {
+		utils.PrintBold("Deleting spicetify folders:")
+		if err := purgeSpicetifyFolders(); err != nil {
+			utils.PrintErrorOf("", err)
+			purge = false
+		} else {
+			utils.PrintGreen("OK")
+		}
+	}
+
+	utils.PrintSuccess("Spicetify is uninstalled.")
+	if !purge {
+		utils.PrintInfo("Config and backups are kept in " + spicetifyFolder)
+	}
+}
+
+// restoreStock puts original app files back to Spotify and removes files
+// injected by spicetify.
+func restoreStock() error {
+	if isAppX {
+		// AppX Spotify is never modified, only launched with our copy.
+		return os.RemoveAll(appDestPath)
+	}
+
+	backupVersion := backupSection.Key("version").String()
+	backStat := backupstatus.Get(prefsPath, backupFolder, backupVersion)
+	spotStat := spotifystatus.Get(appDestPath)
+
+	if !backStat.IsEmpty() && !backStat.IsOutdated() && !spotStat.IsStock() {
+		if err := os.RemoveAll(appDestPath); err != nil {
+			return err
+		}
+		return backup.Restore(backupFolder, backupVersion, appDestPath)
+	}
+
+	// Without a matching backup, Spotify app files are either stock or
+	// were replaced by an update. Only folders spicetify extracts next to
+	// them are removed.
+	fileList, err := ioutil.ReadDir(appDestPath)
+	if err != nil {
+		return err
+	}
+
+	injected := map[string]bool{"zlink": true}
+	if rawList, err := ioutil.ReadDir(rawFolder); err == nil {
+		for _, file := range rawList {
+			injected[file.Name()] = true
+		}
+	}
+
+	hasSPA := false
+	for _, file := range fileList {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".spa") {
+			hasSPA = true
+			injected[strings.TrimSuffix(file.Name(), ".spa")] = true
+		}
+	}
+
+	if !hasSPA {
+		return errors.New("no original app file is found in " + appDestPath)
+	}
+
+	for _, file := range fileList {
+		if !file.IsDir() || !injected[file.Name()] {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(appDestPath, file.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// purgeSpicetifyFolders deletes files and folders spicetify created in its
+// config and cache folders, then the folders themselves when nothing else
+// is left in them. Config folder can be set by "SPICETIFY_CONFIG", so one
+// without spicetify config file is never touched.
+func purgeSpicetifyFolders() error {
+	if _, err := os.Stat(GetConfigPath()); err != nil {
+		return errors.New(spicetifyFolder + " has no spicetify config file, refusing to delete it")
+	}
+
+	for _, name := range []string{"Extracted", "RemoteThemes", "Fetch"} {
+		os.RemoveAll(filepath.Join(cacheFolder, name))
+	}
+	// Only removed when empty, "cache_path" can point to a shared folder.
+	os.Remove(cacheFolder)
+
+	created := []string{
+		backupFolder,
+		userThemesFolder,
+		userExtensionsFolder,
+		userAppsFolder,
+		filepath.Dir(extensionSettingsPath("")),
+		filepath.Join(spicetifyFolder, "AppX"),
+		filepath.Join(spicetifyFolder, "Extracted"),
+		applyJournalPath(),
+		applyJournalPath() + ".tmp",
+		appliedRecordPath(),
+		applyLogPath(),
+		devToolStatePath(),
+		rotationStatePath(),
+		GetConfigPath(),
+	}
+	for _, path := range created {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(spicetifyFolder); err != nil && !os.IsNotExist(err) {
+		utils.PrintWarning(spicetifyFolder + " is kept, it contains files not created by spicetify.")
+	}
+
+	return nil
+}
+
+// autostartEntries returns autostart files of current user that run
+// spicetify, e.g. "spicetify auto" shortcut in Windows Startup folder,
+// a systemd user unit or a launchd agent for rotation.
+func autostartEntries() []string {
+	var patterns []string
+	switch runtime.GOOS {
+	case "windows":
+		patterns = append(patterns, filepath.Join(os.Getenv("APPDATA"),
+			"Microsoft", "Windows", "Start Menu", "Programs", "Startup", "*"))
+	case "linux":
+		parent, isAvailable := os.LookupEnv("XDG_CONFIG_HOME")
+		if !isAvailable || len(parent) == 0 {
+			parent = filepath.Join(os.Getenv("HOME"), ".config")
+		}
+		patterns = append(patterns,
+			filepath.Join(parent, "autostart", "*.desktop"),
+			filepath.Join(parent, "systemd", "user", "*.service"),
+			filepath.Join(parent, "systemd", "user", "*.timer"))
+	case "darwin":
+		patterns = append(patterns, filepath.Join(os.Getenv("HOME"), "Library", "LaunchAgents", "*.plist"))
+	}
+
+	var entries []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if strings.Contains(strings.ToLower(filepath.Base(match)), "spicetify") {
+				entries = append(entries, match)
+			}
+		}
+	}
+
+	return entries
+}
+
+// removeAutostart stops and deletes autostart `entries`.
+func removeAutostart(entries []string) error {
+	for _, entry := range entries {
+		switch filepath.Ext(entry) {
+		case ".service", ".timer":
+			exec.Command("systemctl", "--user", "disable", "--now", filepath.Base(entry)).Run()
+		case ".plist":
+			exec.Command("launchctl", "unload", entry).Run()
+		}
+
+		if err := os.Remove(entry); err != nil {
+			return err
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		exec.Command("systemctl", "--user", "daemon-reload").Run()
+	}
+
+	return nil
+}
@@ -2,34 +2,40 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
-	"io/ioutil"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
 type githubRelease struct {
-	TagName string `json:"tag_name"`
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
 }
 
+const releasesURL = "https://api.github.com/repos/khanhas/spicetify-cli/releases"
+
 func Upgrade(currentVersion string) {
 	utils.PrintBold("Fetch latest release info:")
-	tagName, err := FetchLatestTag()
+	tagName, err := FetchLatestTag(0)
 	if err != nil {
 		utils.PrintError("Cannot fetch latest release info")
-		utils.PrintError(err.Error())
-		return
+		utils.PrintErrorOf("", err)
+		os.Exit(utils.ExitNetwork)
 	}
 	utils.PrintGreen("OK")
 
 	utils.PrintInfo("Current version: " + currentVersion)
-	utils.PrintInfo("Latest release: " + tagName)
-	if currentVersion == tagName {
+	utils.PrintInfo("Latest release: " + tagName + " (" + updateChannel() + ")")
+	if !isNewerVersion(tagName, currentVersion) {
 		utils.PrintSuccess("Already up-to-date.")
 		return
 	}
@@ -56,7 +62,7 @@ func Upgrade(currentVersion string) {
 	}
 	defer out.Close()
 
-	resp2, err := http.Get(assetURL)
+	resp2, err := utils.Download(assetURL)
 	if err != nil {
 		utils.Fatal(err)
 	}
@@ -106,21 +112,137 @@ func permissionError(err error) {
 	utils.Fatal(err)
 }
 
-func FetchLatestTag() (string, error) {
-	res, err := http.Get("https://api.github.com/repos/khanhas/spicetify-cli/releases/latest")
-	if err != nil {
+// updateChannel returns release channel set in config, which is either
+// "stable" or "prerelease".
+func updateChannel() string {
+	if settingSection.Key("update_channel").String() == "prerelease" {
+		return "prerelease"
+	}
+	return "stable"
+}
+
+// FetchLatestTag returns version of latest release in update channel.
+// Release info cached within `maxAge` is used without fetching.
+// Prerelease channel gets the newest release, stable or not.
+func FetchLatestTag(maxAge time.Duration) (string, error) {
+	var release githubRelease
+	if updateChannel() == "prerelease" {
+		var releases []githubRelease
+		if err := fetchJSON(releasesURL+"?per_page=10", maxAge, &releases); err != nil {
+			return "", err
+		}
+
+		for _, r := range releases {
+			if !r.Draft {
+				release = r
+				break
+			}
+		}
+	} else if err := fetchJSON(releasesURL+"/latest", maxAge, &release); err != nil {
 		return "", err
 	}
 
-	body, err := ioutil.ReadAll(res.Body)
+	if len(release.TagName) == 0 {
+		return "", errors.New("no release found")
+	}
+
+	return strings.TrimPrefix(release.TagName, "v"), nil
+}
+
+func fetchJSON(url string, maxAge time.Duration, result interface{}) error {
+	body, err := utils.FetchCached(url, maxAge)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	var release githubRelease
-	if err = json.Unmarshal(body, &release); err != nil {
-		return "", err
+	return json.Unmarshal(body, result)
+}
+
+// isNewerVersion reports whether version `a` is newer than `b`. Versions
+// are compared by their numbers, and a prerelease, e.g. "2.0.0-beta.1",
+// is older than its release. Prereleases of the same version are compared
+// as in semver, build metadata after "+" is ignored.
+func isNewerVersion(a, b string) bool {
+	aNums, aPre := splitVersion(a)
+	bNums, bPre := splitVersion(b)
+
+	for i := 0; i < len(aNums) || i < len(bNums); i++ {
+		var x, y int
+		if i < len(aNums) {
+			x = aNums[i]
+		}
+		if i < len(bNums) {
+			y = bNums[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+
+	if len(aPre) == 0 || len(bPre) == 0 {
+		return len(aPre) == 0 && len(bPre) > 0
+	}
+
+	return comparePrerelease(aPre, bPre) > 0
+}
+
+// comparePrerelease compares dot separated prerelease identifiers `a` and
+// `b`, returning -1, 0 or 1. Numeric identifiers are compared numerically
+// and are lower than alphanumeric ones. A prerelease with more identifiers
+// is higher when all preceding ones are equal.
+func comparePrerelease(a, b string) int {
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		x, y := aIDs[i], bIDs[i]
+		xNum, xErr := strconv.ParseUint(x, 10, 64)
+		yNum, yErr := strconv.ParseUint(y, 10, 64)
+
+		switch {
+		case xErr == nil && yErr == nil:
+			if xNum != yNum {
+				if xNum > yNum {
+					return 1
+				}
+				return -1
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		case x != y:
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	switch {
+	case len(aIDs) > len(bIDs):
+		return 1
+	case len(aIDs) < len(bIDs):
+		return -1
+	}
+	return 0
+}
+
+func splitVersion(version string) ([]int, string) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.Index(version, "+"); i != -1 {
+		version = version[:i]
+	}
+	pre := ""
+	if i := strings.Index(version, "-"); i != -1 {
+		version, pre = version[:i], version[i+1:]
+	}
+
+	var nums []int
+	for _, part := range strings.Split(version, ".") {
+		num, _ := strconv.Atoi(part)
+		nums = append(nums, num)
 	}
 
-	return release.TagName[1:], nil
+	return nums, pre
 }
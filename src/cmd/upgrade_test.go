@@ -0,0 +1,35 @@
+package cmd
+
+import "testing"
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"2.0.1", "2.0.0", true},
+		{"2.0.0", "2.0.1", false},
+		{"2.10.0", "2.9.0", true},
+		{"v2.1", "2.0.9", true},
+		{"2.0", "2.0.0", false},
+		{"2.0.0", "2.0.0", false},
+		{"2.0.0", "2.0.0-beta.1", true},
+		{"2.0.0-beta.1", "2.0.0", false},
+		{"2.0.0-beta.10", "2.0.0-beta.9", true},
+		{"2.0.0-beta.2", "2.0.0-beta.10", false},
+		{"2.0.0-beta", "2.0.0-alpha.5", true},
+		{"2.0.0-alpha.1", "2.0.0-alpha", true},
+		{"2.0.0-alpha", "2.0.0-alpha.1", false},
+		{"2.0.0-alpha.beta", "2.0.0-alpha.1", true},
+		{"2.0.0-rc.1", "2.0.0-rc.1", false},
+		{"2.0.0+build.2", "2.0.0+build.1", false},
+		{"2.0.0+build", "2.0.0-rc.1", true},
+		{"2.0.0-rc.1+build", "2.0.0-rc.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isNewerVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("isNewerVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// appliedRecord holds hashes of every file spicetify wrote to Spotify
+// Apps folder, so changes made by Spotify or other tools can be detected.
+type appliedRecord struct {
+	Time           time.Time         `json:"time"`
+	SpotifyVersion string            `json:"spotify_version"`
+	Files          map[string]string `json:"files"`
+	// Inputs is hash of everything apply depends on, recorded only after
+	// apply succeeds. It's blank when applied files don't reflect inputs.
+	Inputs string `json:"inputs,omitempty"`
+}
+
+func appliedRecordPath() string {
+	return filepath.Join(spicetifyFolder, "applied.json")
+}
+
+// recordAppliedFiles hashes all files in Spotify Apps folder and saves them
+// as applied record.
+func recordAppliedFiles() {
+	writeAppliedRecord("")
+}
+
+// writeAppliedRecord records applied files along with `inputs` hash from
+// applyInputsHash.
+func writeAppliedRecord(inputs string) {
+	record := appliedRecord{
+		Time:           time.Now(),
+		SpotifyVersion: utils.GetSpotifyVersion(prefsPath),
+		Files:          map[string]string{},
+		Inputs:         inputs,
+	}
+
+	if err := hashAppliedFiles(record.Files, appDestPath); err != nil {
+		utils.PrintWarningOf("Cannot record applied files: ", err)
+		return
+	}
+
+	saveAppliedRecord(&record)
+}
+
+// updateAppliedRecord rehashes only `paths`, files or folders in Spotify
+// Apps folder that were just written or removed, instead of every applied
+// file. Inputs hash is cleared like in recordAppliedFiles.
+func updateAppliedRecord(paths ...string) {
+	record, err := readAppliedRecord()
+	if err != nil {
+		recordAppliedFiles()
+		return
+	}
+
+	record.Time = time.Now()
+	record.Inputs = ""
+	for _, path := range paths {
+		rel, err := filepath.Rel(appDestPath, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		rel = filepath.ToSlash(rel)
+		for file := range record.Files {
+			if file == rel || strings.HasPrefix(file, rel+"/") {
+				delete(record.Files, file)
+			}
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		if err := hashAppliedFiles(record.Files, path); err != nil {
+			utils.PrintWarningOf("Cannot record applied files: ", err)
+			return
+		}
+	}
+
+	saveAppliedRecord(record)
+}
+
+// hashAppliedFiles adds hashes of files in `root`, keyed by their paths
+// relative to Spotify Apps folder, to `files`.
+func hashAppliedFiles(files map[string]string, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		// node_modules is a symlink to user's folder, not written by us.
+		if entry.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+
+		hash, err := utils.HashFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(appDestPath, path)
+		files[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+}
+
+func saveAppliedRecord(record *appliedRecord) {
+	content, err := json.Marshal(record)
+	if err != nil {
+		utils.PrintWarningOf("Cannot record applied files: ", err)
+		return
+	}
+
+	if err = ioutil.WriteFile(appliedRecordPath(), content, 0700); err != nil {
+		utils.PrintWarningOf("Cannot record applied files: ", err)
+	}
+}
+
+func readAppliedRecord() (*appliedRecord, error) {
+	content, err := ioutil.ReadFile(appliedRecordPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var record appliedRecord
+	if err = json.Unmarshal(content, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// clearAppliedRecord removes applied record after Spotify is restored.
+func clearAppliedRecord() {
+	utils.CheckExistAndDelete(appliedRecordPath())
+}
+
+// Verify compares files in Spotify Apps folder with hashes recorded at
+// last apply and reports files that are changed or missing since then.
+// It returns false when a re-apply is needed.
+func Verify() bool {
+	record, err := readAppliedRecord()
+	if err != nil {
+		utils.PrintError(`No apply record found. Run "spicetify apply" first.`)
+		return false
+	}
+
+	utils.PrintInfo("Spotify install: " + describeInstall(installKind(), utils.IsSpotifyPreview(spotifyPath)))
+	utils.PrintInfo("Last applied: " + record.Time.Format(time.RFC1123))
+
+	modified, missing, reinstalled := appliedChanges(record)
+
+	spotifyVersion := utils.GetSpotifyVersion(prefsPath)
+	versionChanged := len(record.SpotifyVersion) > 0 && record.SpotifyVersion != spotifyVersion
+
+	if len(modified) == 0 && len(missing) == 0 && len(reinstalled) == 0 && !versionChanged {
+		utils.PrintSuccess("All applied files are intact. No re-apply is needed.")
+		return true
+	}
+
+	if versionChanged {
+		utils.PrintWarning("Spotify is updated from " + record.SpotifyVersion + " to " + spotifyVersion + " since last apply.")
+	}
+
+	if len(reinstalled) > 0 {
+		utils.PrintWarning("Spotify reinstalled its packed app files, which replace applied customization:")
+		printFileList(reinstalled)
+	}
+
+	if len(missing) > 0 {
+		utils.PrintWarning("Applied files are missing:")
+		printFileList(missing)
+	}
+
+	if len(modified) > 0 {
+		utils.PrintWarning("Applied files are overwritten:")
+		printFileList(modified)
+	}
+
+	if versionChanged || len(reinstalled) > 0 {
+		utils.PrintInfo(`Run "spicetify backup apply" to re-apply on new Spotify version.`)
+	} else {
+		utils.PrintInfo(`Run "spicetify apply" to re-apply.`)
+	}
+
+	return false
+}
+
+// appliedChanges returns files in `record` that are modified or missing in
+// Spotify Apps folder, and packed app files Spotify put back there.
+func appliedChanges(record *appliedRecord) (modified, missing, reinstalled []string) {
+	for rel, hash := range record.Files {
+		current, err := utils.HashFile(filepath.Join(appDestPath, filepath.FromSlash(rel)))
+		if err != nil {
+			missing = append(missing, rel)
+		} else if current != hash {
+			modified = append(modified, rel)
+		}
+	}
+	sort.Strings(modified)
+	sort.Strings(missing)
+
+	if fileList, err := ioutil.ReadDir(appDestPath); err == nil {
+		for _, file := range fileList {
+			if !file.IsDir() && strings.HasSuffix(file.Name(), ".spa") {
+				reinstalled = append(reinstalled, file.Name())
+			}
+		}
+	}
+
+	return modified, missing, reinstalled
+}
+
+func printFileList(list []string) {
+	for _, name := range list {
+		log.Println("    " + name)
+	}
+}
@@ -2,17 +2,26 @@ package cmd
 
 import (
 	"encoding/json"
+	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/khanhas/spicetify-cli/src/apply"
+	"github.com/khanhas/spicetify-cli/src/backup"
+	"github.com/khanhas/spicetify-cli/src/cdp"
 	spotifystatus "github.com/khanhas/spicetify-cli/src/status/spotify"
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
 var (
-	debuggerURL    string
+	debuggerClient *cdp.Client
 	autoReloadFunc func()
+	reloadPending  int32
 )
 
 // Watch .
@@ -33,7 +42,7 @@ func Watch(liveUpdate bool) {
 	}
 
 	colorPath := filepath.Join(themeFolder, "color.ini")
-	cssPath := filepath.Join(themeFolder, "user.css")
+	cssPath := apply.UserCSSPath(themeFolder)
 
 	fileList := []string{}
 	if replaceColors {
@@ -42,6 +51,7 @@ func Watch(liveUpdate bool) {
 
 	if injectCSS {
 		fileList = append(fileList, cssPath)
+		fileList = append(fileList, apply.CSSImports(cssPath)...)
 	}
 
 	if overwriteAssets {
@@ -54,6 +64,7 @@ func Watch(liveUpdate bool) {
 				}
 				
 				updateAssets()
+				updateAppliedRecord(assetOutputFiles()...)
 				utils.PrintSuccess(utils.PrependTime("Custom assets are updated"))
 			}, autoReloadFunc)
 		}
@@ -66,6 +77,7 @@ func Watch(liveUpdate bool) {
 
 		InitSetting()
 		updateCSS()
+		updateAppliedRecord(cssOutputFiles()...)
 		utils.PrintSuccess(utils.PrependTime("Custom CSS is updated"))
 	}, autoReloadFunc)
 }
@@ -84,7 +96,7 @@ func WatchExtensions(extName []string, liveUpdate bool) {
 	if len(extName) > 0 {
 		extNameList = extName
 	} else {
-		extNameList = featureSection.Key("extensions").Strings("|")
+		extNameList = expandExtensionList(featureSection.Key("extensions").Strings("|"))
 	}
 
 	var extPathList []string
@@ -105,18 +117,22 @@ func WatchExtensions(extName []string, liveUpdate bool) {
 
 	utils.Watch(extPathList, func(filePath string, err error) {
 		if err != nil {
-			utils.PrintError(err.Error())
+			utils.PrintErrorOf("", err)
 			os.Exit(1)
 		}
 
 		pushExtensions(filePath)
+		updateAppliedRecord(extensionOutputFiles(filePath)...)
 
 		utils.PrintSuccess(utils.PrependTime(`Extension "` + filePath + `" is updated.`))
 	}, autoReloadFunc)
 }
 
-// WatchCustomApp .
-func WatchCustomApp(appName []string, liveUpdate bool) {
+// WatchCustomApp pushes custom apps `appName`, or all enabled ones, to
+// Spotify whenever their files change. When execCommand is set, whole app
+// folder is watched and the command, e.g. "npm run build", is run in it on
+// changes before app is pushed.
+func WatchCustomApp(appName []string, liveUpdate bool, execCommand string) {
 	if !isValidForWatching() {
 		os.Exit(1)
 	}
@@ -166,13 +182,19 @@ func WatchCustomApp(appName []string, liveUpdate bool) {
 
 		threadCount += 1
 		var appName = v
+		if len(execCommand) > 0 {
+			go watchCustomAppBuild(appName, appPath, appFileList, execCommand)
+			continue
+		}
+
 		go utils.Watch(appFileList, func(filePath string, err error) {
 			if err != nil {
-				utils.PrintError(err.Error())
+				utils.PrintErrorOf("", err)
 				os.Exit(1)
 			}
 	
 			pushApps(appName)
+			updateAppliedRecord(appOutputFiles(appName)...)
 	
 			utils.PrintSuccess(utils.PrependTime(`Custom app "` + appName + `" is updated.`))
 		}, autoReloadFunc)
@@ -185,6 +207,95 @@ func WatchCustomApp(appName []string, liveUpdate bool) {
 	}
 }
 
+// watchCustomAppBuild runs execCommand in app folder whenever its source
+// files change, then pushes build output to Spotify. Files written by the
+// build, found by their modification time, and node_modules are not
+// watched to not trigger another build.
+func watchCustomAppBuild(appName, appPath string, outputList []string, execCommand string) {
+	isOutput := map[string]bool{}
+	for _, output := range outputList {
+		isOutput[filepath.Clean(output)] = true
+	}
+
+	excludeDir := func(name string) bool {
+		return name == "node_modules" || strings.HasPrefix(name, ".")
+	}
+	exclude := func(filePath string, isDir bool) bool {
+		if isDir {
+			return excludeDir(filepath.Base(filePath))
+		}
+		return isOutput[filepath.Clean(filePath)]
+	}
+
+	utils.WatchRecursiveExcept(appPath, exclude, func(filePath string, err error) {
+		if err != nil {
+			utils.PrintErrorOf("", err)
+		}
+	}, func() {
+		utils.PrintInfo(utils.PrependTime(`Building custom app "` + appName + `"...`))
+		before := modTimes(appPath, excludeDir)
+		err := runShellCommand(execCommand, appPath)
+		for file, modTime := range modTimes(appPath, excludeDir) {
+			if last, ok := before[file]; !ok || !last.Equal(modTime) {
+				isOutput[file] = true
+			}
+		}
+		if err != nil {
+			utils.PrintErrorOf(`Build of custom app "`+appName+`" failed: `, err)
+			return
+		}
+
+		if err := pushApp(appName); err != nil {
+			utils.PrintErrorOf(`Custom app "`+appName+`": `, err)
+			return
+		}
+		updateAppliedRecord(appOutputFiles(appName)...)
+
+		utils.PrintSuccess(utils.PrependTime(`Custom app "` + appName + `" is updated.`))
+		if autoReloadFunc != nil {
+			autoReloadFunc()
+		}
+	})
+}
+
+// modTimes returns modification time of every file in `root`, skipping
+// folders whose names `excludeDir` returns true for.
+func modTimes(root string, excludeDir func(name string) bool) map[string]time.Time {
+	times := map[string]time.Time{}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if path != root && excludeDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		times[filepath.Clean(path)] = info.ModTime()
+		return nil
+	})
+
+	return times
+}
+
+// runShellCommand runs command line in system shell, in `dir` folder.
+func runShellCommand(command, dir string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	cmd.Dir = dir
+	cmd.Stdout = log.Writer()
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 func isValidForWatching() bool {
 	status := spotifystatus.Get(appDestPath)
 
@@ -193,23 +304,52 @@ func isValidForWatching() bool {
 		return false
 	}
 
+	backupVersion := backupSection.Key("version").MustString("")
+	if len(backupVersion) > 0 && backup.Exists(backupFolder, backupVersion) {
+		if !isSameBuild(backupVersion) {
+			utils.PrintWarning("Spotify files are different from backup. Spotify client possibly just had an new update.")
+			utils.PrintInfo(`Please run "spicetify backup apply".`)
+		}
+		checkExtracted(backupVersion)
+	}
+
 	return true
 }
 
 func startDebugger() {
-	if len(utils.GetDebuggerPath()) == 0 {
-		RestartSpotify("--remote-debugging-port=9222")
+	if !cdp.Reachable() {
+		RestartSpotify(utils.DebuggerFlags()...)
 		utils.PrintInfo("Spotify is restarted with debugger on. Waiting...")
-		for len(utils.GetDebuggerPath()) == 0 {
+		for !cdp.Reachable() {
 			// Wait until debugger is up
+			time.Sleep(utils.INTERVAL)
 		}
 	}
+
+	// Client reconnects by itself when Spotify restarts while watching.
+	var err error
+	if debuggerClient, err = cdp.DialPersistent(); err != nil {
+		utils.Fatal(err)
+	}
+
 	autoReloadFunc = func() {
-		if utils.SendReload(&debuggerURL) != nil {
-			utils.PrintError("Could not Reload Spotify")
-			utils.PrintInfo(`Close Spotify and run watch command again.`)
-		} else {
+		if debuggerClient.Reload() == nil {
 			utils.PrintSuccess("Spotify reloaded")
+			return
+		}
+
+		// Spotify may be restarting. Keep trying in background so watcher
+		// is not blocked, without stacking up retry loops.
+		if !atomic.CompareAndSwapInt32(&reloadPending, 0, 1) {
+			return
 		}
+		utils.PrintWarning("Could not reload Spotify. Retrying in background...")
+		go func() {
+			defer atomic.StoreInt32(&reloadPending, 0)
+			for debuggerClient.Reload() != nil {
+				time.Sleep(utils.DebuggerBackoff)
+			}
+			utils.PrintSuccess("Spotify reloaded")
+		}()
 	}
 }
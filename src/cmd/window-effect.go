@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// Windows builds from which backdrop materials are available.
+const (
+	micaBuild    = 22000
+	acrylicBuild = 22621
+)
+
+// windowEffect returns backdrop material set in "window_effect" when this
+// system supports it, else blank. Unsupported values are reported when
+// `warn` is true.
+func windowEffect(warn bool) string {
+	effect := strings.ToLower(featureSection.Key("window_effect").String())
+	if len(effect) == 0 {
+		return ""
+	}
+
+	if effect != "mica" && effect != "acrylic" {
+		if warn {
+			utils.PrintWarning(`Config "window_effect" is either "mica" or "acrylic". Skipped.`)
+		}
+		return ""
+	}
+
+	if runtime.GOOS != "windows" {
+		if warn {
+			utils.PrintWarning(`Config "window_effect" only works on Windows 11. Skipped.`)
+		}
+		return ""
+	}
+
+	build := windowsBuild()
+	if build < micaBuild {
+		if warn {
+			utils.PrintWarning(`Config "window_effect" needs Windows 11. Skipped.`)
+		}
+		return ""
+	}
+
+	if effect == "acrylic" && build < acrylicBuild {
+		if warn {
+			utils.PrintWarning(`Acrylic needs Windows 11 22H2 or newer. Using Mica instead.`)
+		}
+		return "mica"
+	}
+
+	return effect
+}
+
+// windowsBuild returns Windows build number, or 0 when it is unknown.
+func windowsBuild() int {
+	out, err := exec.Command("reg", "query",
+		`HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion`,
+		"/v", "CurrentBuildNumber").Output()
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if i := strings.Index(line, "REG_SZ"); i != -1 {
+			build, _ := strconv.Atoi(strings.TrimSpace(line[i+len("REG_SZ"):]))
+			return build
+		}
+	}
+	return 0
+}
+
+// windowEffectScript waits for Spotify main window then sets its backdrop
+// type with DWM. Old Windows 11 builds only have undocumented Mica flag.
+const windowEffectScript = `
+Add-Type @"
+using System;
+using System.Runtime.InteropServices;
+public static class Dwm {
+    [StructLayout(LayoutKind.Sequential)]
+    public struct Margins { public int Left, Right, Top, Bottom; }
+    [DllImport("dwmapi.dll")]
+    public static extern int DwmSetWindowAttribute(IntPtr hwnd, int attr, ref int value, int size);
+    [DllImport("dwmapi.dll")]
+    public static extern int DwmExtendFrameIntoClientArea(IntPtr hwnd, ref Margins margins);
+}
+"@
+for ($i = 0; $i -lt 60; $i++) {
+    $window = Get-Process Spotify -ErrorAction SilentlyContinue | Where-Object { $_.MainWindowHandle -ne 0 } | Select-Object -First 1
+    if ($window) {
+        $hwnd = $window.MainWindowHandle
+        $margins = New-Object Dwm+Margins
+        $margins.Left = -1; $margins.Right = -1; $margins.Top = -1; $margins.Bottom = -1
+        [Dwm]::DwmExtendFrameIntoClientArea($hwnd, [ref]$margins) | Out-Null
+        $dark = 1
+        [Dwm]::DwmSetWindowAttribute($hwnd, 20, [ref]$dark, 4) | Out-Null
+        $backdrop = BACKDROP
+        if ([Environment]::OSVersion.Version.Build -ge ACRYLIC_BUILD) {
+            [Dwm]::DwmSetWindowAttribute($hwnd, 38, [ref]$backdrop, 4) | Out-Null
+        } else {
+            $mica = 1
+            [Dwm]::DwmSetWindowAttribute($hwnd, 1029, [ref]$mica, 4) | Out-Null
+        }
+        break
+    }
+    Start-Sleep -Milliseconds 500
+}
+`
+
+// startWindowEffect applies backdrop in "window_effect" to Spotify window
+// once it shows up. It runs in background, so launching is not delayed.
+// Backdrop only lasts as long as the window, Spotify started without
+// spicetify does not get it.
+func startWindowEffect() {
+	effect := windowEffect(false)
+	if len(effect) == 0 {
+		return
+	}
+
+	// DWMSBT_MAINWINDOW is Mica, DWMSBT_TRANSIENTWINDOW is Acrylic.
+	backdrop := "2"
+	if effect == "acrylic" {
+		backdrop = "3"
+	}
+
+	script := strings.NewReplacer(
+		"BACKDROP", backdrop,
+		"ACRYLIC_BUILD", strconv.Itoa(acrylicBuild),
+	).Replace(windowEffectScript)
+
+	ps, _ := exec.LookPath("powershell.exe")
+	exec.Command(ps, "-NoProfile", "-NonInteractive", "-Command", script).Start()
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	backupstatus "github.com/khanhas/spicetify-cli/src/status/backup"
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// IsFreshConfig reports whether config file was just generated, i.e. it's
+// the first run of spicetify.
+func IsFreshConfig() bool {
+	return freshConfig
+}
+
+// Wizard walks user through first-time setup: confirms detected paths,
+// picks theme and color scheme, toggles common options, then backs up and
+// applies. It returns whether apply was run.
+func Wizard(spicetifyVersion string) (bool, error) {
+	utils.PrintBold("Spotify location:")
+	InitPaths()
+	log.Println("    Spotify: " + spotifyPath)
+	log.Println("    Prefs:   " + prefsPath)
+	if !ReadAnswer("Are these correct? [Y/n]: ", true, true) {
+		wizardPath("spotify_path", "Spotify folder", spotifyPath)
+		wizardPath("prefs_path", `"prefs" file`, prefsPath)
+		InitPaths()
+	}
+
+	utils.PrintBold("Theme:")
+	if err := wizardTheme(); err != nil {
+		return false, err
+	}
+
+	utils.PrintBold("Options:")
+	wizardToggle("inject_css", "Inject theme CSS?")
+	wizardToggle("replace_colors", "Replace colors with theme color scheme?")
+	wizardToggle("check_spicetify_upgrade", "Check for spicetify upgrades?")
+	cfg.Write()
+
+	backupVersion := backupSection.Key("version").MustString("")
+	if !backupstatus.Get(prefsPath, backupFolder, backupVersion).IsBackuped() {
+		if !ReadAnswer("Back up Spotify and apply now? [Y/n]: ", true, false) {
+			utils.PrintInfo(`Setup is done. Run "spicetify backup apply" when you are ready.`)
+			return false, nil
+		}
+		Backup(spicetifyVersion)
+	} else if !ReadAnswer("Apply now? [Y/n]: ", true, false) {
+		utils.PrintInfo(`Setup is done. Run "spicetify apply" when you are ready.`)
+		return false, nil
+	}
+
+	return true, Apply(spicetifyVersion)
+}
+
+// wizardPath asks for a new value of path config `key`, repeating until
+// the path exists.
+func wizardPath(key, name, current string) {
+	for {
+		path := readLine("Enter "+name+" path (blank to keep "+current+"): ", current)
+		if _, err := os.Stat(path); err != nil {
+			utils.PrintError(path + " does not exist.")
+			continue
+		}
+
+		settingSection.Key(key).SetValue(path)
+		cfg.Write()
+		return
+	}
+}
+
+// wizardTheme lets user pick an installed theme, then one of its color
+// schemes.
+func wizardTheme() error {
+	themes := listThemes()
+	if len(themes) == 0 {
+		utils.PrintInfo("No theme is installed. Put themes in " + userThemesFolder + ` then run "spicetify theme use <name>".`)
+		settingSection.Key("current_theme").SetValue("")
+		return nil
+	}
+
+	current := strings.ToLower(settingSection.Key("current_theme").String())
+	defaultIndex := len(themes)
+	for i, theme := range themes {
+		log.Printf("    %d. %s\n", i+1, theme.name)
+		if strings.ToLower(theme.name) == current {
+			defaultIndex = i
+		}
+	}
+	log.Printf("    %d. No theme\n", len(themes)+1)
+
+	index := readChoice(len(themes)+1, defaultIndex)
+	if index == len(themes) {
+		settingSection.Key("current_theme").SetValue("")
+		settingSection.Key("theme_source").SetValue("")
+		settingSection.Key("color_scheme").SetValue("")
+		cfg.Write()
+		return nil
+	}
+
+	theme := themes[index]
+	scheme := ""
+	if len(theme.schemes) > 1 {
+		utils.PrintBold("Color scheme:")
+		for i, name := range theme.schemes {
+			log.Printf("    %d. %s\n", i+1, name)
+		}
+		scheme = theme.schemes[readChoice(len(theme.schemes), 0)]
+	}
+
+	return ThemeUse(theme.name, scheme)
+}
+
+func wizardToggle(key, question string) {
+	current := settingSection.Key(key).MustBool(false)
+	info := question + " [y/N]: "
+	if current {
+		info = question + " [Y/n]: "
+	}
+
+	value := "0"
+	if ReadAnswer(info, current, current) {
+		value = "1"
+	}
+	settingSection.Key(key).SetValue(value)
+}
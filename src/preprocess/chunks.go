@@ -0,0 +1,250 @@
+package preprocess
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/khanhas/spicetify-cli/src/utils"
+)
+
+// ChunkPatch is a modification looked up in every JS chunk of xpui, so it
+// keeps working when Spotify moves code between hashed chunk files.
+type ChunkPatch struct {
+	Name string
+	// Modify returns patched content, or content as is when patch target
+	// is not in it.
+	Modify func(content string) string
+	// Once stops patch after the first chunk it changes.
+	Once bool
+	// File is base name of chunk patch target is expected in. It is
+	// patched first, other chunks only when target is not found in it.
+	File string
+}
+
+// Chunk is a JS chunk of xpui and its content.
+type Chunk struct {
+	Name    string
+	Content string
+}
+
+// ReplacePatch creates a ChunkPatch replacing all matches of regexp `find`
+// with `repl`.
+func ReplacePatch(name, find, repl string) ChunkPatch {
+	return ChunkPatch{
+		Name: name,
+		Modify: func(content string) string {
+			utils.Replace(&content, find, repl)
+			return content
+		},
+	}
+}
+
+// jsChunks returns JS files of app in `appPath`, sorted so that entry
+// files xpui.js and vendor~xpui.js come first. Files injected by
+// spicetify are skipped.
+func jsChunks(appPath string) []string {
+	var chunks []string
+	filepath.Walk(appPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if path != appPath && (info.Name() == "helper" || info.Name() == "extensions") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) == ".js" {
+			chunks = append(chunks, path)
+		}
+		return nil
+	})
+
+	rank := func(path string) int {
+		switch filepath.Base(path) {
+		case "xpui.js":
+			return 0
+		case "vendor~xpui.js":
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return rank(chunks[i]) < rank(chunks[j])
+	})
+
+	return chunks
+}
+
+// PatchChunks applies `patches` to every JS chunk in `appPath` and returns
+// names of patches whose targets are found in no chunk.
+func PatchChunks(appPath string, patches []ChunkPatch) []string {
+	var chunks, originals []Chunk
+	for _, file := range jsChunks(appPath) {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		chunks = append(chunks, Chunk{file, string(raw)})
+	}
+	originals = append(originals, chunks...)
+
+	missed := ApplyPatches(chunks, patches)
+
+	for i, chunk := range chunks {
+		if chunk.Content != originals[i].Content {
+			ioutil.WriteFile(chunk.Name, []byte(chunk.Content), 0700)
+		}
+	}
+
+	return missed
+}
+
+// ApplyPatches applies `patches` in order to `chunks` in place and returns
+// names of patches whose targets are found in no chunk.
+func ApplyPatches(chunks []Chunk, patches []ChunkPatch) []string {
+	var missed []string
+	for _, patch := range patches {
+		matched := false
+		apply := func(chunk *Chunk) {
+			if patched := patch.Modify(chunk.Content); patched != chunk.Content {
+				chunk.Content = patched
+				matched = true
+			}
+		}
+
+		if len(patch.File) > 0 {
+			for i := range chunks {
+				if chunkBase(chunks[i].Name) == patch.File {
+					apply(&chunks[i])
+				}
+			}
+		}
+
+		// Target found in File is not looked for anywhere else.
+		if !matched {
+			for i := range chunks {
+				if patch.Once && matched {
+					break
+				}
+				if len(patch.File) > 0 && chunkBase(chunks[i].Name) == patch.File {
+					continue
+				}
+				apply(&chunks[i])
+			}
+		}
+
+		if !matched {
+			missed = append(missed, patch.Name)
+		}
+	}
+
+	return missed
+}
+
+// chunkBase returns file name of chunk `name`, which is a file path or a
+// slash separated path in archive.
+func chunkBase(name string) string {
+	return path.Base(filepath.ToSlash(name))
+}
+
+// integritySnapshot maps asset paths to their content, to find out which
+// ones are modified later.
+type integritySnapshot map[string][]byte
+
+// snapshotIntegrity records JS and CSS assets of app in `appPath`.
+func snapshotIntegrity(appPath string) integritySnapshot {
+	snapshot := integritySnapshot{}
+	filepath.Walk(appPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		if ext := filepath.Ext(path); ext == ".js" || ext == ".css" {
+			if content, err := ioutil.ReadFile(path); err == nil {
+				snapshot[path] = content
+			}
+		}
+		return nil
+	})
+
+	return snapshot
+}
+
+// fixIntegrity updates Subresource Integrity hashes, like
+// integrity="sha384-..." attributes and chunk hash maps in webpack runtime,
+// of assets modified since `snapshot`. Otherwise, browser refuses to load
+// modified chunks. Updating a hash map changes the chunk holding it, so
+// this repeats a few times until nothing changes.
+func fixIntegrity(appPath string, snapshot integritySnapshot) {
+	for pass := 0; pass < 3; pass++ {
+		replacer := []string{}
+		for path, original := range snapshot {
+			current, err := ioutil.ReadFile(path)
+			if err != nil || string(current) == string(original) {
+				continue
+			}
+
+			for prefix, newHash := range map[string]func() hash.Hash{
+				"sha256-": sha256.New,
+				"sha384-": sha512.New384,
+				"sha512-": sha512.New,
+			} {
+				replacer = append(replacer,
+					prefix+sriDigest(newHash(), original),
+					prefix+sriDigest(newHash(), current))
+			}
+		}
+
+		if len(replacer) == 0 {
+			return
+		}
+
+		snapshot = snapshotIntegrity(appPath)
+		r := strings.NewReplacer(replacer...)
+		filepath.Walk(appPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+
+			switch filepath.Ext(path) {
+			case ".html", ".js", ".json":
+				content, err := ioutil.ReadFile(path)
+				if err != nil {
+					return nil
+				}
+
+				if fixed := r.Replace(string(content)); fixed != string(content) {
+					ioutil.WriteFile(path, []byte(fixed), 0700)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func sriDigest(h hash.Hash, content []byte) string {
+	h.Write(content)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// BuiltinPatches returns built-in JS patches, named after their
+// "Preprocesses" config keys, so patch authors can test them against new
+// Spotify builds. Parts of "expose_apis" are named like
+// "expose_apis_main.player".
+func BuiltinPatches() []ChunkPatch {
+	return append([]ChunkPatch{
+		{Name: "disable_sentry", Modify: disableSentry},
+		{Name: "disable_ui_logging", Modify: disableLogging},
+	}, exposeAPIsPatches()...)
+}
@@ -0,0 +1,89 @@
+package preprocess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		chunks     []Chunk
+		patches    []ChunkPatch
+		want       []string
+		wantMissed []string
+	}{
+		{
+			name:    "every chunk",
+			chunks:  []Chunk{{"a.js", "foo"}, {"b.js", "foo foo"}},
+			patches: []ChunkPatch{ReplacePatch("p", "foo", "bar")},
+			want:    []string{"bar", "bar bar"},
+		},
+		{
+			name:    "once",
+			chunks:  []Chunk{{"a.js", "x"}, {"b.js", "foo"}, {"c.js", "foo"}},
+			patches: []ChunkPatch{{Name: "p", Modify: replaceAll("foo", "bar"), Once: true}},
+			want:    []string{"x", "bar", "foo"},
+		},
+		{
+			name:   "file is patched first",
+			chunks: []Chunk{{"a.js", "foo"}, {"xpui/xpui.js", "foo"}},
+			patches: []ChunkPatch{
+				{Name: "p", Modify: replaceAll("foo", "bar"), Once: true, File: "xpui.js"},
+			},
+			want: []string{"foo", "bar"},
+		},
+		{
+			name:   "file without target falls back to other chunks",
+			chunks: []Chunk{{"xpui.js", "x"}, {"a.js", "foo"}, {"b.js", "foo"}},
+			patches: []ChunkPatch{
+				{Name: "p", Modify: replaceAll("foo", "bar"), File: "xpui.js"},
+			},
+			want: []string{"x", "bar", "bar"},
+		},
+		{
+			name:   "patches apply in order",
+			chunks: []Chunk{{"a.js", "foo"}},
+			patches: []ChunkPatch{
+				ReplacePatch("first", "foo", "bar"),
+				ReplacePatch("second", "bar", "baz"),
+			},
+			want: []string{"baz"},
+		},
+		{
+			name:       "missed patches",
+			chunks:     []Chunk{{"a.js", "foo"}},
+			patches:    []ChunkPatch{ReplacePatch("hit", "foo", "bar"), ReplacePatch("miss", "nothing", "x")},
+			want:       []string{"bar"},
+			wantMissed: []string{"miss"},
+		},
+		{
+			name:       "replacing with same content is a miss",
+			chunks:     []Chunk{{"a.js", "foo"}},
+			patches:    []ChunkPatch{ReplacePatch("same", "foo", "foo")},
+			want:       []string{"foo"},
+			wantMissed: []string{"same"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missed := ApplyPatches(tt.chunks, tt.patches)
+
+			var got []string
+			for _, chunk := range tt.chunks {
+				got = append(got, chunk.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(missed, tt.wantMissed) {
+				t.Errorf("got missed %q, want %q", missed, tt.wantMissed)
+			}
+		})
+	}
+}
+
+func replaceAll(find, repl string) func(string) string {
+	return ReplacePatch("", find, repl).Modify
+}
@@ -3,11 +3,11 @@ package preprocess
 import (
 	"encoding/json"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -26,6 +26,9 @@ type Flag struct {
 	ExposeAPIs bool
 	// DisableUpgrade stops Spotify to display new version upgrade notification
 	DisableUpgrade bool
+	// LocalCSSMap uses css-map.json next to executable instead of fetching
+	// the latest one, so output only depends on local files.
+	LocalCSSMap bool
 }
 
 type jsMap struct {
@@ -36,13 +39,20 @@ type jsMap struct {
 // Start preprocessing apps assets in extractedAppPath
 func Start(extractedAppsPath string, flags Flag) {
 	appPath := filepath.Join(extractedAppsPath, "xpui")
+	snapshot := snapshotIntegrity(appPath)
 	var cssTranslationMap = make(map[string]string)
 	// readSourceMapAndGenerateCSSMap(appPath)
 
 	var cssMapURL string = "https://raw.githubusercontent.com/khanhas/spicetify-cli/master/css-map.json"
-	cssMapResp, err := http.Get(cssMapURL)
-	if err != nil {
-		utils.PrintInfo("Cannot fetch remote CSS map. Using local CSS map instead...")
+	var cssMapContent []byte
+	var err error
+	if !flags.LocalCSSMap {
+		cssMapContent, err = utils.FetchCached(cssMapURL, utils.FetchCacheTTL)
+		if err != nil {
+			utils.PrintInfo("Cannot fetch remote CSS map. Using local CSS map instead...")
+		}
+	}
+	if flags.LocalCSSMap || err != nil {
 		cssMapLocalPath := path.Join(utils.GetExecutableDir(), "css-map.json")
 		cssMapContent, err := os.ReadFile(cssMapLocalPath)
 		if err != nil {
@@ -54,12 +64,19 @@ func Start(extractedAppsPath string, flags Flag) {
 			}
 		}
 	} else {
-		err := json.NewDecoder(cssMapResp.Body).Decode(&cssTranslationMap)
+		err := json.Unmarshal(cssMapContent, &cssTranslationMap)
 		if err != nil {
 			utils.PrintWarning("Remote CSS map JSON malformed.")
 		}
 	}
 
+	// Replaced in fixed order, so output is the same on every run.
+	cssMapKeys := make([]string, 0, len(cssTranslationMap))
+	for k := range cssTranslationMap {
+		cssMapKeys = append(cssMapKeys, k)
+	}
+	sort.Strings(cssMapKeys)
+
 	filepath.Walk(appPath, func(path string, info os.FileInfo, err error) error {
 		fileName := info.Name()
 		extension := filepath.Ext(fileName)
@@ -78,24 +95,16 @@ func Start(extractedAppsPath string, flags Flag) {
 				// 		if flags.DisableUpgrade {
 				// 			content = disableUpgradeCheck(content, appName)
 				// 		}
-				if flags.ExposeAPIs {
-					switch fileName {
-					case "xpui.js":
-						content = exposeAPIs_main(content)
-					case "vendor~xpui.js":
-						content = exposeAPIs_vendor(content)
-					}
-				}
-				for k, v := range cssTranslationMap {
-					utils.Replace(&content, k, v)
+				for _, k := range cssMapKeys {
+					utils.Replace(&content, k, cssTranslationMap[k])
 				}
 				content = colorVariableReplaceForJS(content)
 				return content
 			})
 		case ".css":
 			utils.ModifyFile(path, func(content string) string {
-				for k, v := range cssTranslationMap {
-					utils.Replace(&content, k, v)
+				for _, k := range cssMapKeys {
+					utils.Replace(&content, k, cssTranslationMap[k])
 				}
 				if flags.RemoveRTL {
 					content = removeRTL(content)
@@ -130,6 +139,16 @@ func Start(extractedAppsPath string, flags Flag) {
 		return nil
 	})
 
+	// Newer builds split xpui into many chunks, APIs are looked up in all
+	// of them instead of only entry files.
+	if flags.ExposeAPIs {
+		missed := PatchChunks(appPath, exposeAPIsPatches())
+		for _, name := range missed {
+			utils.PrintWarning(`Cannot apply "` + name + `": no matching code in any xpui chunk.`)
+		}
+	}
+
+	fixIntegrity(appPath, snapshot)
 	fakeZLink(filepath.Join(extractedAppsPath, "zlink"))
 }
 
@@ -137,12 +156,14 @@ func Start(extractedAppsPath string, flags Flag) {
 // all colors value with CSS variables.
 func StartCSS(extractedAppsPath string) {
 	appPath := filepath.Join(extractedAppsPath, "xpui")
+	snapshot := snapshotIntegrity(appPath)
 	filepath.Walk(appPath, func(path string, info os.FileInfo, err error) error {
 		if filepath.Ext(info.Name()) == ".css" {
 			utils.ModifyFile(path, colorVariableReplace)
 		}
 		return nil
 	})
+	fixIntegrity(appPath, snapshot)
 }
 
 func colorVariableReplace(content string) string {
@@ -227,47 +248,163 @@ func removeRTL(input string) string {
 	return input
 }
 
-func exposeAPIs_main(input string) string {
-	// Player
-	utils.Replace(
-		&input,
-		`this\._cosmos=(\w+),this\._defaultFeatureVersion=\w+`,
-		`(globalThis.Spicetify.Player.origin=this),${0}`)
-
-	utils.Replace(
-		&input,
-		`,this.player=\w+,`,
-		`,(globalThis.Spicetify.Player.origin2=this)${0}`)
-
-	// Show Notification
-	utils.Replace(
-		&input,
-		`,(\w+)=(\(\w+=\w+\.dispatch)`,
-		`;globalThis.Spicetify.showNotification=(message)=>${1}({message});const ${1}=${2}`)
-
-	// Remove list of exclusive shows
-	utils.Replace(
-		&input,
-		`\["spotify:show.+?\]`,
-		`[]`)
-
-	// Remove Star Wars easter eggs since it aggressively
-	// listens to keystroke, checking URIs at all time
-	utils.Replace(
-		&input,
-		`\w+\(\)\.createElement\(\w+,\{onChange:this\.handleSaberStateChange\}\),`,
-		"")
-
-	utils.Replace(
-		&input,
-		`;class \w+ extends (\w+)\(\).Component`,
-		`;Spicetify.React=${1}()${0}`)
-
-	utils.Replace(
-		&input,
-		`"data-testid":`,
-		`"":`)
+// exposeAPIsPatches returns patches exposing Spotify internals to
+// Spicetify. Each one has its own target in entry file xpui.js or
+// vendor~xpui.js and changes only the first chunk it matches, so broad
+// patterns never touch unrelated chunks.
+func exposeAPIsPatches() []ChunkPatch {
+	main := func(name, find, repl string) ChunkPatch {
+		patch := ReplacePatch("expose_apis_main."+name, find, repl)
+		patch.File, patch.Once = "xpui.js", true
+		return patch
+	}
+	vendor := func(name, find, repl string) ChunkPatch {
+		patch := ReplacePatch("expose_apis_vendor."+name, find, repl)
+		patch.File, patch.Once = "vendor~xpui.js", true
+		return patch
+	}
+
+	return []ChunkPatch{
+		// Player
+		main("player",
+			`this\._cosmos=(\w+),this\._defaultFeatureVersion=\w+`,
+			`(globalThis.Spicetify.Player.origin=this),${0}`),
+		main("player2",
+			`,this.player=\w+,`,
+			`,(globalThis.Spicetify.Player.origin2=this)${0}`),
+
+		// Show Notification
+		main("notification",
+			`,(\w+)=(\(\w+=\w+\.dispatch)`,
+			`;globalThis.Spicetify.showNotification=(message)=>${1}({message});const ${1}=${2}`),
+
+		// Remove list of exclusive shows
+		main("exclusive_shows",
+			`\["spotify:show.+?\]`,
+			`[]`),
+
+		// Remove Star Wars easter eggs since it aggressively
+		// listens to keystroke, checking URIs at all time
+		main("star_wars",
+			`\w+\(\)\.createElement\(\w+,\{onChange:this\.handleSaberStateChange\}\),`,
+			""),
+
+		main("react",
+			`;class \w+ extends (\w+)\(\).Component`,
+			`;Spicetify.React=${1}()${0}`),
+
+		main("test_id",
+			`"data-testid":`,
+			`"":`),
+
+		{
+			Name:   "expose_apis_main.platform",
+			Modify: exposePlatform,
+			Once:   true,
+			File:   "xpui.js",
+		},
+
+		// Profile Menu hook v1.1.56
+		main("profile_menu",
+			`\{listItems:\w+,icons:\w+,onOutsideClick:(\w+)\}=\w+;`,
+			`${0};
+Spicetify.React.useEffect(() => {
+	const container = document.querySelector(".main-userWidget-dropDownMenu")?.parentElement;
+	if (!container) {
+		console.error("Profile Menu Hook v1.1.56 failed");
+		return;
+	}
+	container._tippy = { props: { onClickOutside: ${1} }};
+	Spicetify.Menu._addItems(container);
+}, []);`),
+
+		// React Component: Context Menu and Right Click Menu
+		main("context_menu",
+			`(const \w+)(=\w+=>\w+\(\)\.createElement\(([\w\.]+),\w+\(\)\(\{\},\w+,\{action:"open",trigger:"right-click"\}\)\)\})`,
+			`Spicetify.ReactComponent.ContextMenu=${3};${1}=Spicetify.ReactComponent.RightClickMenu${2}`),
+
+		// React Component: Context Menu - Menu
+		main("menu",
+			`=\(\{children:\w+,onClose:\w+,getInitialFocusElement:\w+\}\)`,
+			`=Spicetify.ReactComponent.Menu${0}`),
+
+		// React Component: Context Menu - Menu Item
+		main("menu_item",
+			`=\w+=>\{let\{children:\w+,icon:\w+`,
+			`=Spicetify.ReactComponent.MenuItem${0}`),
+
+		// React Component: Album Context Menu items
+		main("album_menu",
+			`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,sharingInfo:\w+,onRemoveCallback:\w+\}\)=>\w+\(\)\.createElement\([\w\.]+,\{value:"album"\})`,
+			`${1}=Spicetify.ReactComponent.AlbumMenu${2}`),
+
+		// React Component: Show Context Menu items
+		main("show_menu",
+			`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,sharingInfo:\w+,onRemoveCallback:\w+\}\)=>\w+\(\)\.createElement\([\w\.]+,\{value:"show"\})`,
+			`${1}=Spicetify.ReactComponent.PodcastShowMenu${2}`),
+
+		// React Component: Artist Context Menu items
+		main("artist_menu",
+			`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,sharingInfo:\w+,onRemoveCallback:\w+\}\)=>\w+\(\)\.createElement\([\w\.]+,\{value:"artist"\})`,
+			`${1}=Spicetify.ReactComponent.ArtistMenu${2}`),
+
+		// React Component: Playlist Context Menu items
+		main("playlist_menu",
+			`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,onRemoveCallback:\w+\}\))`,
+			`${1}=Spicetify.ReactComponent.PlaylistMenu${2}`),
+
+		// Locale
+		main("locale",
+			`this\._dictionary=\{\},`,
+			`${0}Spicetify.Locale=this,`),
+
+		// URI
+		vendor("uri",
+			`,(\w+)\.prototype\.toAppType`,
+			`,(globalThis.Spicetify.URI=${1})${0}`),
+
+		// Mousetrap
+		vendor("mousetrap",
+			`,(\w+\.Mousetrap=(\w+))`,
+			`;Spicetify.Mousetrap=${2};${1}`),
+
+		// Context Menu hook
+		vendor("context_menu_hook",
+			`\w+\("onMount",\[(\w+)\]\)`,
+			`${0};
+if (${1}.popper?.firstChild?.id === "context-menu") {
+    const container = ${1}.popper.firstChild;
+	if (!container.children.length) {
+		const observer = new MutationObserver(() => {
+			Spicetify.ContextMenu._addItems(${1}.popper);
+			observer.disconnect();
+		});
+		observer.observe(container, { childList: true });
+    } else if (container.firstChild.classList.contains("main-userWidget-dropDownMenu")) {
+        Spicetify.Menu._addItems(${1}.popper);
+    } else {
+		Spicetify.ContextMenu._addItems(${1}.popper);
+	}
+};0`),
+
+		{
+			Name: "expose_apis_vendor.react_dom",
+			Modify: func(input string) string {
+				utils.ReplaceOnce(
+					&input,
+					`(\w+=)(\{createPortal:\w+)`,
+					`${1}Spicetify.ReactDOM=${2}`)
+				return input
+			},
+			Once: true,
+			File: "vendor~xpui.js",
+		},
+	}
+}
 
+// exposePlatform assigns Spotify platform APIs, awaited all together on
+// start up, to Spicetify.Platform.
+func exposePlatform(input string) string {
 	reAllAPIPromises := regexp.MustCompile(`await Promise.all\(\[([\w\(\)\.,]+?)\]\)([;,])`)
 	allAPIPromises := reAllAPIPromises.FindAllStringSubmatch(input, -1)
 	for _, found := range allAPIPromises {
@@ -295,110 +432,6 @@ func exposeAPIs_main(input string) string {
 		}
 	}
 
-	// Profile Menu hook v1.1.56
-	utils.Replace(
-		&input,
-		`\{listItems:\w+,icons:\w+,onOutsideClick:(\w+)\}=\w+;`,
-		`${0};
-Spicetify.React.useEffect(() => {
-	const container = document.querySelector(".main-userWidget-dropDownMenu")?.parentElement;
-	if (!container) {
-		console.error("Profile Menu Hook v1.1.56 failed");
-		return;
-	}
-	container._tippy = { props: { onClickOutside: ${1} }};
-	Spicetify.Menu._addItems(container);
-}, []);`)
-
-	// React Component: Context Menu and Right Click Menu
-	utils.Replace(
-		&input,
-		`(const \w+)(=\w+=>\w+\(\)\.createElement\(([\w\.]+),\w+\(\)\(\{\},\w+,\{action:"open",trigger:"right-click"\}\)\)\})`,
-		`Spicetify.ReactComponent.ContextMenu=${3};${1}=Spicetify.ReactComponent.RightClickMenu${2}`)
-
-	// React Component: Context Menu - Menu
-	utils.Replace(
-		&input,
-		`=\(\{children:\w+,onClose:\w+,getInitialFocusElement:\w+\}\)`,
-		`=Spicetify.ReactComponent.Menu${0}`)
-
-	// React Component: Context Menu - Menu Item
-	utils.Replace(
-		&input,
-		`=\w+=>\{let\{children:\w+,icon:\w+`,
-		`=Spicetify.ReactComponent.MenuItem${0}`)
-
-	// React Component: Album Context Menu items
-	utils.Replace(
-		&input,
-		`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,sharingInfo:\w+,onRemoveCallback:\w+\}\)=>\w+\(\)\.createElement\([\w\.]+,\{value:"album"\})`,
-		`${1}=Spicetify.ReactComponent.AlbumMenu${2}`)
-
-	// React Component: Show Context Menu items
-	utils.Replace(
-		&input,
-		`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,sharingInfo:\w+,onRemoveCallback:\w+\}\)=>\w+\(\)\.createElement\([\w\.]+,\{value:"show"\})`,
-		`${1}=Spicetify.ReactComponent.PodcastShowMenu${2}`)
-
-	// React Component: Artist Context Menu items
-	utils.Replace(
-		&input,
-		`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,sharingInfo:\w+,onRemoveCallback:\w+\}\)=>\w+\(\)\.createElement\([\w\.]+,\{value:"artist"\})`,
-		`${1}=Spicetify.ReactComponent.ArtistMenu${2}`)
-
-	// React Component: Playlist Context Menu items
-	utils.Replace(
-		&input,
-		`(const \w+)(=\w+\(\)\.memo\(\(\(\{uri:\w+,onRemoveCallback:\w+\}\))`,
-		`${1}=Spicetify.ReactComponent.PlaylistMenu${2}`)
-
-	// Locale
-	utils.Replace(
-		&input,
-		`this\._dictionary=\{\},`,
-		`${0}Spicetify.Locale=this,`)
-
-	return input
-}
-
-func exposeAPIs_vendor(input string) string {
-	// URI
-	utils.Replace(
-		&input,
-		`,(\w+)\.prototype\.toAppType`,
-		`,(globalThis.Spicetify.URI=${1})${0}`)
-
-	// Mousetrap
-	utils.Replace(
-		&input,
-		`,(\w+\.Mousetrap=(\w+))`,
-		`;Spicetify.Mousetrap=${2};${1}`)
-
-	// Context Menu hook
-	utils.Replace(
-		&input,
-		`\w+\("onMount",\[(\w+)\]\)`,
-		`${0};
-if (${1}.popper?.firstChild?.id === "context-menu") {
-    const container = ${1}.popper.firstChild;
-	if (!container.children.length) {
-		const observer = new MutationObserver(() => {
-			Spicetify.ContextMenu._addItems(${1}.popper);
-			observer.disconnect();
-		});
-		observer.observe(container, { childList: true });
-    } else if (container.firstChild.classList.contains("main-userWidget-dropDownMenu")) {
-        Spicetify.Menu._addItems(${1}.popper);
-    } else {
-		Spicetify.ContextMenu._addItems(${1}.popper);
-	}
-};0`)
-
-	utils.ReplaceOnce(
-		&input,
-		`(\w+=)(\{createPortal:\w+)`,
-		`${1}Spicetify.ReactDOM=${2}`)
-
 	return input
 }
 
@@ -1,10 +1,7 @@
 package backupstatus
 
 import (
-	"io/ioutil"
-	"log"
-	"strings"
-
+	"github.com/khanhas/spicetify-cli/src/backup"
 	"github.com/khanhas/spicetify-cli/src/utils"
 )
 
@@ -30,29 +27,15 @@ const (
 
 // Get returns status of backup folder
 func Get(prefsPath, backupPath, backupVersion string) Status {
-	fileList, err := ioutil.ReadDir(backupPath)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	cur := EMPTY
 
-	if len(fileList) != 0 {
-		spaCount := 0
-		for _, file := range fileList {
-			if !file.IsDir() && strings.HasSuffix(file.Name(), ".spa") {
-				spaCount++
-			}
-		}
-
-		if spaCount > 0 {
-			spotifyVersion := utils.GetSpotifyVersion(prefsPath)
+	if len(backupVersion) > 0 && backup.Exists(backupPath, backupVersion) {
+		spotifyVersion := utils.GetSpotifyVersion(prefsPath)
 
-			if backupVersion != spotifyVersion {
-				cur = OUTDATED
-			} else {
-				cur = BACKUPED
-			}
+		if backupVersion != spotifyVersion {
+			cur = OUTDATED
+		} else {
+			cur = BACKUPED
 		}
 	}
 
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-ini/ini"
+)
+
+// configMigration is a change of config layout between spicetify versions.
+// Key `key` in `section` is moved to `toKey` in `toSection`, or removed when
+// `toKey` is blank. Blank `toSection` keeps the same section.
+type configMigration struct {
+	section   string
+	key       string
+	toSection string
+	toKey     string
+}
+
+// configMigrations lists layout changes, oldest first. Configs that still
+// have old keys are migrated when they are read.
+var configMigrations = []configMigration{
+	// 2.0: Spotify client moved to xpui, features of old client are gone.
+	{section: "AdditionalOptions", key: "experimental_features"},
+	{section: "AdditionalOptions", key: "fastUser_switching"},
+	{section: "AdditionalOptions", key: "home"},
+	{section: "AdditionalOptions", key: "lyric_always_show"},
+	{section: "AdditionalOptions", key: "lyric_force_no_sync"},
+	{section: "AdditionalOptions", key: "made_for_you_hub"},
+	{section: "AdditionalOptions", key: "minimal_ui"},
+	{section: "AdditionalOptions", key: "new_feedback_ui"},
+	{section: "AdditionalOptions", key: "radio"},
+	{section: "AdditionalOptions", key: "search_in_sidebar"},
+	{section: "AdditionalOptions", key: "song_page"},
+	{section: "AdditionalOptions", key: "tastebuds"},
+	{section: "AdditionalOptions", key: "visualization_high_framerate"},
+	{section: "AdditionalOptions", key: "wheel_search"},
+}
+
+// LegacyConfigPath returns path of config file used before xpui next to
+// `configPath`, or blank when `configPath` is not the default one.
+func LegacyConfigPath(configPath string) string {
+	if filepath.Base(configPath) != "config-xpui.ini" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), "config.ini")
+}
+
+// loadLegacyConfig reads config of spicetify before xpui to be migrated
+// when `configPath` doesn't exist yet.
+func loadLegacyConfig(configPath string) (*ini.File, error) {
+	legacyPath := LegacyConfigPath(configPath)
+	if len(legacyPath) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	cfg, err := loadConfigFile(legacyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := cfg.GetSection("Setting"); err != nil {
+		return nil, err
+	}
+
+	// Backup of old client can't be restored by this version.
+	cfg.DeleteSection("Backup")
+	backup, _ := cfg.NewSection("Backup")
+	backup.Comment = "DO NOT CHANGE!"
+	backup.NewKey("version", "")
+	backup.NewKey("with", "")
+
+	return cfg, nil
+}
+
+// needsMigration reports whether cfg has keys of an old layout.
+func needsMigration(cfg *ini.File) bool {
+	for _, migration := range configMigrations {
+		if section, err := cfg.GetSection(migration.section); err == nil && section.HasKey(migration.key) {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateConfig applies configMigrations to cfg and reports whether any is
+// applied. Values of moved keys are kept unless new key is already set.
+func migrateConfig(cfg *ini.File) bool {
+	changed := false
+	for _, migration := range configMigrations {
+		section, err := cfg.GetSection(migration.section)
+		if err != nil {
+			continue
+		}
+		key, err := section.GetKey(migration.key)
+		if err != nil {
+			continue
+		}
+
+		name := migration.section + "." + migration.key
+		if len(migration.toKey) == 0 {
+			PrintInfo(`Config "` + name + `" is removed.`)
+		} else {
+			toSectionName := migration.toSection
+			if len(toSectionName) == 0 {
+				toSectionName = migration.section
+			}
+			toSection, err := cfg.GetSection(toSectionName)
+			if err != nil {
+				toSection, _ = cfg.NewSection(toSectionName)
+			}
+			if !toSection.HasKey(migration.toKey) {
+				toSection.NewKey(migration.toKey, key.Value())
+			}
+			PrintInfo(`Config "` + name + `" is moved to "` + toSectionName + "." + migration.toKey + `".`)
+		}
+
+		section.DeleteKey(migration.key)
+		changed = true
+	}
+
+	return changed
+}
+
+// backupConfig copies config file before it is migrated, so settings are
+// not lost if migration goes wrong.
+func backupConfig(configPath string) (string, error) {
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	backupPath := configPath + "." + time.Now().Format("20060102-150405") + ".bak"
+	return backupPath, ioutil.WriteFile(backupPath, content, 0644)
+}
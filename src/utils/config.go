@@ -17,11 +17,26 @@ var (
 			"prefs_path":              "",
 			"current_theme":           "SpicetifyDefault",
 			"color_scheme":            "",
+			"theme_source":            "",
+			"theme_paths":             "",
+			"download_mirror":         "",
 			"inject_css":              "1",
 			"replace_colors":          "1",
 			"overwrite_assets":        "0",
 			"spotify_launch_flags":    "",
 			"check_spicetify_upgrade": "0",
+			"update_channel":          "stable",
+			"rotate_schemes":          "",
+			"rotate_list":             "",
+			"debug_port":              "9222",
+			"debug_host":              "",
+			"debug_expose":            "0",
+			"debug_timeout":           "5s",
+			"debug_retries":           "5",
+			"debug_backoff":           "500ms",
+			"cache_path":              "",
+			"kiosk_mode":              "0",
+			"max_backups":             "0",
 		},
 		"Preprocesses": {
 			"disable_sentry":        "1",
@@ -35,16 +50,27 @@ var (
 			"custom_apps":    "",
 			"sidebar_config": "1",
 			"home_config":    "1",
+			"js_snippets":    "",
+			"window_effect":  "",
+			"rtl":            "0",
+			"ui_scale":       "",
 		},
-		"Patch": {},
+		"Patch":      {},
+		"JsSnippets": {},
 	}
 )
 
 type config struct {
 	path    string
 	content *ini.File
+	// base holds values as last read from or written to file, to tell
+	// which keys other processes changed in the meantime.
+	base configValues
 }
 
+// configValues maps section name to its keys and values.
+type configValues map[string]map[string]string
+
 // Config .
 type Config interface {
 	Write()
@@ -55,51 +81,215 @@ type Config interface {
 // ParseConfig read config file content, return default config
 // if file doesn't exist.
 func ParseConfig(configPath string) Config {
-	cfg, err := ini.LoadSources(
-		ini.LoadOptions{
-			IgnoreContinuation: true,
-		},
-		configPath)
+	cfg, err := loadConfigFile(configPath)
 
 	if err != nil {
+		if legacy, err := loadLegacyConfig(configPath); err == nil {
+			migrateConfig(legacy)
+			fillConfigLayout(legacy)
+			c := config{
+				path:    configPath,
+				content: legacy,
+				base:    configValues{},
+			}
+			c.Write()
+			PrintSuccess("Config is migrated from " + LegacyConfigPath(configPath) + ".")
+			return c
+		}
+
 		defaultConfig := config{
 			path:    configPath,
 			content: getDefaultConfig(),
+			base:    configValues{},
 		}
 		defaultConfig.Write()
 		PrintSuccess("Default config-xpui.ini generated.")
 		return defaultConfig
 	}
 
-	needRewrite := false
+	c := config{
+		path:    configPath,
+		content: cfg,
+		base:    configValues{},
+	}
+	c.base.take(cfg)
+
+	// Old file is backed up before it's changed, as migration drops keys.
+	if needsMigration(cfg) {
+		backupPath, err := backupConfig(configPath)
+		if err != nil {
+			PrintWarningOf("Cannot back up config file, keeping old layout: ", err)
+		} else {
+			PrintInfo("Config layout is outdated. Original is backed up to " + backupPath)
+			migrateConfig(cfg)
+			fillConfigLayout(cfg)
+			PrintSuccess("Config is migrated.")
+			c.Write()
+			return c
+		}
+	}
+
+	if fillConfigLayout(cfg) {
+		PrintSuccess("Config is updated.")
+		c.Write()
+	}
+
+	return c
+}
+
+func loadConfigFile(configPath string) (*ini.File, error) {
+	return ini.LoadSources(
+		ini.LoadOptions{
+			IgnoreContinuation: true,
+		},
+		configPath)
+}
+
+// ParseConfigContent parses config from `content`, which has no file to
+// be written back to. Changes made to returned config are not saved.
+func ParseConfigContent(content []byte) (Config, error) {
+	cfg, err := ini.LoadSources(
+		ini.LoadOptions{
+			IgnoreContinuation: true,
+		},
+		content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	migrateConfig(cfg)
+	fillConfigLayout(cfg)
+
+	return config{
+		content: cfg,
+	}, nil
+}
+
+// fillConfigLayout adds sections and keys missing in cfg with their
+// default values and reports whether any is added.
+func fillConfigLayout(cfg *ini.File) bool {
+	changed := false
 	for sectionName, keyList := range configLayout {
 		section, err := cfg.GetSection(sectionName)
 		if err != nil {
 			section, _ = cfg.NewSection(sectionName)
-			needRewrite = true
+			changed = true
 		}
 		for keyName, defaultValue := range keyList {
 			if _, err := section.GetKey(keyName); err != nil {
 				section.NewKey(keyName, defaultValue)
-				needRewrite = true
+				changed = true
 			}
 		}
 	}
 
-	if needRewrite {
-		PrintSuccess("Config is updated.")
-		cfg.SaveTo(configPath)
+	return changed
+}
+
+// Write writes content to config file.
+// Config without a file, e.g. read from stdin, is not written.
+// Other spicetify processes, like watch mode, can write the file at the
+// same time, so it's locked while written, and keys they changed since it
+// was read are merged in unless this process changed them too.
+func (c config) Write() {
+	if len(c.path) == 0 {
+		PrintWarning("Config is not read from a file. Changes are not saved.")
+		return
 	}
 
-	return config{
-		path:    configPath,
-		content: cfg,
+	unlock, err := LockFile(c.path)
+	if err != nil {
+		PrintWarningOf("Cannot lock config file: ", err)
+	} else {
+		defer unlock()
 	}
+
+	if disk, err := loadConfigFile(c.path); err == nil {
+		c.merge(disk)
+	}
+
+	// Written to a temporary file first, so readers never see it half
+	// written.
+	temp := c.path + ".tmp"
+	if err := c.content.SaveTo(temp); err != nil {
+		PrintWarningOf("Cannot write config file: ", err)
+		return
+	}
+	if err := RetryLocked(func() error { return os.Rename(temp, c.path) }); err != nil {
+		os.Remove(temp)
+		PrintWarningOf("Cannot write config file: ", err)
+		return
+	}
+
+	c.base.take(c.content)
 }
 
-// Write writes content to config file.
-func (c config) Write() {
-	c.content.SaveTo(c.path)
+// merge applies changes made in `disk` since config was read to keys this
+// process hasn't changed.
+func (c config) merge(disk *ini.File) {
+	diskValues := configValues{}
+	diskValues.take(disk)
+
+	for sectionName, keys := range diskValues {
+		for keyName, value := range keys {
+			baseValue, inBase := c.base[sectionName][keyName]
+			if inBase && baseValue == value {
+				continue
+			}
+
+			current, inCurrent := c.value(sectionName, keyName)
+			if inCurrent == inBase && current == baseValue {
+				section, err := c.content.GetSection(sectionName)
+				if err != nil {
+					section, _ = c.content.NewSection(sectionName)
+				}
+				section.Key(keyName).SetValue(value)
+			}
+		}
+	}
+
+	for sectionName, keys := range c.base {
+		for keyName, baseValue := range keys {
+			if _, onDisk := diskValues[sectionName][keyName]; onDisk {
+				continue
+			}
+
+			if current, inCurrent := c.value(sectionName, keyName); inCurrent && current == baseValue {
+				section, _ := c.content.GetSection(sectionName)
+				section.DeleteKey(keyName)
+			}
+		}
+	}
+}
+
+func (c config) value(sectionName, keyName string) (string, bool) {
+	section, err := c.content.GetSection(sectionName)
+	if err != nil {
+		return "", false
+	}
+
+	key, err := section.GetKey(keyName)
+	if err != nil {
+		return "", false
+	}
+
+	return key.Value(), true
+}
+
+// take replaces values with ones in `file`.
+func (values configValues) take(file *ini.File) {
+	for name := range values {
+		delete(values, name)
+	}
+
+	for _, section := range file.Sections() {
+		keys := map[string]string{}
+		for _, key := range section.Keys() {
+			keys[key.Name()] = key.Value()
+		}
+		values[section.Name()] = keys
+	}
 }
 
 func (c config) GetSection(name string) *ini.Section {
@@ -122,19 +312,21 @@ func (c config) GetPath() string {
 func getDefaultConfig() *ini.File {
 	var cfg = ini.Empty()
 
-	spotifyPath := FindAppPath()
-	prefsFilePath := FindPrefFilePath()
+	// With multiple candidates, paths are left blank so user can pick one
+	// when they are needed.
+	spotifyPaths := FindAppPaths()
+	prefsFilePaths := FindPrefFilePaths()
 
-	if len(spotifyPath) == 0 {
+	if len(spotifyPaths) == 0 {
 		PrintError("Could not detect Spotify location.")
-	} else {
-		configLayout["Setting"]["spotify_path"] = spotifyPath
+	} else if len(spotifyPaths) == 1 {
+		configLayout["Setting"]["spotify_path"] = spotifyPaths[0]
 	}
 
-	if len(prefsFilePath) == 0 {
+	if len(prefsFilePaths) == 0 {
 		PrintError(`Could not detect "prefs" file location.`)
-	} else {
-		configLayout["Setting"]["prefs_path"] = prefsFilePath
+	} else if len(prefsFilePaths) == 1 {
+		configLayout["Setting"]["prefs_path"] = prefsFilePaths[0]
 	}
 
 	for sectionName, keyList := range configLayout {
@@ -161,101 +353,225 @@ func getDefaultConfig() *ini.File {
 // of each platform and returns it.
 // Returns blank string if none of default locations exists.
 func FindAppPath() string {
+	if paths := FindAppPaths(); len(paths) > 0 {
+		return paths[0]
+	}
+	return ""
+}
+
+// FindAppPaths returns all Spotify locations found in possible places of
+// each platform, most likely one first.
+func FindAppPaths() []string {
 	switch runtime.GOOS {
 	case "windows":
-		path := winApp()
-		if len(path) == 0 {
-			path = winXApp()
-		}
-		return path
+		return uniquePaths(append(append(winApps(), winManagedApps()...), winXApps()...))
 
 	case "linux":
-		return linuxApp()
+		return uniquePaths(linuxApps())
 
 	case "darwin":
-		return darwinApp()
+		return uniquePaths(darwinApps())
 	}
 
-	return ""
+	return nil
 }
 
 // FindPrefFilePath finds Spotify "prefs" file location
 // in various possible places of each platform and returns it.
 // Returns blank string if none of default locations exists.
 func FindPrefFilePath() string {
+	if paths := FindPrefFilePaths(); len(paths) > 0 {
+		return paths[0]
+	}
+	return ""
+}
+
+// FindPrefFilePaths returns all Spotify "prefs" files found in possible
+// places of each platform, most likely one first.
+func FindPrefFilePaths() []string {
 	switch runtime.GOOS {
 	case "windows":
-		path := winPrefs()
-		if len(path) == 0 {
-			path = winXPrefs()
-		}
-		return path
+		return uniquePaths(append(winPrefs(), winXPrefs()...))
 
 	case "linux":
-		return linuxPrefs()
+		return uniquePaths(linuxPrefs())
 
 	case "darwin":
-		return darwinPrefs()
+		return uniquePaths(darwinPrefs())
 	}
 
-	return ""
+	return nil
 }
 
-func winApp() string {
-	path := filepath.Join(os.Getenv("APPDATA"), "Spotify")
-	if _, err := os.Stat(path); err == nil {
-		return path
+// existingPaths returns paths in `list` that exist.
+func existingPaths(list ...string) []string {
+	var result []string
+	for _, path := range list {
+		if _, err := os.Stat(path); err == nil {
+			result = append(result, path)
+		}
 	}
+	return result
+}
 
-	return ""
+// uniquePaths removes duplicated paths, e.g. same folder reached through
+// a symlink, keeping first occurrence.
+func uniquePaths(list []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, path := range list {
+		key := filepath.Clean(path)
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, path)
+	}
+	return result
+}
+
+func winApps() []string {
+	return existingPaths(filepath.Join(os.Getenv("APPDATA"), "Spotify"))
 }
 
-func winPrefs() string {
-	path := filepath.Join(os.Getenv("APPDATA"), "Spotify", "prefs")
-	if _, err := os.Stat(path); err == nil {
-		return path
+// winManagedApps returns Spotify folders installed through package
+// managers: install location in registry, which covers winget and
+// Chocolatey installs in non-default roots, Scoop apps and shims, and
+// winget portable packages.
+func winManagedApps() []string {
+	var candidates []string
+
+	for _, root := range []string{"HKCU", "HKLM"} {
+		out, err := exec.Command("reg", "query",
+			root+`\Software\Microsoft\Windows\CurrentVersion\Uninstall\Spotify`,
+			"/v", "InstallLocation").Output()
+		if err != nil {
+			continue
+		}
+		// Value line is "    InstallLocation    REG_SZ    <path>".
+		for _, line := range strings.Split(string(out), "\n") {
+			if i := strings.Index(line, "REG_SZ"); i != -1 {
+				candidates = append(candidates, strings.Trim(strings.TrimSpace(line[i+len("REG_SZ"):]), `"`))
+			}
+		}
+	}
+
+	scoopRoots := []string{
+		os.Getenv("SCOOP"),
+		filepath.Join(os.Getenv("USERPROFILE"), "scoop"),
+		os.Getenv("SCOOP_GLOBAL"),
+		filepath.Join(os.Getenv("ProgramData"), "scoop"),
+	}
+	for _, root := range scoopRoots {
+		candidates = append(candidates, filepath.Join(root, "apps", "spotify", "current"))
 	}
 
-	return ""
+	// Scoop shim "spotify.exe" has a "spotify.shim" file next to it
+	// holding real executable path.
+	if shim, err := exec.LookPath("spotify.exe"); err == nil {
+		content, err := os.ReadFile(strings.TrimSuffix(shim, filepath.Ext(shim)) + ".shim")
+		if err != nil {
+			candidates = append(candidates, filepath.Dir(shim))
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if parts := strings.SplitN(line, "=", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == "path" {
+				candidates = append(candidates, filepath.Dir(strings.Trim(strings.TrimSpace(parts[1]), `"`)))
+			}
+		}
+	}
+
+	for _, root := range []string{
+		filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "WinGet", "Packages"),
+		filepath.Join(os.Getenv("ProgramFiles"), "WinGet", "Packages"),
+	} {
+		if matches, err := filepath.Glob(filepath.Join(root, "Spotify.Spotify_*")); err == nil {
+			candidates = append(candidates, matches...)
+		}
+	}
+
+	var result []string
+	for _, path := range candidates {
+		// Skip relative paths made from unset variables.
+		if !filepath.IsAbs(path) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(path, "Spotify.exe")); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+func winPrefs() []string {
+	return existingPaths(filepath.Join(os.Getenv("APPDATA"), "Spotify", "prefs"))
 }
 
-func winXApp() string {
+func winXApps() []string {
 	ps, _ := exec.LookPath("powershell.exe")
 	cmd := exec.Command(ps,
 		"-NoProfile",
 		"-NonInteractive",
-		`(Get-AppxPackage | Where-Object -Property Name -Eq "SpotifyAB.SpotifyMusic").InstallLocation`)
+		`Get-AppxPackage | Where-Object -Property Name -Match "^SpotifyAB\.SpotifyMusic" | ForEach-Object { $_.InstallLocation }`)
 
 	stdOut, err := cmd.CombinedOutput()
 	if err == nil {
-		return strings.TrimSpace(string(stdOut))
+		return stableFirst(string(stdOut))
 	}
 
-	return ""
+	return nil
 }
 
-func winXPrefs() string {
+func winXPrefs() []string {
 	ps, _ := exec.LookPath("powershell.exe")
 	cmd := exec.Command(ps,
 		"-NoProfile",
 		"-NonInteractive",
-		`(Get-AppxPackage | Where-Object -Property Name -Match "^SpotifyAB").PackageFamilyName`)
+		`Get-AppxPackage | Where-Object -Property Name -Match "^SpotifyAB" | ForEach-Object { $_.PackageFamilyName }`)
 
 	stdOut, err := cmd.CombinedOutput()
-	if err == nil {
-		return filepath.Join(
+	if err != nil {
+		return nil
+	}
+
+	var prefs []string
+	for _, family := range stableFirst(string(stdOut)) {
+		prefs = append(prefs, filepath.Join(
 			os.Getenv("LOCALAPPDATA"),
 			"Packages",
-			strings.TrimSpace(string(stdOut)),
+			family,
 			"LocalState",
 			"Spotify",
-			"prefs")
+			"prefs"))
 	}
 
-	return ""
+	return prefs
+}
+
+// stableFirst returns non-blank lines of install locations or package
+// names, stable installs before preview ones.
+func stableFirst(lines string) []string {
+	var stable, preview []string
+	for _, line := range strings.Split(lines, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if IsSpotifyPreview(line) {
+			preview = append(preview, line)
+		} else {
+			stable = append(stable, line)
+		}
+	}
+
+	return append(stable, preview...)
 }
 
-func linuxApp() string {
+func linuxApps() []string {
+	var result []string
 	path, err := exec.Command("whereis", "-b", "spotify").Output()
 
 	if err == nil {
@@ -284,7 +600,7 @@ func linuxApp() string {
 			bin = filepath.Dir(bin)
 
 			if _, err := os.Stat(filepath.Join(bin, "Apps")); err == nil {
-				return bin
+				result = append(result, bin)
 			}
 		}
 	}
@@ -293,48 +609,100 @@ func linuxApp() string {
 		"/opt/spotify/",
 		"/usr/share/spotify/",
 		"/var/lib/flatpak/app/com.spotify.Client/x86_64/stable/active/files/extra/share/spotify/",
+		filepath.Join(os.Getenv("HOME"), ".local/share/flatpak/app/com.spotify.Client/x86_64/stable/active/files/extra/share/spotify/"),
+		"/snap/spotify/current/usr/share/spotify/",
 	}
 
 	for _, v := range potentialList {
 		_, err := os.Stat(filepath.Join(v, "Apps"))
 		_, err2 := os.Stat(filepath.Join(v, "spotify"))
 		if err == nil && err2 == nil {
-			return v
+			result = append(result, v)
 		}
 	}
 
-	return ""
+	for _, roaming := range wineRoamingFolders() {
+		for _, path := range existingPaths(filepath.Join(roaming, "Spotify")) {
+			if _, err := os.Stat(filepath.Join(path, "Apps")); err == nil {
+				result = append(result, path)
+			}
+		}
+	}
+
+	return result
 }
 
-func linuxPrefs() string {
+func linuxPrefs() []string {
 	dotConfig := os.Getenv("XDG_CONFIG_HOME")
 
 	if len(dotConfig) == 0 {
 		dotConfig = filepath.Join(os.Getenv("HOME"), ".config")
 	}
 
-	pref := filepath.Join(dotConfig, "spotify", "prefs")
-	if _, err := os.Stat(pref); err == nil {
-		return pref
+	result := existingPaths(
+		filepath.Join(dotConfig, "spotify", "prefs"),
+		filepath.Join(os.Getenv("HOME"), ".var/app/com.spotify.Client/config/spotify/prefs"),
+		filepath.Join(os.Getenv("HOME"), "snap/spotify/current/.config/spotify/prefs"),
+	)
+
+	for _, roaming := range wineRoamingFolders() {
+		result = append(result, existingPaths(filepath.Join(roaming, "Spotify", "prefs"))...)
 	}
 
-	return ""
+	return result
 }
 
-func darwinApp() string {
-	path := filepath.Join("/Applications", "Spotify.app", "Contents", "Resources")
-	if _, err := os.Stat(path); err == nil {
-		return path
+// wineRoamingFolders returns "AppData/Roaming" folders of users in Wine
+// prefixes: WINEPREFIX, ~/.wine and Bottles or Lutris style prefix folders.
+func wineRoamingFolders() []string {
+	home := os.Getenv("HOME")
+	prefixes := []string{os.Getenv("WINEPREFIX"), filepath.Join(home, ".wine")}
+	for _, pattern := range []string{
+		filepath.Join(home, ".local/share/bottles/bottles/*"),
+		filepath.Join(home, "Games/*"),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		prefixes = append(prefixes, matches...)
 	}
 
-	return ""
+	var result []string
+	for _, prefix := range prefixes {
+		if len(prefix) == 0 {
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(prefix, "drive_c/users/*/AppData/Roaming"))
+		result = append(result, matches...)
+	}
+	return result
 }
 
-func darwinPrefs() string {
-	pref := filepath.Join(os.Getenv("HOME"), "Library/Application Support/Spotify/prefs")
-	if _, err := os.Stat(pref); err == nil {
-		return pref
+func darwinApps() []string {
+	var result []string
+	for _, app := range []string{"Spotify.app", "Spotify Preview.app"} {
+		result = append(result, existingPaths(
+			filepath.Join("/Applications", app, "Contents", "Resources"),
+			filepath.Join(os.Getenv("HOME"), "Applications", app, "Contents", "Resources"),
+		)...)
 	}
 
-	return ""
+	return result
+}
+
+func darwinPrefs() []string {
+	var result []string
+	for _, folder := range []string{"Spotify", "Spotify Preview"} {
+		result = append(result, existingPaths(
+			filepath.Join(os.Getenv("HOME"), "Library/Application Support", folder, "prefs"),
+		)...)
+	}
+
+	return result
+}
+
+// IsSpotifyPreview reports whether Spotify install at `path` is a preview
+// (beta) build.
+func IsSpotifyPreview(path string) bool {
+	return strings.Contains(path, "SpotifyMusic-Beta") ||
+		strings.Contains(path, "Spotify Preview") ||
+		strings.Contains(strings.ToLower(path), "spotify-preview")
 }
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestConfigMerge(t *testing.T) {
+	load := func(t *testing.T, content string) *ini.File {
+		t.Helper()
+		file, err := ini.Load([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	const base = "[Setting]\na = 1\nb = 1\n"
+
+	tests := []struct {
+		name    string
+		current string
+		disk    string
+		want    configValues
+	}{
+		{
+			name:    "nothing changed",
+			current: base,
+			disk:    base,
+			want:    configValues{"Setting": {"a": "1", "b": "1"}},
+		},
+		{
+			name:    "disk change is taken",
+			current: base,
+			disk:    "[Setting]\na = 2\nb = 1\n",
+			want:    configValues{"Setting": {"a": "2", "b": "1"}},
+		},
+		{
+			name:    "own change is kept",
+			current: "[Setting]\na = 3\nb = 1\n",
+			disk:    "[Setting]\na = 2\nb = 2\n",
+			want:    configValues{"Setting": {"a": "3", "b": "2"}},
+		},
+		{
+			name:    "disk key and section are added",
+			current: base,
+			disk:    base + "c = 1\n[Patch]\nd = x\n",
+			want:    configValues{"Setting": {"a": "1", "b": "1", "c": "1"}, "Patch": {"d": "x"}},
+		},
+		{
+			name:    "key deleted on disk is deleted",
+			current: base,
+			disk:    "[Setting]\na = 1\n",
+			want:    configValues{"Setting": {"a": "1"}},
+		},
+		{
+			name:    "changed key deleted on disk is kept",
+			current: "[Setting]\na = 1\nb = 3\n",
+			disk:    "[Setting]\na = 1\n",
+			want:    configValues{"Setting": {"a": "1", "b": "3"}},
+		},
+		{
+			name:    "own deleted key stays deleted",
+			current: "[Setting]\na = 1\n",
+			disk:    base,
+			want:    configValues{"Setting": {"a": "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config{content: load(t, tt.current), base: configValues{}}
+			c.base.take(load(t, base))
+
+			c.merge(load(t, tt.disk))
+
+			got := configValues{}
+			got.take(c.content)
+			delete(got, ini.DefaultSection)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
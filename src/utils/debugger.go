@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// DebuggerHost is host of Spotify remote debugging server.
+	DebuggerHost = "localhost"
+	// DebuggerPort is port of Spotify remote debugging server.
+	DebuggerPort = 9222
+	// DebuggerExpose lets remote debugging server listen on non-local
+	// DebuggerHost.
+	DebuggerExpose = false
+	// DebuggerTimeout limits each attempt to connect to debugging server.
+	DebuggerTimeout = 5 * time.Second
+	// DebuggerRetries is how many times connecting is retried before
+	// giving up.
+	DebuggerRetries = 5
+	// DebuggerBackoff is delay before first retry. It doubles after each
+	// retry.
+	DebuggerBackoff = 500 * time.Millisecond
+)
+
+// DebuggerAddress returns "host:port" of Spotify remote debugging server.
+func DebuggerAddress() string {
+	return net.JoinHostPort(DebuggerHost, strconv.Itoa(DebuggerPort))
+}
+
+// DebuggerFlags returns command-line flags to launch Spotify with remote
+// debugging server on. Server only listens on non-local DebuggerHost when
+// DebuggerExpose is set, since anyone reaching it can control Spotify.
+func DebuggerFlags() []string {
+	flags := []string{"--remote-debugging-port=" + strconv.Itoa(DebuggerPort)}
+	if isLocalHost(DebuggerHost) {
+		return flags
+	}
+
+	if !DebuggerExpose {
+		PrintWarning(`Remote debugging server only listens on localhost. Set "debug_expose" to 1 to listen on "` +
+			DebuggerHost + `", which lets anyone reaching it control Spotify.`)
+		return flags
+	}
+
+	return append(flags, "--remote-debugging-address="+DebuggerHost)
+}
+
+func isLocalHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// diffMaxTable limits the size of LCS table. Beyond it, changed region is
+// shown as a whole removal followed by a whole addition.
+const diffMaxTable = 4 << 20
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // line numbers in old and new content, 0-based
+}
+
+// UnifiedDiff returns lines of a unified diff between `a` and `b`, with
+// `context` unchanged lines around changes. Removed lines are colored red,
+// added ones green and hunk headers blue. Returns nil when there's no change.
+func UnifiedDiff(a, b []string, context int) []string {
+	ops := diffLines(a, b)
+
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var result []string
+	for i := 0; i < len(changes); {
+		start := changes[i] - context
+		if start < 0 {
+			start = 0
+		}
+
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*context+1 {
+			j++
+		}
+		end := changes[j] + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		hunk := ops[start:end]
+		aCount, bCount := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+
+		result = append(result, Blue(fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(hunk[0].a, aCount), hunkRange(hunk[0].b, bCount))))
+		for _, op := range hunk {
+			switch op.kind {
+			case '-':
+				result = append(result, Red("-"+op.line))
+			case '+':
+				result = append(result, Green("+"+op.line))
+			default:
+				result = append(result, " "+op.line)
+			}
+		}
+
+		i = j + 1
+	}
+
+	return result
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// diffLines returns edit script turning `a` into `b`.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA)*len(midB) > diffMaxTable {
+		for i, line := range midA {
+			ops = append(ops, diffOp{'-', line, prefix + i, prefix})
+		}
+		for i, line := range midB {
+			ops = append(ops, diffOp{'+', line, prefix + len(midA), prefix + i})
+		}
+	} else {
+		ops = append(ops, diffLCS(midA, midB, prefix)...)
+	}
+
+	for i := 0; i < suffix; i++ {
+		ia, ib := len(a)-suffix+i, len(b)-suffix+i
+		ops = append(ops, diffOp{' ', a[ia], ia, ib})
+	}
+
+	return ops
+}
+
+// diffLCS diffs `a` and `b` using longest common subsequence table.
+// `offset` is added to line numbers.
+func diffLCS(a, b []string, offset int) []diffOp {
+	width := len(b) + 1
+	table := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i*width+j] = table[(i+1)*width+j+1] + 1
+			} else if table[(i+1)*width+j] >= table[i*width+j+1] {
+				table[i*width+j] = table[(i+1)*width+j]
+			} else {
+				table[i*width+j] = table[i*width+j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			ops = append(ops, diffOp{' ', a[i], offset + i, offset + j})
+			i++
+			j++
+		} else if table[(i+1)*width+j] >= table[i*width+j+1] {
+			ops = append(ops, diffOp{'-', a[i], offset + i, offset + j})
+			i++
+		} else {
+			ops = append(ops, diffOp{'+', b[j], offset + i, offset + j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i], offset + i, offset + j})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j], offset + i, offset + j})
+	}
+
+	return ops
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var colorCodeRe = regexp.MustCompile("\x1B\\[[0-9;]*m")
+
+func TestUnifiedDiff(t *testing.T) {
+	numbers := strings.Split("1 2 3 4 5 6 7 8 9 10", " ")
+	changed := strings.Split("1 B 3 4 5 6 7 8 I 10", " ")
+
+	tests := []struct {
+		name    string
+		a, b    []string
+		context int
+		want    []string
+	}{
+		{
+			name:    "no change",
+			a:       []string{"a", "b"},
+			b:       []string{"a", "b"},
+			context: 3,
+			want:    nil,
+		},
+		{
+			name:    "changed line",
+			a:       []string{"a", "b", "c", "d", "e"},
+			b:       []string{"a", "b", "X", "d", "e"},
+			context: 1,
+			want:    []string{"@@ -2,3 +2,3 @@", " b", "-c", "+X", " d"},
+		},
+		{
+			name:    "added to empty",
+			a:       nil,
+			b:       []string{"x", "y"},
+			context: 3,
+			want:    []string{"@@ -0,0 +1,2 @@", "+x", "+y"},
+		},
+		{
+			name:    "removed line",
+			a:       []string{"a", "b", "c"},
+			b:       []string{"a", "c"},
+			context: 0,
+			want:    []string{"@@ -2,1 +1,0 @@", "-b"},
+		},
+		{
+			name:    "separate hunks",
+			a:       numbers,
+			b:       changed,
+			context: 1,
+			want: []string{
+				"@@ -1,3 +1,3 @@", " 1", "-2", "+B", " 3",
+				"@@ -8,3 +8,3 @@", " 8", "-9", "+I", " 10",
+			},
+		},
+		{
+			name:    "close changes share a hunk",
+			a:       numbers,
+			b:       changed,
+			context: 3,
+			want: []string{
+				"@@ -1,10 +1,10 @@", " 1", "-2", "+B", " 3", " 4", " 5", " 6", " 7", " 8", "-9", "+I", " 10",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, line := range UnifiedDiff(tt.a, tt.b, tt.context) {
+				got = append(got, colorCodeRe.ReplaceAllString(line, ""))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FetchCacheTTL is how long metadata fetched with FetchCached is used
+// before it is downloaded again.
+const FetchCacheTTL = 6 * time.Hour
+
+// DownloadMirror is URL template every download is routed through, for
+// networks where Github is blocked. "{url}" is replaced with original URL,
+// "{host}" with its host and "{path}" with its path and query. Without
+// placeholders, original URL is appended to it. Blank means no mirror.
+var DownloadMirror string
+
+// FetchCacheFolder stores responses of FetchCached. Blank disables cache.
+var FetchCacheFolder string
+
+// FetchRefresh makes FetchCached download again even when cache is fresh.
+var FetchRefresh bool
+
+// MirrorURL returns `rawURL` routed through DownloadMirror.
+func MirrorURL(rawURL string) string {
+	if len(DownloadMirror) == 0 {
+		return rawURL
+	}
+
+	if !strings.Contains(DownloadMirror, "{") {
+		return strings.TrimSuffix(DownloadMirror, "/") + "/" + rawURL
+	}
+
+	host, path := "", ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+		path = u.RequestURI()
+	}
+
+	return strings.NewReplacer(
+		"{url}", rawURL,
+		"{host}", host,
+		"{path}", path,
+	).Replace(DownloadMirror)
+}
+
+// Download is http.Get through download mirror.
+func Download(rawURL string) (*http.Response, error) {
+	return http.Get(MirrorURL(rawURL))
+}
+
+// FetchCached returns body of `rawURL`, from cache when it is younger than
+// `maxAge`. When download fails, cache of any age is used instead, so
+// commands keep working offline.
+func FetchCached(rawURL string, maxAge time.Duration) ([]byte, error) {
+	cachePath := ""
+	if len(FetchCacheFolder) > 0 {
+		sum := sha256.Sum256([]byte(rawURL))
+		cachePath = filepath.Join(FetchCacheFolder, hex.EncodeToString(sum[:8]))
+	}
+
+	if len(cachePath) > 0 && !FetchRefresh {
+		if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < maxAge {
+			if content, err := ioutil.ReadFile(cachePath); err == nil {
+				return content, nil
+			}
+		}
+	}
+
+	content, err := fetch(rawURL)
+	if err != nil {
+		if len(cachePath) > 0 {
+			if cached, cacheErr := ioutil.ReadFile(cachePath); cacheErr == nil {
+				return cached, nil
+			}
+		}
+		return nil, err
+	}
+
+	if len(cachePath) > 0 {
+		CheckExistAndCreate(FetchCacheFolder)
+		ioutil.WriteFile(cachePath, content, 0600)
+	}
+	return content, nil
+}
+
+func fetch(rawURL string) ([]byte, error) {
+	res, err := Download(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(rawURL + ": " + res.Status)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
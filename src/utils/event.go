@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is one line of JSON log, enabled with "--log-format json".
+// Stages are the bold headers printed while backing up, applying or
+// restoring, like "Transferring extensions".
+type Event struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Command  string    `json:"command,omitempty"`
+	Stage    string    `json:"stage,omitempty"`
+	Level    string    `json:"level,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	File     string    `json:"file,omitempty"`
+	Result   string    `json:"result,omitempty"`
+	Duration int64     `json:"duration_ms,omitempty"`
+}
+
+type eventState struct {
+	name    string
+	start   time.Time
+	errored bool
+}
+
+var (
+	eventWriter  io.Writer
+	eventMutex   sync.Mutex
+	eventCommand *eventState
+	eventStage   *eventState
+)
+
+// EnableJSONLog makes print functions write JSON events to `out`
+// instead of human readable messages.
+func EnableJSONLog(out io.Writer) {
+	eventWriter = out
+}
+
+// IsJSONLog reports whether JSON log is enabled.
+func IsJSONLog() bool {
+	return eventWriter != nil
+}
+
+// StartCommand emits start event of command `name`.
+func StartCommand(name string) {
+	if eventWriter == nil {
+		return
+	}
+
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+	eventCommand = &eventState{name: name, start: time.Now()}
+	emit(Event{Event: "command", Result: "start"})
+}
+
+// EndCommand emits end event of current command. Result is "error" when
+// `failed` is true or any error is printed while command runs.
+func EndCommand(failed bool) {
+	if eventWriter == nil || eventCommand == nil {
+		return
+	}
+
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+	endStage(failed)
+	emit(Event{
+		Event:    "command",
+		Result:   result(failed || eventCommand.errored),
+		Duration: time.Since(eventCommand.start).Milliseconds(),
+	})
+	eventCommand = nil
+}
+
+// logEvent handles a print function call in JSON log mode.
+func logEvent(level, text string, err error) {
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+
+	switch level {
+	case "stage":
+		endStage(false)
+		eventStage = &eventState{
+			name:  strings.TrimSuffix(strings.TrimSpace(text), ":"),
+			start: time.Now(),
+		}
+		emit(Event{Event: "stage", Result: "start"})
+		return
+	case "ok":
+		if eventStage != nil {
+			if text != "OK" {
+				emit(Event{Event: "message", Level: "info", Message: text})
+			}
+			endStage(false)
+			return
+		}
+		if text == "OK" {
+			return
+		}
+		level = "info"
+	case "error", "fatal":
+		if eventStage != nil {
+			eventStage.errored = true
+		}
+		if eventCommand != nil {
+			eventCommand.errored = true
+		}
+	}
+
+	emit(Event{Event: "message", Level: level, Message: text, File: errorFile(err)})
+
+	if level == "fatal" {
+		endStage(true)
+		if eventCommand != nil {
+			emit(Event{
+				Event:    "command",
+				Result:   "error",
+				Duration: time.Since(eventCommand.start).Milliseconds(),
+			})
+		}
+	}
+}
+
+// errorFile returns path of file that `err` is about, if it tells.
+func errorFile(err error) string {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Path
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		return linkErr.New
+	}
+	return ""
+}
+
+// endStage emits end event of current stage, if there is one.
+func endStage(failed bool) {
+	if eventStage == nil {
+		return
+	}
+
+	emit(Event{
+		Event:    "stage",
+		Result:   result(failed || eventStage.errored),
+		Duration: time.Since(eventStage.start).Milliseconds(),
+	})
+	eventStage = nil
+}
+
+func emit(event Event) {
+	event.Time = time.Now()
+	if eventCommand != nil {
+		event.Command = eventCommand.name
+	}
+	if eventStage != nil {
+		event.Stage = eventStage.name
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	eventWriter.Write(append(line, '\n'))
+}
+
+func result(failed bool) string {
+	if failed {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// Exit codes, so scripts and package hooks can tell failures apart.
+const (
+	ExitOK              = 0
+	ExitError           = 1  // Any other failure
+	ExitUsage           = 2  // Invalid command, flag or argument
+	ExitSpotifyNotFound = 3  // Spotify installation cannot be found
+	ExitPrefsNotFound   = 4  // Spotify "prefs" file cannot be found
+	ExitNoBackup        = 5  // Backup is needed but there is none
+	ExitBackupOutdated  = 6  // Backup and Spotify versions are mismatched
+	ExitPatchFailed     = 7  // A patch in [Patch] section cannot be applied
+	ExitNetwork         = 8  // Network request failed
+	ExitPermission      = 9  // Spotify or spicetify files cannot be written
+	ExitAddonFailed     = 10 // Some extensions, apps or snippets are skipped
+	ExitThemeNotFound   = 11 // Theme in config cannot be found
+	ExitHealthCheck     = 12 // Spotify is broken after apply
+)
+
+// ExitCodeError is an error carrying exit code spicetify should exit with.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// WithExitCode attaches exit `code` to `err`.
+func WithExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ExitCodeError{Code: code, Err: err}
+}
+
+// ExitCodeOf returns exit code matching failure class of `err`.
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ExitNetwork
+	}
+
+	if errors.Is(err, os.ErrPermission) {
+		return ExitPermission
+	}
+
+	return ExitError
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// LockRetries is how many times an operation failing because of a locked
+// file is retried.
+var LockRetries = 5
+
+// Windows error codes of files opened by another process.
+const (
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+// IsLockError reports whether err is caused by a file being locked by
+// another process, like a running Spotify client on Windows.
+func IsLockError(err error) bool {
+	if runtime.GOOS != "windows" {
+		return false
+	}
+
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+
+	return errno == errorSharingViolation || errno == errorLockViolation
+}
+
+// RetryLocked runs `operation` and runs it again, with growing delay,
+// while it fails because of locked files.
+func RetryLocked(operation func() error) error {
+	delay := INTERVAL
+	err := operation()
+	for i := 0; i < LockRetries && IsLockError(err); i++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = operation()
+	}
+
+	return err
+}
+
+const (
+	// lockTimeout is how long LockFile waits for a lock held by others.
+	lockTimeout = 10 * time.Second
+	// staleLockAge is age of a lock file after which its owner is assumed
+	// to be dead.
+	staleLockAge = 30 * time.Second
+)
+
+// LockFile takes an advisory lock on `path` by creating "<path>.lock",
+// waiting while another process holds it. Returned function releases it.
+func LockFile(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.WriteString(strconv.Itoa(os.Getpid()))
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New(lockPath + " is held by another process")
+		}
+
+		time.Sleep(INTERVAL)
+	}
+}
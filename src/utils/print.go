@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"io"
 	"log"
 	"os"
 )
@@ -32,41 +33,104 @@ func Blue(text string) string {
 
 // PrintBold prints a bold message
 func PrintBold(text string) {
+	if eventWriter != nil {
+		logEvent("stage", text, nil)
+		return
+	}
 	log.Println(Bold(text))
 }
 
 // PrintRed prints a message in red color
 func PrintRed(text string) {
+	if eventWriter != nil {
+		logEvent("error", text, nil)
+		return
+	}
 	log.Println(Red(text))
 }
 
 // PrintGreen prints a message in green color
 func PrintGreen(text string) {
+	if eventWriter != nil {
+		logEvent("ok", text, nil)
+		return
+	}
 	log.Println(Green(text))
 }
 
 // PrintWarning prints a warning message
 func PrintWarning(text string) {
+	if eventWriter != nil {
+		logEvent("warning", text, nil)
+		return
+	}
 	log.Println(Yellow("warning"), text)
 }
 
 // PrintError prints an error message
 func PrintError(text string) {
+	if eventWriter != nil {
+		logEvent("error", text, nil)
+		return
+	}
 	log.Println(Red("error"), text)
 }
 
+// PrintErrorOf prints `text` followed by `err` as an error message. In JSON
+// log, file that `err` is about is put in the event.
+func PrintErrorOf(text string, err error) {
+	if eventWriter != nil {
+		logEvent("error", text+err.Error(), err)
+		return
+	}
+	log.Println(Red("error"), text+err.Error())
+}
+
+// PrintWarningOf prints `text` followed by `err` as a warning message. In
+// JSON log, file that `err` is about is put in the event.
+func PrintWarningOf(text string, err error) {
+	if eventWriter != nil {
+		logEvent("warning", text+err.Error(), err)
+		return
+	}
+	log.Println(Yellow("warning"), text+err.Error())
+}
+
 // PrintSuccess prints a success message
 func PrintSuccess(text string) {
+	if eventWriter != nil {
+		logEvent("success", text, nil)
+		return
+	}
 	log.Println(Green("success"), text)
 }
 
 // PrintInfo prints an info message
 func PrintInfo(text string) {
+	if eventWriter != nil {
+		logEvent("info", text, nil)
+		return
+	}
 	log.Println(Blue("info"), text)
 }
 
 // Fatal prints fatal message and exits process
 func Fatal(err error) {
+	if eventWriter != nil {
+		logEvent("fatal", err.Error(), err)
+		os.Exit(ExitCodeOf(err))
+	}
+
 	log.Println(Red("fatal"), err)
-	os.Exit(1)
+	os.Exit(ExitCodeOf(err))
+}
+
+// TeeLog copies all printed messages, or JSON events when JSON logging is
+// enabled, to `w` too.
+func TeeLog(w io.Writer) {
+	if eventWriter != nil {
+		eventWriter = io.MultiWriter(eventWriter, w)
+		return
+	}
+	log.SetOutput(io.MultiWriter(log.Writer(), w))
 }
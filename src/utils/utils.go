@@ -2,8 +2,11 @@ package utils
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
@@ -12,6 +15,7 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-ini/ini"
@@ -43,45 +47,89 @@ func Unzip(src, dest string) error {
 	}
 	defer r.Close()
 
+	return UnzipReader(&r.Reader, dest)
+}
+
+// UnzipReader extracts all files of an opened zip archive to dest,
+// keeping their modification times. Files are extracted by a bounded pool
+// of workers.
+func UnzipReader(r *zip.Reader, dest string) error {
+	var files []*zip.File
 	for _, f := range r.File {
-		rc, err := f.Open()
-		if err != nil {
-			return err
+		fpath := filepath.Join(dest, f.Name)
+		if !strings.HasPrefix(fpath, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("%s: illegal file path in archive", f.Name)
 		}
-		defer rc.Close()
 
-		fpath := filepath.Join(dest, f.Name)
 		if f.FileInfo().IsDir() {
 			os.MkdirAll(fpath, 0700)
-		} else {
-			var fdir string
-			if lastIndex := strings.LastIndex(fpath, string(os.PathSeparator)); lastIndex > -1 {
-				fdir = fpath[:lastIndex]
-			}
-
-			err = os.MkdirAll(fdir, 0700)
-			if err != nil {
-				log.Fatal(err)
-				return err
-			}
-			f, err := os.OpenFile(
-				fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0700)
-			if err != nil {
-				return err
-			}
-			defer f.Close()
+			continue
+		}
 
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
-			}
+		if err := os.MkdirAll(filepath.Dir(fpath), 0700); err != nil {
+			return err
 		}
+		files = append(files, f)
+	}
+
+	return RunParallel(len(files), func(i int) error {
+		return unzipFile(files[i], filepath.Join(dest, files[i].Name))
+	})
+}
+
+func unzipFile(f *zip.File, fpath string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(
+		fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0700)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	if modTime := f.Modified; !modTime.IsZero() {
+		os.Chtimes(fpath, modTime, modTime)
 	}
+
 	return nil
 }
 
-// Copy .
+// CopyWorkers is maximum number of files copied, extracted or backed up at
+// the same time.
+var CopyWorkers = 8
+
+type copyJob struct {
+	src  string
+	dest string
+	info os.FileInfo
+}
+
+// Copy copies files from src to dest, using a bounded pool of workers.
+// Files' permissions and modification times are preserved.
+// If recursive is true, sub-folders are copied too. If filters is not
+// empty, only files whose names contain one of filters are copied.
 func Copy(src, dest string, recursive bool, filters []string) error {
+	var jobs []copyJob
+	if err := collectCopyJobs(src, dest, recursive, filters, &jobs); err != nil {
+		return err
+	}
+
+	return copyParallel(jobs)
+}
+
+func collectCopyJobs(src, dest string, recursive bool, filters []string, jobs *[]copyJob) error {
 	dir, err := ioutil.ReadDir(src)
 	if err != nil {
 		return err
@@ -96,11 +144,11 @@ func Copy(src, dest string, recursive bool, filters []string) error {
 		fDestPath := filepath.Join(dest, fileName)
 		if file.IsDir() && recursive {
 			os.MkdirAll(fDestPath, 0700)
-			if err = Copy(fSrcPath, fDestPath, true, filters); err != nil {
+			if err = collectCopyJobs(fSrcPath, fDestPath, true, filters, jobs); err != nil {
 				return err
 			}
-		} else {
-			if filters != nil && len(filters) > 0 {
+		} else if !file.IsDir() {
+			if len(filters) > 0 {
 				isMatch := false
 
 				for _, filter := range filters {
@@ -115,26 +163,82 @@ func Copy(src, dest string, recursive bool, filters []string) error {
 				}
 			}
 
-			fSrc, err := os.Open(fSrcPath)
-			if err != nil {
-				return err
-			}
-			defer fSrc.Close()
+			*jobs = append(*jobs, copyJob{fSrcPath, fDestPath, file})
+		}
+	}
 
-			fDest, err := os.OpenFile(
-				fDestPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0700)
-			if err != nil {
-				return err
-			}
-			defer fDest.Close()
+	return nil
+}
 
-			_, err = io.Copy(fDest, fSrc)
-			if err != nil {
-				return err
+func copyParallel(jobs []copyJob) error {
+	return RunParallel(len(jobs), func(i int) error {
+		return RetryLocked(func() error { return copyWithMeta(jobs[i]) })
+	})
+}
+
+// RunParallel calls `work` with every index from 0 to count-1, at most
+// CopyWorkers at the same time, and returns the first error.
+func RunParallel(count int, work func(i int) error) error {
+	workers := CopyWorkers
+	if workers > count {
+		workers = count
+	}
+
+	queue := make(chan int)
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				if err := work(index); err != nil {
+					errOnce.Do(func() { firstErr = err })
+				}
 			}
-		}
+		}()
 	}
-	return nil
+
+	for i := 0; i < count; i++ {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
+
+// copyWithMeta copies one file and applies source file's permissions and
+// modification time to the copy. Owner write permission is always kept so
+// the copy can be overwritten later.
+func copyWithMeta(job copyJob) error {
+	fSrc, err := os.Open(job.src)
+	if err != nil {
+		return err
+	}
+	defer fSrc.Close()
+
+	mode := job.info.Mode().Perm() | 0200
+	fDest, err := os.OpenFile(
+		job.dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(fDest, fSrc); err != nil {
+		fDest.Close()
+		return err
+	}
+
+	if err = fDest.Close(); err != nil {
+		return err
+	}
+
+	os.Chmod(job.dest, mode)
+	modTime := job.info.ModTime()
+	return os.Chtimes(job.dest, modTime, modTime)
 }
 
 // CopyFile .
@@ -162,6 +266,72 @@ func CopyFile(srcPath, dest string) error {
 	return nil
 }
 
+// HashFile returns hex encoded SHA-256 hash of file content.
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// DirSize returns total size of files in `path`, or size of `path` itself
+// when it is a file. Unreadable files are not counted.
+func DirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
+
+// FormatSize formats `size` in bytes to human readable string.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// MinInt returns the smaller of `a` and `b`.
+func MinInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// MaxInt returns the larger of `a` and `b`.
+func MaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // Replace uses Regexp to find any matched from `input` with `regexpTerm`
 // and replaces them with `replaceTerm` then returns new string.
 func Replace(input *string, regexpTerm string, replaceTerm string) {
@@ -2,15 +2,10 @@ package utils
 
 import (
 	"bytes"
-	"encoding/json"
 	"io/fs"
 	"io/ioutil"
-	"net/http"
 	"path/filepath"
-	"strings"
 	"time"
-
-	"golang.org/x/net/websocket"
 )
 
 var (
@@ -48,12 +43,30 @@ func Watch(fileList []string, callbackEach func(fileName string, err error), cal
 
 // WatchRecursive .
 func WatchRecursive(root string, callbackEach func(fileName string, err error), callbackAfter func()) {
+	WatchRecursiveExcept(root, nil, callbackEach, callbackAfter)
+}
+
+// WatchRecursiveExcept watches all files in root like WatchRecursive,
+// except files and folders that `exclude` returns true for.
+func WatchRecursiveExcept(root string, exclude func(filePath string, isDir bool) bool, callbackEach func(fileName string, err error), callbackAfter func()) {
 	var cache = map[string][]byte{}
 
 	for {
 		finalCallback := false
 
 		filepath.WalkDir(root, func(filePath string, info fs.DirEntry, err error) error {
+			if err != nil {
+				callbackEach(filePath, err)
+				return nil
+			}
+
+			if exclude != nil && filePath != root && exclude(filePath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
 			if info.IsDir() {
 				return nil
 			}
@@ -67,6 +80,7 @@ func WatchRecursive(root string, callbackEach func(fileName string, err error),
 			if !bytes.Equal(cache[filePath], curr) {
 				callbackEach(filePath, nil)
 				cache[filePath] = curr
+				finalCallback = true
 			}
 
 			return nil
@@ -79,59 +93,3 @@ func WatchRecursive(root string, callbackEach func(fileName string, err error),
 		time.Sleep(INTERVAL)
 	}
 }
-
-type debugger struct {
-	Description          string
-	DevtoolsFrontendUrl  string
-	Id                   string
-	Title                string
-	Type                 string
-	Url                  string
-	WebSocketDebuggerUrl string
-}
-
-// GetDebuggerPath fetches opening debugger list from localhost and returns
-// the Spotify one.
-func GetDebuggerPath() string {
-	res, err := http.Get("http://localhost:9222/json/list")
-	if err != nil {
-		return ""
-	}
-
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return ""
-	}
-
-	var list []debugger
-	if err = json.Unmarshal(body, &list); err != nil {
-		return ""
-	}
-
-	for _, debugger := range list {
-		if strings.Contains(debugger.Url, "spotify") {
-			return debugger.WebSocketDebuggerUrl
-		}
-	}
-
-	return ""
-}
-
-// SendReload sends reload command to debugger Websocket server
-func SendReload(debuggerURL *string) error {
-	if len(*debuggerURL) == 0 {
-		*debuggerURL = GetDebuggerPath()
-	}
-
-	socket, err := websocket.Dial(*debuggerURL, "", "http://localhost/")
-	if err != nil {
-		return err
-	}
-	defer socket.Close()
-
-	if _, err := socket.Write([]byte(`{"id":1,"method":"Page.reload"}`)); err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,336 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseYAML parses the subset of YAML spicetify files use: block mappings
+// and sequences, flow sequences and mappings on one line, quoted and plain
+// scalars, and comments. Result is made of map[string]interface{},
+// []interface{}, string, bool and nil, like decoded JSON.
+func ParseYAML(content []byte) (interface{}, error) {
+	p := &yamlParser{}
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	for i, raw := range strings.Split(text, "\n") {
+		line := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(line, " ")
+		if len(trimmed) == 0 || trimmed == "---" {
+			continue
+		}
+		if trimmed[0] == '\t' {
+			return nil, yamlError(i+1, "tabs are not allowed for indentation")
+		}
+		p.lines = append(p.lines, yamlLine{
+			indent: len(line) - len(trimmed),
+			text:   trimmed,
+			number: i + 1,
+		})
+	}
+
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, yamlError(p.lines[p.pos].number, "unexpected line")
+	}
+
+	return value, nil
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func yamlError(line int, message string) error {
+	return fmt.Errorf("line %d: %s", line, message)
+}
+
+// parseBlock parses node starting at current line, which is indented by
+// `indent`.
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if isYAMLItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(indent)
+	}
+
+	p.pos++
+	return parseYAMLScalar(line.text, line.number)
+}
+
+// parseNested parses node indented deeper than its parent, or returns nil
+// when there is none.
+func (p *yamlParser) parseNested(parentIndent int) (interface{}, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= parentIndent {
+		return nil, nil
+	}
+	return p.parseBlock(p.lines[p.pos].indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	mapping := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, yamlError(line.number, "unexpected indentation")
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, yamlError(line.number, `expected "key: value"`)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, yamlError(line.number, `duplicate key "`+key+`"`)
+		}
+		p.pos++
+
+		var value interface{}
+		var err error
+		if len(rest) > 0 {
+			value, err = parseYAMLScalar(rest, line.number)
+		} else if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLItem(p.lines[p.pos].text) {
+			// Sequence in a mapping can have the same indentation as key.
+			value, err = p.parseSequence(indent)
+		} else {
+			value, err = p.parseNested(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapping[key] = value
+	}
+
+	return mapping, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || line.indent == indent && !isYAMLItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, yamlError(line.number, "unexpected indentation")
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		var value interface{}
+		var err error
+		if len(rest) == 0 {
+			p.pos++
+			value, err = p.parseNested(indent)
+		} else {
+			// Content after "- " is parsed as a node indented to its
+			// column, so following keys of a mapping item line up with it.
+			itemIndent := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{indent: itemIndent, text: rest, number: line.number}
+			value, err = p.parseBlock(itemIndent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+
+	return list, nil
+}
+
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" line. Value is blank when it is in
+// following lines.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+
+	var end int
+	if text[0] == '"' || text[0] == '\'' {
+		closing := quotedEnd(text)
+		if closing == -1 || closing+1 >= len(text) || text[closing+1] != ':' {
+			return "", "", false
+		}
+		end = closing + 1
+	} else {
+		end = strings.Index(text, ": ")
+		if end == -1 && strings.HasSuffix(text, ":") {
+			end = len(text) - 1
+		}
+		if end == -1 {
+			return "", "", false
+		}
+	}
+
+	keyValue, err := parseYAMLScalar(strings.TrimSpace(text[:end]), 0)
+	if err != nil || keyValue == nil {
+		return "", "", false
+	}
+	return fmt.Sprint(keyValue), strings.TrimSpace(text[end+1:]), true
+}
+
+// quotedEnd returns index of quote closing the one `text` starts with, or
+// -1 when it is not closed.
+func quotedEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func parseYAMLScalar(text string, line int) (interface{}, error) {
+	if len(text) == 0 {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '"':
+		if quotedEnd(text) != len(text)-1 {
+			return nil, yamlError(line, "unterminated string")
+		}
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, yamlError(line, "invalid string "+text)
+		}
+		return value, nil
+
+	case '\'':
+		if quotedEnd(text) != len(text)-1 {
+			return nil, yamlError(line, "unterminated string")
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+
+	case '[':
+		if !strings.HasSuffix(text, "]") {
+			return nil, yamlError(line, "unterminated list")
+		}
+		list := []interface{}{}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			value, err := parseYAMLScalar(item, line)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+
+	case '{':
+		if !strings.HasSuffix(text, "}") {
+			return nil, yamlError(line, "unterminated mapping")
+		}
+		mapping := map[string]interface{}{}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			key, rest, ok := splitYAMLKey(item)
+			if !ok || len(rest) == 0 {
+				return nil, yamlError(line, `expected "key: value" in mapping`)
+			}
+			value, err := parseYAMLScalar(rest, line)
+			if err != nil {
+				return nil, err
+			}
+			mapping[key] = value
+		}
+		return mapping, nil
+
+	case '|', '>':
+		return nil, yamlError(line, "block scalars are not supported, use a quoted string")
+	}
+
+	switch strings.ToLower(text) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+
+	return text, nil
+}
+
+// splitYAMLFlow splits items of a flow sequence or mapping at commas that
+// are neither quoted nor in a nested collection.
+func splitYAMLFlow(text string) []string {
+	var items []string
+	start, depth := 0, 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && (text[i] == '"' || text[i] == '\'') && startsYAMLToken(text[start:i]) {
+			if end := quotedEnd(text[i:]); end != -1 {
+				i += end
+			}
+			continue
+		}
+		if i < len(text) {
+			switch text[i] {
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+			}
+		}
+		if i == len(text) || text[i] == ',' && depth == 0 {
+			if item := strings.TrimSpace(text[start:i]); len(item) > 0 {
+				items = append(items, item)
+			}
+			start = i + 1
+		}
+	}
+	return items
+}
+
+// stripYAMLComment removes comment from line. "#" starts a comment at line
+// start or after a space, outside quoted strings.
+func stripYAMLComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if (c == '"' || c == '\'') && startsYAMLToken(line[:i]) {
+			if end := quotedEnd(line[i:]); end != -1 {
+				i += end
+				continue
+			}
+		}
+		if c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// startsYAMLToken reports whether a quote after `before` opens a string
+// rather than being part of a plain scalar.
+func startsYAMLToken(before string) bool {
+	before = strings.TrimRight(before, " \t")
+	if len(before) == 0 {
+		return true
+	}
+	return strings.ContainsRune(":-[{,", rune(before[len(before)-1]))
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	type m = map[string]interface{}
+	type l = []interface{}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name:  "empty",
+			input: "# only a comment\n---\n",
+			want:  nil,
+		},
+		{
+			name:  "plain scalars",
+			input: "theme: Dribbblish\nscheme: nord-dark\nempty:\nnothing: ~",
+			want:  m{"theme": "Dribbblish", "scheme": "nord-dark", "empty": nil, "nothing": nil},
+		},
+		{
+			name:  "booleans",
+			input: "a: true\nb: No\nc: on\nd: OFF\ne: \"true\"",
+			want:  m{"a": true, "b": false, "c": true, "d": false, "e": "true"},
+		},
+		{
+			name:  "nested mapping",
+			input: "theme:\n  name: Sleek\n  source: https://github.com/a/b\npreprocesses:\n  expose_apis: true",
+			want: m{
+				"theme":        m{"name": "Sleek", "source": "https://github.com/a/b"},
+				"preprocesses": m{"expose_apis": true},
+			},
+		},
+		{
+			name:  "indented sequence",
+			input: "extensions:\n  - fullAppDisplay.js\n  - shuffle+.js",
+			want:  m{"extensions": l{"fullAppDisplay.js", "shuffle+.js"}},
+		},
+		{
+			name:  "sequence at key indentation",
+			input: "custom_apps:\n- lyrics-plus\n- reddit\nscheme: dark",
+			want:  m{"custom_apps": l{"lyrics-plus", "reddit"}, "scheme": "dark"},
+		},
+		{
+			name:  "sequence of mappings",
+			input: "- name: a\n  value: 1\n- name: b\n  value: 2",
+			want:  l{m{"name": "a", "value": "1"}, m{"name": "b", "value": "2"}},
+		},
+		{
+			name:  "nested sequences",
+			input: "-\n  - a\n  - b\n- - c",
+			want:  l{l{"a", "b"}, l{"c"}},
+		},
+		{
+			name:  "flow collections",
+			input: `list: [a, "b, c", 'd']` + "\nmap: {x: 1, \"y\": no}",
+			want:  m{"list": l{"a", "b, c", "d"}, "map": m{"x": "1", "y": false}},
+		},
+		{
+			name:  "nested flow collections",
+			input: `a: [[b, c], {d: "e, f", g: [h]}]`,
+			want:  m{"a": l{l{"b", "c"}, m{"d": "e, f", "g": l{"h"}}}},
+		},
+		{
+			name:  "quoted strings",
+			input: `a: "tab\tand \"quote\""` + "\n" + `b: 'it''s'` + "\n" + `"quoted key": x`,
+			want:  m{"a": "tab\tand \"quote\"", "b": "it's", "quoted key": "x"},
+		},
+		{
+			name:  "comments",
+			input: "# header\na: b # trailing\nc: \"# not comment\"\nd: e#f\nurl: https://x.com/#anchor",
+			want:  m{"a": "b", "c": "# not comment", "d": "e#f", "url": "https://x.com/#anchor"},
+		},
+		{
+			name:  "colon without space is part of value",
+			input: "source: https://github.com/a/b\ntime: 12:30",
+			want:  m{"source": "https://github.com/a/b", "time": "12:30"},
+		},
+		{
+			name:  "windows line endings",
+			input: "a: 1\r\nb:\r\n  - c\r\n",
+			want:  m{"a": "1", "b": l{"c"}},
+		},
+		{
+			name:    "duplicate key",
+			input:   "a: 1\na: 2",
+			wantErr: `line 2: duplicate key "a"`,
+		},
+		{
+			name:    "tab indentation",
+			input:   "a:\n\t- b",
+			wantErr: "line 2: tabs are not allowed",
+		},
+		{
+			name:    "block scalar",
+			input:   "a: |\n  text",
+			wantErr: "line 1: block scalars are not supported",
+		},
+		{
+			name:    "unterminated string",
+			input:   `a: "open`,
+			wantErr: "line 1: unterminated string",
+		},
+		{
+			name:    "unterminated list",
+			input:   "a: [b, c",
+			wantErr: "line 1: unterminated list",
+		},
+		{
+			name:    "unexpected indentation",
+			input:   "a: 1\n  b: 2",
+			wantErr: "line 2: unexpected indentation",
+		},
+		{
+			name:    "line after root value",
+			input:   "  a: 1\nb: 2",
+			wantErr: "line 2: unexpected line",
+		},
+		{
+			name:    "not a key",
+			input:   "a: 1\nplain text",
+			wantErr: `line 2: expected "key: value"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseYAML([]byte(tt.input))
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}